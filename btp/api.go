@@ -34,7 +34,9 @@ import (
 	"github.com/btpereum/go-btpereum/core/rawdb"
 	"github.com/btpereum/go-btpereum/core/state"
 	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/btp/fetcher"
 	"github.com/btpereum/go-btpereum/internal/btpapi"
+	"github.com/btpereum/go-btpereum/miner"
 	"github.com/btpereum/go-btpereum/rlp"
 	"github.com/btpereum/go-btpereum/rpc"
 	"github.com/btpereum/go-btpereum/trie"
@@ -75,6 +77,94 @@ func (api *PublicbtpereumAPI) ChainId() hexutil.Uint64 {
 	return (hexutil.Uint64)(chainID.Uint64())
 }
 
+// SafeBlockNumber returns the number of the most recent block with at least the
+// configured number of confirmations on top of it.
+func (api *PublicbtpereumAPI) SafeBlockNumber() (hexutil.Uint64, error) {
+	header, err := api.e.SafeHeader()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.Number.Uint64()), nil
+}
+
+// FinalizedBlockNumber returns the number of the most recent block the consensus
+// engine considers final, i.e. built upon by a majority of clique signers.
+func (api *PublicbtpereumAPI) FinalizedBlockNumber() (hexutil.Uint64, error) {
+	header, err := api.e.FinalizedHeader()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.Number.Uint64()), nil
+}
+
+// SendPrivateRawTransaction adds a signed transaction to the transaction pool
+// which is only included by the local miner and never broadcast to the network.
+// Unless included within the configured number of blocks, it is dropped.
+func (api *PublicbtpereumAPI) SendPrivateRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := api.e.TxPool().AddPrivate(tx); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// FeeHistoryResult is the fee history of a range of blocks.
+type FeeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the fee history of up to blockCount blocks ending with the
+// given last block: the gas used ratio of each block and the requested gas price
+// percentiles (weighted by gas used) of the transactions included in each.
+func (api *PublicbtpereumAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*FeeHistoryResult, error) {
+	oldest, ratios, rewards, err := api.e.APIBackend.gpo.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: ratios,
+	}
+	if rewards != nil {
+		result.Reward = make([][]*hexutil.Big, len(rewards))
+		for i, prices := range rewards {
+			result.Reward[i] = make([]*hexutil.Big, len(prices))
+			for j, price := range prices {
+				result.Reward[i][j] = (*hexutil.Big)(price)
+			}
+		}
+	}
+	return result, nil
+}
+
+// GasPriceLevels are the suggested gas prices for different inclusion speeds.
+type GasPriceLevels struct {
+	Fast     *hexutil.Big `json:"fast"`
+	Standard *hexutil.Big `json:"standard"`
+	Slow     *hexutil.Big `json:"slow"`
+}
+
+// SuggestGasPrices returns the gas prices recommended for inclusion within the
+// next block (fast), about half of the configured target blocks (standard) and
+// the target blocks (slow). The levels only differ if the oracle accounts for
+// the pending transaction pool.
+func (api *PublicbtpereumAPI) SuggestGasPrices(ctx context.Context) (*GasPriceLevels, error) {
+	prices, err := api.e.APIBackend.gpo.SuggestPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &GasPriceLevels{
+		Fast:     (*hexutil.Big)(prices.Fast),
+		Standard: (*hexutil.Big)(prices.Standard),
+		Slow:     (*hexutil.Big)(prices.Slow),
+	}, nil
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only mbtpods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -149,6 +239,116 @@ func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// SendBundle submits an ordered bundle of signed transactions to be included
+// atomically at the top of the given block, or not at all. The bundle hash is
+// returned on success.
+func (api *PrivateMinerAPI) SendBundle(encodedTxs []hexutil.Bytes, blockNumber hexutil.Uint64) (common.Hash, error) {
+	bundle := &miner.Bundle{BlockNumber: uint64(blockNumber)}
+	for i, encodedTx := range encodedTxs {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+			return common.Hash{}, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+	if err := api.e.Miner().SendBundle(bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return bundle.Hash(), nil
+}
+
+// BlockTemplate is an unsealed block assembled by the local node, as handed out
+// to external block builders and sealers.
+type BlockTemplate struct {
+	ParentHash   common.Hash     `json:"parentHash"`
+	Number       *hexutil.Big    `json:"number"`
+	Coinbase     common.Address  `json:"miner"`
+	Timestamp    hexutil.Uint64  `json:"timestamp"`
+	Difficulty   *hexutil.Big    `json:"difficulty"`
+	GasLimit     hexutil.Uint64  `json:"gasLimit"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	ExtraData    hexutil.Bytes   `json:"extraData"`
+	StateRoot    common.Hash     `json:"stateRoot"`
+	TxRoot       common.Hash     `json:"transactionsRoot"`
+	ReceiptRoot  common.Hash     `json:"receiptsRoot"`
+	Bloom        types.Bloom     `json:"logsBloom"`
+	SealHash     common.Hash     `json:"sealHash"`
+	Transactions []hexutil.Bytes `json:"transactions"` // RLP encoded transactions in block order
+	Block        hexutil.Bytes   `json:"block"`        // RLP encoded unsealed block
+}
+
+// GetBlockTemplate assembles an unsealed block out of the pending transactions
+// on top of the given parent (default = current head). The coinbase defaults to
+// the configured btperbase and the timestamp to the current time.
+func (api *PrivateMinerAPI) GetBlockTemplate(parent *common.Hash, coinbase *common.Address, timestamp *hexutil.Uint64) (*BlockTemplate, error) {
+	parentHash := api.e.BlockChain().CurrentBlock().Hash()
+	if parent != nil {
+		parentHash = *parent
+	}
+	var author common.Address
+	if coinbase != nil {
+		author = *coinbase
+	} else {
+		btperbase, err := api.e.btperbase()
+		if err != nil {
+			return nil, err
+		}
+		author = btperbase
+	}
+	stamp := uint64(time.Now().Unix())
+	if timestamp != nil {
+		stamp = uint64(*timestamp)
+	}
+	block, _, err := api.e.Miner().BlockTemplate(parentHash, author, stamp)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return nil, err
+	}
+	header := block.Header()
+	template := &BlockTemplate{
+		ParentHash:   header.ParentHash,
+		Number:       (*hexutil.Big)(header.Number),
+		Coinbase:     header.Coinbase,
+		Timestamp:    hexutil.Uint64(header.Time),
+		Difficulty:   (*hexutil.Big)(header.Difficulty),
+		GasLimit:     hexutil.Uint64(header.GasLimit),
+		GasUsed:      hexutil.Uint64(header.GasUsed),
+		ExtraData:    header.Extra,
+		StateRoot:    header.Root,
+		TxRoot:       header.TxHash,
+		ReceiptRoot:  header.ReceiptHash,
+		Bloom:        header.Bloom,
+		SealHash:     api.e.Engine().SealHash(header),
+		Transactions: make([]hexutil.Bytes, 0, len(block.Transactions())),
+		Block:        encoded,
+	}
+	for _, tx := range block.Transactions() {
+		enc, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			return nil, err
+		}
+		template.Transactions = append(template.Transactions, enc)
+	}
+	return template, nil
+}
+
+// SubmitBlock accepts an RLP encoded sealed or externally assembled block,
+// validates it with the consensus engine and imports it into the chain. The
+// hash of the imported block is returned.
+func (api *PrivateMinerAPI) SubmitBlock(encodedBlock hexutil.Bytes) (common.Hash, error) {
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(encodedBlock, block); err != nil {
+		return common.Hash{}, err
+	}
+	if err := api.e.Miner().SubmitBlock(block); err != nil {
+		return common.Hash{}, err
+	}
+	return block.Hash(), nil
+}
+
 // Gbtpashrate returns the current hashrate of the miner.
 func (api *PrivateMinerAPI) Gbtpashrate() uint64 {
 	return api.e.miner.HashRate()
@@ -188,6 +388,42 @@ func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// ExportTxPool exports the pending and queued transactions of the transaction
+// pool into a local file.
+func (api *PrivateAdminAPI) ExportTxPool(file string) (int, error) {
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	var writer io.Writer = out
+	if strings.HasSuffix(file, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	return api.btp.TxPool().Export(writer)
+}
+
+// ImportTxPool injects the transactions of a pool export from a local file into
+// the transaction pool, returning the number of rejected transactions.
+func (api *PrivateAdminAPI) ImportTxPool(file string) (int, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return 0, err
+		}
+	}
+	_, failed, err := api.btp.TxPool().Import(reader)
+	return failed, err
+}
+
 func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	for _, b := range bs {
 		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
@@ -304,11 +540,26 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return nil, errors.New("unknown preimage")
 }
 
+// BlockPropagation returns the propagation records of the blocks recently
+// announced to or received by the node, newest first.
+func (api *PrivateDebugAPI) BlockPropagation() []*fetcher.PropagationRecord {
+	return api.btp.protocolManager.fetcher.Propagation()
+}
+
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
 	Hash  common.Hash            `json:"hash"`
 	Block map[string]interface{} `json:"block"`
 	RLP   string                 `json:"rlp"`
+
+	Reason              string         `json:"reason"`
+	Peer                string         `json:"peer,omitempty"`
+	Time                hexutil.Uint64 `json:"time"`
+	ExpectedStateRoot   common.Hash    `json:"expectedStateRoot"`
+	ComputedStateRoot   *common.Hash   `json:"computedStateRoot,omitempty"`
+	ExpectedReceiptRoot common.Hash    `json:"expectedReceiptRoot"`
+	ComputedReceiptRoot *common.Hash   `json:"computedReceiptRoot,omitempty"`
+	Receipts            types.Receipts `json:"receipts,omitempty"`
 }
 
 // GetBadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
@@ -318,9 +569,27 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	results := make([]*BadBlockArgs, len(blocks))
 
 	var err error
-	for i, block := range blocks {
+	for i, bad := range blocks {
+		block := bad.Block
 		results[i] = &BadBlockArgs{
-			Hash: block.Hash(),
+			Hash:                block.Hash(),
+			Reason:              bad.Reason,
+			Peer:                bad.Peer,
+			Time:                hexutil.Uint64(bad.Time),
+			ExpectedStateRoot:   block.Root(),
+			ExpectedReceiptRoot: block.ReceiptHash(),
+		}
+		if bad.StateRoot != (common.Hash{}) {
+			results[i].ComputedStateRoot = &bad.StateRoot
+		}
+		if bad.ReceiptRoot != (common.Hash{}) {
+			results[i].ComputedReceiptRoot = &bad.ReceiptRoot
+		}
+		if bad.Receipts != nil {
+			// Only the consensus fields are persisted, rederive the rest
+			if err := bad.Receipts.DeriveFields(api.btp.blockchain.Config(), block.Hash(), block.NumberU64(), block.Transactions()); err == nil {
+				results[i].Receipts = bad.Receipts
+			}
 		}
 		if rlpBytes, err := rlp.EncodeToBytes(block); err != nil {
 			results[i].RLP = err.Error() // Hacky, but hey, it works
@@ -334,6 +603,74 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	return results, nil
 }
 
+// ReorgArgs represents the entries in the list returned when the reorg history is
+// queried.
+type ReorgArgs struct {
+	Time           hexutil.Uint64 `json:"time"`
+	AncestorHash   common.Hash    `json:"ancestorHash"`
+	AncestorNumber hexutil.Uint64 `json:"ancestorNumber"`
+	Depth          hexutil.Uint64 `json:"depth"`
+	Dropped        []common.Hash  `json:"dropped"`
+	Added          []common.Hash  `json:"added"`
+	DroppedTxs     []common.Hash  `json:"droppedTransactions"`
+}
+
+// ReorgHistory returns the most recent canonical chain reorganisations the node
+// went through, oldest first.
+func (api *PrivateDebugAPI) ReorgHistory() []*ReorgArgs {
+	history := rawdb.ReadReorgHistory(api.btp.ChainDb())
+	results := make([]*ReorgArgs, len(history))
+	for i, reorg := range history {
+		results[i] = &ReorgArgs{
+			Time:           hexutil.Uint64(reorg.Time),
+			AncestorHash:   reorg.AncestorHash,
+			AncestorNumber: hexutil.Uint64(reorg.AncestorNumber),
+			Depth:          hexutil.Uint64(len(reorg.Dropped)),
+			Dropped:        reorg.Dropped,
+			Added:          reorg.Added,
+			DroppedTxs:     reorg.DroppedTxs,
+		}
+	}
+	return results
+}
+
+// RewindResult is the result of a debug_rewind API call.
+type RewindResult struct {
+	Number          hexutil.Uint64 `json:"number"`
+	Hash            common.Hash    `json:"hash"`
+	OldHeader       hexutil.Uint64 `json:"oldHeader"`
+	OldFastBlock    hexutil.Uint64 `json:"oldFastBlock"`
+	OldBlock        hexutil.Uint64 `json:"oldBlock"`
+	RemovedHeaders  hexutil.Uint64 `json:"removedHeaders"`
+	RemovedAncients hexutil.Uint64 `json:"removedAncients"`
+}
+
+// Rewind rewinds the local chain to the nearest block at or below the given number
+// whose state is available, truncating the ancient store if needed, and reports
+// what was removed. Unlike debug_setHead, it never leaves a stateless head behind.
+//
+// Rewinds removing more than core.DefaultRewindLimit headers are refused unless
+// force is set.
+func (api *PrivateDebugAPI) Rewind(number hexutil.Uint64, force *bool) (*RewindResult, error) {
+	limit := uint64(core.DefaultRewindLimit)
+	if force != nil && *force {
+		limit = 0
+	}
+	result, err := api.btp.BlockChain().Rewind(uint64(number), limit)
+	if err != nil {
+		return nil, err
+	}
+	return &RewindResult{
+		Number:          hexutil.Uint64(result.Head.NumberU64()),
+		Hash:            result.Head.Hash(),
+		OldHeader:       hexutil.Uint64(result.OldHeader),
+		OldFastBlock:    hexutil.Uint64(result.OldFastBlock),
+		OldBlock:        hexutil.Uint64(result.OldBlock),
+		RemovedHeaders:  hexutil.Uint64(result.RemovedHeaders),
+		RemovedAncients: hexutil.Uint64(result.RemovedAncients),
+	}, nil
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -66,8 +66,13 @@ func (b *btpAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 		return block.Header(), nil
 	}
 	// Otherwise resolve and return the block
-	if blockNr == rpc.LatestBlockNumber {
+	switch blockNr {
+	case rpc.LatestBlockNumber:
 		return b.btp.blockchain.CurrentBlock().Header(), nil
+	case rpc.SafeBlockNumber:
+		return b.btp.SafeHeader()
+	case rpc.FinalizedBlockNumber:
+		return b.btp.FinalizedHeader()
 	}
 	return b.btp.blockchain.GbtpeaderByNumber(uint64(blockNr)), nil
 }
@@ -83,8 +88,15 @@ func (b *btpAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 		return block, nil
 	}
 	// Otherwise resolve and return the block
-	if blockNr == rpc.LatestBlockNumber {
+	switch blockNr {
+	case rpc.LatestBlockNumber:
 		return b.btp.blockchain.CurrentBlock(), nil
+	case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+		header, err := b.HeaderByNumber(ctx, blockNr)
+		if header == nil || err != nil {
+			return nil, err
+		}
+		return b.btp.blockchain.GetBlock(header.Hash(), header.Number.Uint64()), nil
 	}
 	return b.btp.blockchain.GetBlockByNumber(uint64(blockNr)), nil
 }
@@ -164,7 +176,7 @@ func (b *btpAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 }
 
 func (b *btpAPIBackend) GetPoolTransactions() (types.Transactions, error) {
-	pending, err := b.btp.txPool.Pending()
+	pending, err := b.btp.txPool.Propagatable()
 	if err != nil {
 		return nil, err
 	}
@@ -176,6 +188,9 @@ func (b *btpAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 }
 
 func (b *btpAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	if b.btp.txPool.IsPrivate(hash) {
+		return nil
+	}
 	return b.btp.txPool.Get(hash)
 }
 
@@ -188,18 +203,30 @@ func (b *btpAPIBackend) GetPoolNonce(ctx context.Context, addr common.Address) (
 	return b.btp.txPool.Nonce(addr), nil
 }
 
+func (b *btpAPIBackend) TxPoolGeneration() uint64 {
+	return b.btp.txPool.Generation()
+}
+
 func (b *btpAPIBackend) Stats() (pending int, queued int) {
 	return b.btp.txPool.Stats()
 }
 
 func (b *btpAPIBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
-	return b.btp.TxPool().Content()
+	return b.btp.TxPool().PublicContent()
 }
 
 func (b *btpAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.btp.TxPool().SubscribeNewTxsEvent(ch)
 }
 
+func (b *btpAPIBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return b.btp.TxPool().SubscribeTxLifecycleEvent(ch)
+}
+
+func (b *btpAPIBackend) SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription {
+	return b.btp.BlockChain().SubscribeReorgEvent(ch)
+}
+
 func (b *btpAPIBackend) Downloader() *downloader.Downloader {
 	return b.btp.Downloader()
 }
@@ -232,6 +259,10 @@ func (b *btpAPIBackend) RPCGasCap() *big.Int {
 	return b.btp.config.RPCGasCap
 }
 
+func (b *btpAPIBackend) RPCLogsCap() int {
+	return b.btp.config.RPCLogsCap
+}
+
 func (b *btpAPIBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.btp.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
@@ -0,0 +1,321 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/rawdb"
+	"github.com/btpereum/go-btpereum/core/state"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/core/vm"
+	"github.com/btpereum/go-btpereum/btp/tracers"
+	"github.com/btpereum/go-btpereum/internal/btpapi"
+	"github.com/btpereum/go-btpereum/log"
+	"github.com/btpereum/go-btpereum/rpc"
+)
+
+const (
+	// debugSessionTimeout is the amount of time a step debugging session may stay
+	// idle before it is closed and its execution released.
+	debugSessionTimeout = 5 * time.Minute
+
+	// debugSessionCleanup is the interval at which idle sessions are looked for.
+	debugSessionCleanup = 30 * time.Second
+
+	// maxDebugSessions is the maximum number of concurrently open step debugging
+	// sessions, each of them holding a paused EVM and its state.
+	maxDebugSessions = 16
+)
+
+var (
+	// errDebugSessionNotFound is returned if a step debugging command references
+	// a session that doesn't exist, was closed or timed out.
+	errDebugSessionNotFound = errors.New("debug session not found")
+
+	// errTooManyDebugSessions is returned if a new step debugging session is
+	// requested while the maximum number of sessions are open.
+	errTooManyDebugSessions = errors.New("too many debug sessions")
+)
+
+// DebugSession is the reply to the creation of a step debugging session.
+type DebugSession struct {
+	ID rpc.ID `json:"id"` // Identifier of the session to issue commands to
+	*tracers.DebugEvent
+}
+
+// debugSession is a live step debugging session.
+type debugSession struct {
+	debugger *tracers.StepDebugger // Debugger controlling the paused execution
+	expiry   time.Time             // Time after which the idle session is closed
+}
+
+// debugSessions tracks the live step debugging sessions, closing idle ones.
+type debugSessions struct {
+	sessions map[rpc.ID]*debugSession
+	lock     sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newDebugSessions creates a session tracker and starts its cleanup loop.
+func newDebugSessions() *debugSessions {
+	s := &debugSessions{
+		sessions: make(map[rpc.ID]*debugSession),
+		quit:     make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.loop()
+	return s
+}
+
+// loop periodically closes the sessions that have been idle for too long.
+func (s *debugSessions) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(debugSessionCleanup)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.lock.Lock()
+			for id, session := range s.sessions {
+				if now.After(session.expiry) {
+					log.Debug("Closing idle debug session", "id", id)
+					session.debugger.Close()
+					delete(s.sessions, id)
+				}
+			}
+			s.lock.Unlock()
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// add starts tracking a new session, returning its identifier.
+func (s *debugSessions) add(debugger *tracers.StepDebugger) (rpc.ID, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.sessions) >= maxDebugSessions {
+		return "", errTooManyDebugSessions
+	}
+	id := rpc.NewID()
+	s.sessions[id] = &debugSession{
+		debugger: debugger,
+		expiry:   time.Now().Add(debugSessionTimeout),
+	}
+	return id, nil
+}
+
+// get retrieves the debugger of a session, extending its idle timeout.
+func (s *debugSessions) get(id rpc.ID) (*tracers.StepDebugger, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, errDebugSessionNotFound
+	}
+	session.expiry = time.Now().Add(debugSessionTimeout)
+	return session.debugger, nil
+}
+
+// remove closes a session and stops tracking it.
+func (s *debugSessions) remove(id rpc.ID) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return errDebugSessionNotFound
+	}
+	session.debugger.Close()
+	delete(s.sessions, id)
+	return nil
+}
+
+// close terminates the cleanup loop and closes all the live sessions.
+func (s *debugSessions) close() {
+	close(s.quit)
+	s.wg.Wait()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, session := range s.sessions {
+		session.debugger.Close()
+		delete(s.sessions, id)
+	}
+}
+
+// StepTransaction starts a step debugging session of a transaction, executing it
+// on top of the state it was originally executed on. The execution is paused at
+// its first instruction.
+func (api *PrivateDebugAPI) StepTransaction(ctx context.Context, hash common.Hash, reexec *uint64) (*DebugSession, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(api.btp.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	msg, vmctx, statedb, err := api.computeTxEnv(blockHash, int(index), traceReexec(reexec))
+	if err != nil {
+		return nil, err
+	}
+	return api.startDebugSession(msg, vmctx, statedb)
+}
+
+// StepCall starts a step debugging session of a call executed on top of the
+// state of the given block. The execution is paused at its first instruction.
+func (api *PrivateDebugAPI) StepCall(ctx context.Context, args btpapi.CallArgs, number rpc.BlockNumber) (*DebugSession, error) {
+	var header *types.Header
+	if number == rpc.LatestBlockNumber {
+		header = api.btp.blockchain.CurrentHeader()
+	} else {
+		header = api.btp.blockchain.GbtpeaderByNumber(uint64(number))
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	statedb, err := api.btp.blockchain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble the message to execute, defaulting to a free call with all the
+	// gas of the block
+	var (
+		from     common.Address
+		gas      = header.GasLimit
+		gasPrice = new(big.Int)
+		value    = new(big.Int)
+		data     []byte
+	)
+	if args.From != nil {
+		from = *args.From
+	}
+	if args.Gas != nil {
+		gas = uint64(*args.Gas)
+	}
+	if args.GasPrice != nil {
+		gasPrice = args.GasPrice.ToInt()
+	}
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+	if args.Data != nil {
+		data = *args.Data
+	}
+	msg := types.NewMessage(from, args.To, 0, value, gas, gasPrice, data, false)
+	vmctx := core.NewEVMContext(msg, header, api.btp.blockchain, nil)
+
+	return api.startDebugSession(msg, vmctx, statedb)
+}
+
+// startDebugSession executes a message on a background goroutine with a step
+// debugger attached, waiting until it pauses at its first instruction.
+func (api *PrivateDebugAPI) startDebugSession(msg core.Message, vmctx vm.Context, statedb *state.StateDB) (*DebugSession, error) {
+	debugger := tracers.NewStepDebugger()
+
+	id, err := api.btp.debugSessions.add(debugger)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		vmenv := vm.NewEVM(vmctx, statedb, api.btp.blockchain.Config(), vm.Config{Debug: true, Tracer: debugger})
+		_, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+		debugger.Finish(err)
+	}()
+	event, err := debugger.Wait()
+	if err != nil {
+		api.btp.debugSessions.remove(id)
+		return nil, err
+	}
+	return &DebugSession{ID: id, DebugEvent: event}, nil
+}
+
+// StepInto resumes the execution of a debug session until the next instruction.
+func (api *PrivateDebugAPI) StepInto(id rpc.ID) (*tracers.DebugEvent, error) {
+	debugger, err := api.btp.debugSessions.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return debugger.StepInto()
+}
+
+// StepOver resumes the execution of a debug session until the next instruction
+// of the current call, stepping over any calls made in between.
+func (api *PrivateDebugAPI) StepOver(id rpc.ID) (*tracers.DebugEvent, error) {
+	debugger, err := api.btp.debugSessions.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return debugger.StepOver()
+}
+
+// StepOut resumes the execution of a debug session until the current call
+// returns to its caller.
+func (api *PrivateDebugAPI) StepOut(id rpc.ID) (*tracers.DebugEvent, error) {
+	debugger, err := api.btp.debugSessions.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return debugger.StepOut()
+}
+
+// StepContinue resumes the execution of a debug session until a breakpoint is
+// hit or the execution finishes.
+func (api *PrivateDebugAPI) StepContinue(id rpc.ID) (*tracers.DebugEvent, error) {
+	debugger, err := api.btp.debugSessions.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return debugger.Continue()
+}
+
+// StepBreakpoints replaces the breakpoints of a debug session.
+func (api *PrivateDebugAPI) StepBreakpoints(id rpc.ID, breakpoints []tracers.Breakpoint) error {
+	debugger, err := api.btp.debugSessions.get(id)
+	if err != nil {
+		return err
+	}
+	return debugger.SetBreakpoints(breakpoints)
+}
+
+// StepStorage retrieves a storage slot of an account at the instruction a debug
+// session is paused at.
+func (api *PrivateDebugAPI) StepStorage(id rpc.ID, address common.Address, key common.Hash) (common.Hash, error) {
+	debugger, err := api.btp.debugSessions.get(id)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return debugger.Storage(address, key)
+}
+
+// StepClose terminates a debug session, releasing its execution.
+func (api *PrivateDebugAPI) StepClose(id rpc.ID) error {
+	return api.btp.debugSessions.remove(id)
+}
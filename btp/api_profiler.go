@@ -0,0 +1,90 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/core/vm"
+	"github.com/btpereum/go-btpereum/btp/tracers"
+	"github.com/btpereum/go-btpereum/rpc"
+)
+
+// maxGasProfileBlocks is the maximum number of blocks a single gas profiling
+// request is allowed to re-execute.
+const maxGasProfileBlocks = 1024
+
+// GasProfile re-executes all the transactions of the given block range with a
+// gas profiler, returning the gas burnt per contract, function selector and
+// instruction.
+func (api *PrivateDebugAPI) GasProfile(ctx context.Context, start, end rpc.BlockNumber, reexec *uint64) (tracers.GasProfile, error) {
+	// Resolve the block range to profile
+	resolve := func(number rpc.BlockNumber) (*types.Block, error) {
+		var block *types.Block
+		if number == rpc.LatestBlockNumber {
+			block = api.btp.blockchain.CurrentBlock()
+		} else {
+			block = api.btp.blockchain.GetBlockByNumber(uint64(number))
+		}
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		return block, nil
+	}
+	from, err := resolve(start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := resolve(end)
+	if err != nil {
+		return nil, err
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", to.NumberU64(), from.NumberU64())
+	}
+	if blocks := to.NumberU64() - from.NumberU64() + 1; blocks > maxGasProfileBlocks {
+		return nil, fmt.Errorf("block range too large: %d blocks, max %d", blocks, maxGasProfileBlocks)
+	}
+	// Retrieve the state the range starts from and profile all its blocks
+	parent := api.btp.blockchain.GetBlock(from.ParentHash(), from.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", from.ParentHash())
+	}
+	statedb, err := api.computeStateDB(parent, traceReexec(reexec))
+	if err != nil {
+		return nil, err
+	}
+	profiler := tracers.NewGasProfiler()
+
+	for number := from.NumberU64(); number <= to.NumberU64(); number++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		block := api.btp.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		if _, _, _, err := api.btp.blockchain.Processor().Process(block, statedb, vm.Config{Debug: true, Tracer: profiler}); err != nil {
+			return nil, fmt.Errorf("processing block #%d failed: %v", number, err)
+		}
+	}
+	return profiler.Profile(), nil
+}
@@ -17,13 +17,19 @@
 package btp
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/consensus/btpash"
+	"github.com/btpereum/go-btpereum/core"
 	"github.com/btpereum/go-btpereum/core/rawdb"
 	"github.com/btpereum/go-btpereum/core/state"
+	"github.com/btpereum/go-btpereum/core/vm"
+	"github.com/btpereum/go-btpereum/params"
+	"github.com/btpereum/go-btpereum/rpc"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -88,3 +94,55 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the safe and finalized blocks are resolved both by the dedicated
+// API methods and wherever the backend accepts a block number.
+func TestSafeAndFinalizedBlocks(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		engine  = btpash.NewFaker()
+		genesis = (&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 5, nil)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	var (
+		btp     = &btpereum{config: &Config{RPCSafeDepth: 2}, blockchain: chain, engine: engine}
+		api     = NewPublicbtpereumAPI(btp)
+		backend = &btpAPIBackend{btp: btp}
+	)
+	// The safe block is the configured depth below the head, capped at genesis
+	if number, err := api.SafeBlockNumber(); err != nil || number != 3 {
+		t.Errorf("safe block mismatch: have %d, %v, want %d", number, err, 3)
+	}
+	header, err := backend.HeaderByNumber(context.Background(), rpc.SafeBlockNumber)
+	if err != nil || header.Hash() != blocks[2].Hash() {
+		t.Errorf("safe header mismatch: have %v, %v, want #%d", header, err, 3)
+	}
+	block, err := backend.BlockByNumber(context.Background(), rpc.SafeBlockNumber)
+	if err != nil || block.Hash() != blocks[2].Hash() {
+		t.Errorf("safe block mismatch: have %v, %v, want #%d", block, err, 3)
+	}
+	_, header, err = backend.StateAndHeaderByNumber(context.Background(), rpc.SafeBlockNumber)
+	if err != nil || header.Hash() != blocks[2].Hash() {
+		t.Errorf("safe state header mismatch: have %v, %v, want #%d", header, err, 3)
+	}
+	btp.config.RPCSafeDepth = 10
+	if header, err := btp.SafeHeader(); err != nil || header.Hash() != genesis.Hash() {
+		t.Errorf("capped safe header mismatch: have %v, %v, want genesis", header, err)
+	}
+	// Finality is only provided by clique, other engines must error out
+	if _, err := api.FinalizedBlockNumber(); err == nil {
+		t.Errorf("finalized block retrieved from a non-clique chain")
+	}
+	if _, err := backend.HeaderByNumber(context.Background(), rpc.FinalizedBlockNumber); err == nil {
+		t.Errorf("finalized header retrieved from a non-clique chain")
+	}
+}
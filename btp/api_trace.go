@@ -0,0 +1,380 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/rawdb"
+	"github.com/btpereum/go-btpereum/core/state"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/core/vm"
+	"github.com/btpereum/go-btpereum/btp/tracers"
+	"github.com/btpereum/go-btpereum/rpc"
+)
+
+// PrivateTraceAPI is the collection of btpereum APIs exposing the internal calls
+// of transactions as flat call traces, queryable by block and by account.
+type PrivateTraceAPI struct {
+	btp   *btpereum
+	debug *PrivateDebugAPI
+}
+
+// NewPrivateTraceAPI creates a new API definition for the call tracing methods
+// of the btpereum service.
+func NewPrivateTraceAPI(btp *btpereum) *PrivateTraceAPI {
+	return &PrivateTraceAPI{btp: btp, debug: NewPrivateDebugAPI(btp)}
+}
+
+// flatTrace is a single call of a transaction, positioned within the call tree
+// of the transaction by its trace address.
+type flatTrace struct {
+	Action              interface{} `json:"action"`
+	BlockHash           common.Hash `json:"blockHash"`
+	BlockNumber         uint64      `json:"blockNumber"`
+	Error               string      `json:"error,omitempty"`
+	Result              interface{} `json:"result"`
+	Subtraces           int         `json:"subtraces"`
+	TraceAddress        []int       `json:"traceAddress"`
+	TransactionHash     common.Hash `json:"transactionHash"`
+	TransactionPosition uint64      `json:"transactionPosition"`
+	Type                string      `json:"type"`
+
+	from common.Address // Account initiating the call, for filtering
+	to   common.Address // Account targeted by the call, for filtering
+}
+
+// traceCallAction is the action of a message call trace.
+type traceCallAction struct {
+	CallType string         `json:"callType"`
+	From     common.Address `json:"from"`
+	To       common.Address `json:"to"`
+	Gas      hexutil.Uint64 `json:"gas"`
+	Input    hexutil.Bytes  `json:"input"`
+	Value    *hexutil.Big   `json:"value"`
+}
+
+// traceCallResult is the result of a successful message call trace.
+type traceCallResult struct {
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Output  hexutil.Bytes  `json:"output"`
+}
+
+// traceCreateAction is the action of a contract creation trace.
+type traceCreateAction struct {
+	From  common.Address `json:"from"`
+	Gas   hexutil.Uint64 `json:"gas"`
+	Init  hexutil.Bytes  `json:"init"`
+	Value *hexutil.Big   `json:"value"`
+}
+
+// traceCreateResult is the result of a successful contract creation trace.
+type traceCreateResult struct {
+	Address common.Address `json:"address"`
+	Code    hexutil.Bytes  `json:"code"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+}
+
+// traceSuicideAction is the action of a self destruct trace.
+type traceSuicideAction struct {
+	Address       common.Address `json:"address"`
+	RefundAddress common.Address `json:"refundAddress"`
+	Balance       *hexutil.Big   `json:"balance"`
+}
+
+// TraceFilterArgs are the criteria to filter the call traces of a block range
+// with. Traces are matched if they originate from any of the from addresses and
+// target any of the to addresses, empty lists matching all accounts.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       uint64           `json:"after"` // Number of matching traces to skip
+	Count       *uint64          `json:"count"` // Maximum number of traces to return
+}
+
+// Block returns the call traces of all the transactions within a block. If the
+// state of the parent block is not available, at most reexec blocks (or 128 by
+// default) are reexecuted to regenerate it.
+func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber, reexec *uint64) ([]*flatTrace, error) {
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.traceBlock(ctx, block, traceReexec(reexec))
+}
+
+// Transaction returns the call traces of a single transaction.
+func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash, reexec *uint64) ([]*flatTrace, error) {
+	tx, blockHash, number, index := rawdb.ReadTransaction(api.btp.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	msg, vmctx, statedb, err := api.debug.computeTxEnv(blockHash, int(index), traceReexec(reexec))
+	if err != nil {
+		return nil, err
+	}
+	frame, err := api.traceTx(msg, vmctx, statedb)
+	if err != nil {
+		return nil, err
+	}
+	traces := flattenCallFrame(frame, nil, nil)
+	for _, trace := range traces {
+		trace.BlockHash, trace.BlockNumber = blockHash, number
+		trace.TransactionHash, trace.TransactionPosition = hash, index
+	}
+	return traces, nil
+}
+
+// Filter returns the call traces within a range of blocks matching the given
+// criteria, skipping the first After of them and returning at most Count.
+func (api *PrivateTraceAPI) Filter(ctx context.Context, args TraceFilterArgs, reexec *uint64) ([]*flatTrace, error) {
+	from, err := api.blockByNumber(rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	to := from
+	if args.FromBlock != nil {
+		if from, err = api.blockByNumber(*args.FromBlock); err != nil {
+			return nil, err
+		}
+	}
+	if args.ToBlock != nil {
+		if to, err = api.blockByNumber(*args.ToBlock); err != nil {
+			return nil, err
+		}
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, fmt.Errorf("invalid block range: fromBlock #%d after toBlock #%d", from.NumberU64(), to.NumberU64())
+	}
+	var (
+		skip   = args.After
+		traces = []*flatTrace{}
+	)
+	for number := from.NumberU64(); number <= to.NumberU64(); number++ {
+		block := api.btp.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		found, err := api.traceBlock(ctx, block, traceReexec(reexec))
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range found {
+			if !args.matches(trace) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			traces = append(traces, trace)
+			if args.Count != nil && uint64(len(traces)) >= *args.Count {
+				return traces, nil
+			}
+		}
+	}
+	return traces, nil
+}
+
+// matches reports whether a call trace satisfies the address criteria.
+func (args *TraceFilterArgs) matches(trace *flatTrace) bool {
+	return containsAddress(args.FromAddress, trace.from) && containsAddress(args.ToAddress, trace.to)
+}
+
+// containsAddress reports whether the address is in the list, an empty list
+// containing all addresses.
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	if len(addresses) == 0 {
+		return true
+	}
+	for _, addr := range addresses {
+		if addr == address {
+			return true
+		}
+	}
+	return false
+}
+
+// blockByNumber retrieves a block from the local chain, or the pending block.
+func (api *PrivateTraceAPI) blockByNumber(number rpc.BlockNumber) (*types.Block, error) {
+	var block *types.Block
+
+	switch number {
+	case rpc.PendingBlockNumber:
+		block = api.btp.miner.PendingBlock()
+	case rpc.LatestBlockNumber:
+		block = api.btp.blockchain.CurrentBlock()
+	default:
+		block = api.btp.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return block, nil
+}
+
+// traceBlock executes all the transactions contained within a block on top of
+// the state of its parent, returning the flat call traces of all of them.
+func (api *PrivateTraceAPI) traceBlock(ctx context.Context, block *types.Block, reexec uint64) ([]*flatTrace, error) {
+	frames, err := api.traceBlockFrames(ctx, block, reexec)
+	if err != nil {
+		return nil, err
+	}
+	traces := []*flatTrace{}
+	for i, tx := range block.Transactions() {
+		start := len(traces)
+		traces = flattenCallFrame(frames[i], nil, traces)
+		for _, trace := range traces[start:] {
+			trace.BlockHash, trace.BlockNumber = block.Hash(), block.NumberU64()
+			trace.TransactionHash, trace.TransactionPosition = tx.Hash(), uint64(i)
+		}
+	}
+	return traces, nil
+}
+
+// traceBlockFrames executes all the transactions contained within a block on top
+// of the state of its parent, returning the call trees of all of them.
+func (api *PrivateTraceAPI) traceBlockFrames(ctx context.Context, block *types.Block, reexec uint64) ([]*tracers.CallFrame, error) {
+	if len(block.Transactions()) == 0 {
+		return nil, nil
+	}
+	parent := api.btp.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, err := api.debug.computeStateDB(parent, reexec)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer = types.MakeSigner(api.btp.blockchain.Config(), block.Number())
+		frames = make([]*tracers.CallFrame, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		msg, _ := tx.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, block.Header(), api.btp.blockchain, nil)
+
+		if frames[i], err = api.traceTx(msg, vmctx, statedb); err != nil {
+			return nil, fmt.Errorf("transaction %#x: %v", tx.Hash(), err)
+		}
+		// Finalize the state so any modifications are written to the trie
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		statedb.Finalise(api.btp.blockchain.Config().IsEIP158(block.Number()))
+	}
+	return frames, nil
+}
+
+// traceTx executes the given message in the provided environment with the native
+// call tracer, returning the call tree of the execution.
+func (api *PrivateTraceAPI) traceTx(message core.Message, vmctx vm.Context, statedb *state.StateDB) (*tracers.CallFrame, error) {
+	tracer := tracers.NewCallTracer()
+	vmenv := vm.NewEVM(vmctx, statedb, api.btp.blockchain.Config(), vm.Config{Debug: true, Tracer: tracer})
+
+	if _, _, _, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas())); err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	return tracer.Result()
+}
+
+// traceReexec returns the number of blocks to reexecute for missing state.
+func traceReexec(reexec *uint64) uint64 {
+	if reexec != nil {
+		return *reexec
+	}
+	return defaultTraceReexec
+}
+
+// flattenCallFrame appends the flat traces of a call and all its subcalls, in
+// depth first order, to the given list. The address is the position of the call
+// within the call tree of the transaction.
+func flattenCallFrame(frame *tracers.CallFrame, address []int, traces []*flatTrace) []*flatTrace {
+	trace := &flatTrace{
+		Error:        frame.Error,
+		Subtraces:    len(frame.Calls),
+		TraceAddress: append([]int{}, address...),
+		from:         frame.From,
+		to:           frame.To,
+	}
+	switch frame.Type {
+	case vm.CREATE, vm.CREATE2:
+		trace.Type = "create"
+		trace.Action = &traceCreateAction{
+			From:  frame.From,
+			Gas:   hexutil.Uint64(frame.Gas),
+			Init:  frame.Input,
+			Value: (*hexutil.Big)(traceValue(frame.Value)),
+		}
+		if frame.Error == "" {
+			trace.Result = &traceCreateResult{
+				Address: frame.To,
+				Code:    frame.Output,
+				GasUsed: hexutil.Uint64(frame.GasUsed),
+			}
+		}
+
+	case vm.SELFDESTRUCT:
+		trace.Type = "suicide"
+		trace.Action = &traceSuicideAction{
+			Address:       frame.From,
+			RefundAddress: frame.To,
+			Balance:       (*hexutil.Big)(traceValue(frame.Value)),
+		}
+
+	default:
+		trace.Type = "call"
+		trace.Action = &traceCallAction{
+			CallType: strings.ToLower(frame.Type.String()),
+			From:     frame.From,
+			To:       frame.To,
+			Gas:      hexutil.Uint64(frame.Gas),
+			Input:    frame.Input,
+			Value:    (*hexutil.Big)(traceValue(frame.Value)),
+		}
+		if frame.Error == "" {
+			trace.Result = &traceCallResult{
+				GasUsed: hexutil.Uint64(frame.GasUsed),
+				Output:  frame.Output,
+			}
+		}
+	}
+	traces = append(traces, trace)
+	for i, call := range frame.Calls {
+		traces = flattenCallFrame(call, append(address, i), traces)
+	}
+	return traces
+}
+
+// traceValue returns the value transferred by a call, zero if none.
+func traceValue(value *big.Int) *big.Int {
+	if value == nil {
+		return new(big.Int)
+	}
+	return value
+}
@@ -0,0 +1,312 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
+	"github.com/btpereum/go-btpereum/consensus/btpash"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/rawdb"
+	"github.com/btpereum/go-btpereum/core/state"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/core/vm"
+	"github.com/btpereum/go-btpereum/crypto"
+	"github.com/btpereum/go-btpereum/btp/tracers"
+	"github.com/btpereum/go-btpereum/params"
+	"github.com/btpereum/go-btpereum/rlp"
+	"github.com/btpereum/go-btpereum/rpc"
+)
+
+// Tests that chain traces keep streaming results after the subscription request
+// itself returned and its context was cancelled.
+func TestTraceChain(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db      = rawdb.NewMemoryDatabase()
+		engine  = btpash.NewFaker()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, engine, db, 4, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{0x01}, big.NewInt(1000), params.TxGas, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, gspec.Config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	// Trace the whole chain through an in-process RPC subscription
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("debug", NewPrivateDebugAPI(&btpereum{config: &DefaultConfig, blockchain: chain, chainDb: db})); err != nil {
+		t.Fatalf("failed to register debug API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	results := make(chan *blockTraceResult)
+	sub, err := client.Subscribe(context.Background(), "debug", results, "traceChain", hexutil.Uint64(0), hexutil.Uint64(len(blocks)))
+	if err != nil {
+		t.Fatalf("failed to subscribe to chain trace: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for i, block := range blocks {
+		select {
+		case result := <-results:
+			if result.Hash != block.Hash() {
+				t.Fatalf("result %d: block mismatch: have %x, want %x", i, result.Hash, block.Hash())
+			}
+			if len(result.Traces) != 1 || result.Traces[0].Error != "" || result.Traces[0].Result == nil {
+				t.Fatalf("result %d: trace mismatch: have %+v", i, result.Traces)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("chain trace subscription failed: %v", err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("result %d: chain trace timed out", i)
+		}
+	}
+}
+
+// Tests that the aggregate limits of a trace default to the node's configured ones
+// and that requested limits are capped at the node's maximums.
+func TestTraceBudgetLimits(t *testing.T) {
+	node := &Config{
+		RPCTraceTimeout:       time.Minute,
+		RPCTraceTimeoutCap:    time.Hour,
+		RPCTraceResultSize:    100,
+		RPCTraceResultSizeCap: 1000,
+		RPCTraceHeapSize:      10,
+		RPCTraceHeapSizeCap:   0,
+	}
+	var (
+		short  = "1s"
+		long   = "2h"
+		none   = "0s"
+		small  = uint64(50)
+		large  = uint64(5000)
+		zero   = uint64(0)
+		larger = uint64(1 << 40)
+	)
+	tests := []struct {
+		config  *TraceConfig
+		timeout time.Duration
+		result  uint64
+		heap    uint64
+	}{
+		// Unset limits default to the node's ones
+		{nil, time.Minute, 100, 10},
+		{&TraceConfig{}, time.Minute, 100, 10},
+		// Requested limits within the caps are honoured
+		{&TraceConfig{TotalTimeout: &short, MaxResultSize: &small, MaxHeapSize: &small}, time.Second, 50, 50},
+		// Requested limits above the caps, or unlimited ones, are capped
+		{&TraceConfig{TotalTimeout: &long, MaxResultSize: &large, MaxHeapSize: &larger}, time.Hour, 1000, 1 << 40},
+		{&TraceConfig{TotalTimeout: &none, MaxResultSize: &zero, MaxHeapSize: &zero}, time.Hour, 1000, 0},
+	}
+	for i, tt := range tests {
+		timeout, result, heap, err := traceBudgetLimits(node, tt.config)
+		if err != nil {
+			t.Errorf("test %d: failed to resolve limits: %v", i, err)
+			continue
+		}
+		if timeout != tt.timeout || result != tt.result || heap != tt.heap {
+			t.Errorf("test %d: limits mismatch: have %v/%d/%d, want %v/%d/%d", i, timeout, result, heap, tt.timeout, tt.result, tt.heap)
+		}
+	}
+}
+
+// Tests that call trees are flattened in depth first order with the correct
+// trace addresses and subtrace counts.
+func TestFlattenCallFrame(t *testing.T) {
+	var (
+		a = common.HexToAddress("0x0a")
+		b = common.HexToAddress("0x0b")
+		c = common.HexToAddress("0x0c")
+		d = common.HexToAddress("0x0d")
+	)
+	root := &tracers.CallFrame{
+		Type: vm.CALL, From: a, To: b, Value: big.NewInt(1),
+		Calls: []*tracers.CallFrame{
+			{
+				Type: vm.CREATE, From: b, To: c, Value: new(big.Int),
+				Calls: []*tracers.CallFrame{
+					{Type: vm.SELFDESTRUCT, From: c, To: a, Value: big.NewInt(2)},
+				},
+			},
+			{Type: vm.DELEGATECALL, From: b, To: d, Error: "execution reverted"},
+		},
+	}
+	traces := flattenCallFrame(root, nil, nil)
+
+	want := []struct {
+		typ       string
+		address   []int
+		subtraces int
+		from, to  common.Address
+		result    bool
+	}{
+		{"call", []int{}, 2, a, b, true},
+		{"create", []int{0}, 1, b, c, true},
+		{"suicide", []int{0, 0}, 0, c, a, false},
+		{"call", []int{1}, 0, b, d, false},
+	}
+	if len(traces) != len(want) {
+		t.Fatalf("trace count mismatch: have %d, want %d", len(traces), len(want))
+	}
+	for i, trace := range traces {
+		if trace.Type != want[i].typ {
+			t.Errorf("trace %d: type mismatch: have %s, want %s", i, trace.Type, want[i].typ)
+		}
+		if !reflect.DeepEqual(trace.TraceAddress, want[i].address) {
+			t.Errorf("trace %d: address mismatch: have %v, want %v", i, trace.TraceAddress, want[i].address)
+		}
+		if trace.Subtraces != want[i].subtraces {
+			t.Errorf("trace %d: subtraces mismatch: have %d, want %d", i, trace.Subtraces, want[i].subtraces)
+		}
+		if trace.from != want[i].from || trace.to != want[i].to {
+			t.Errorf("trace %d: accounts mismatch: have %x->%x, want %x->%x", i, trace.from, trace.to, want[i].from, want[i].to)
+		}
+		if (trace.Result != nil) != want[i].result {
+			t.Errorf("trace %d: result presence mismatch: have %v, want %v", i, trace.Result != nil, want[i].result)
+		}
+	}
+	if action := traces[3].Action.(*traceCallAction); action.CallType != "delegatecall" || action.Value.ToInt().Sign() != 0 {
+		t.Errorf("delegate call action mismatch: have %s with value %v", action.CallType, action.Value)
+	}
+	// Ensure the filter criteria are applied to the flattened traces
+	args := &TraceFilterArgs{FromAddress: []common.Address{b}}
+	var matched int
+	for _, trace := range traces {
+		if args.matches(trace) {
+			matched++
+		}
+	}
+	if matched != 2 {
+		t.Errorf("filtered trace count mismatch: have %d, want %d", matched, 2)
+	}
+}
+
+// Tests that the trace indexer persists the state of the indexed section heads,
+// so that it can resume after a restart without regenerating the state of a
+// pruned node from genesis.
+func TestTraceIndexerResume(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		engine  = btpash.NewFaker()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, engine, db, traceIndexSectionSize+1, func(i int, b *core.BlockGen) {})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	btp := &btpereum{config: &DefaultConfig, blockchain: chain, chainDb: db}
+	head := blocks[traceIndexSectionSize-2]
+	if _, err := state.New(head.Root(), state.NewDatabase(db)); err == nil {
+		t.Fatalf("section head state persisted by the pruning chain")
+	}
+	// Index the first section and ensure its head state gets persisted
+	indexer := &TraceIndexer{btp: btp, debug: NewPrivateDebugAPI(btp), db: db}
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	if err := indexer.Process(context.Background(), genesis.Header()); err != nil {
+		t.Fatalf("failed to process genesis: %v", err)
+	}
+	for _, block := range blocks[:traceIndexSectionSize-1] {
+		if err := indexer.Process(context.Background(), block.Header()); err != nil {
+			t.Fatalf("failed to process block %d: %v", block.NumberU64(), err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit section: %v", err)
+	}
+	if _, err := state.New(head.Root(), state.NewDatabase(db)); err != nil {
+		t.Fatalf("section head state not persisted: %v", err)
+	}
+	// Resume the next section with a fresh indexer, as after a restart
+	indexer = &TraceIndexer{btp: btp, debug: NewPrivateDebugAPI(btp), db: db}
+	if err := indexer.Reset(context.Background(), 1, head.Hash()); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	if err := indexer.Process(context.Background(), blocks[traceIndexSectionSize-1].Header()); err != nil {
+		t.Fatalf("failed to resume indexing: %v", err)
+	}
+}
+
+// Tests that the internal transactions of a call tree are gathered without the
+// top level call, and survive the encoding of the trace index.
+func TestCollectInternalTxs(t *testing.T) {
+	var (
+		a    = common.HexToAddress("0x0a")
+		b    = common.HexToAddress("0x0b")
+		c    = common.HexToAddress("0x0c")
+		hash = common.HexToHash("0x01")
+	)
+	root := &tracers.CallFrame{
+		Type: vm.CALL, From: a, To: b, Value: big.NewInt(1),
+		Calls: []*tracers.CallFrame{
+			{Type: vm.STATICCALL, From: b, To: c},
+			{
+				Type: vm.CALL, From: b, To: c, Value: big.NewInt(3),
+				Calls: []*tracers.CallFrame{
+					{Type: vm.SELFDESTRUCT, From: c, To: a, Value: big.NewInt(5)},
+				},
+			},
+		},
+	}
+	txs := collectInternalTxs(root, nil, 10, hash, 2, nil)
+	if len(txs) != 3 {
+		t.Fatalf("internal transaction count mismatch: have %d, want %d", len(txs), 3)
+	}
+	blob, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		t.Fatalf("failed to encode internal transactions: %v", err)
+	}
+	var decoded []*internalTx
+	if err := rlp.DecodeBytes(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode internal transactions: %v", err)
+	}
+	want := []*internalTx{
+		{BlockNumber: 10, TxHash: hash, TxIndex: 2, TraceAddress: []uint64{0}, Type: vm.STATICCALL, From: b, To: c, Value: new(big.Int)},
+		{BlockNumber: 10, TxHash: hash, TxIndex: 2, TraceAddress: []uint64{1}, Type: vm.CALL, From: b, To: c, Value: big.NewInt(3)},
+		{BlockNumber: 10, TxHash: hash, TxIndex: 2, TraceAddress: []uint64{1, 0}, Type: vm.SELFDESTRUCT, From: c, To: a, Value: big.NewInt(5)},
+	}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("internal transaction mismatch:\nhave %+v\nwant %+v", decoded, want)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -57,9 +58,16 @@ const (
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*vm.LogConfig
-	Tracer  *string
-	Timeout *string
-	Reexec  *uint64
+	Tracer    *string
+	Timeout   *string
+	Reexec    *uint64
+	StateDiff bool // Report the state changes of each transaction instead of tracing
+
+	// Aggregate limits of a block or chain trace across all its transactions, each
+	// of them defaulting to and capped at the limits configured on the node
+	TotalTimeout  *string // Total wall time allowance of the trace
+	MaxResultSize *uint64 // Maximum total size of the trace results in bytes
+	MaxHeapSize   *uint64 // Maximum total size of the live JavaScript tracer heaps in bytes
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -71,8 +79,79 @@ type StdTraceConfig struct {
 
 // txTraceResult is the result of a single transaction trace.
 type txTraceResult struct {
-	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
-	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
+	Result    interface{} `json:"result,omitempty"`    // Trace results produced by the tracer
+	Error     string      `json:"error,omitempty"`     // Trace failure produced by the tracer
+	Truncated bool        `json:"truncated,omitempty"` // Whbtper the trace budget ran out
+}
+
+// newTxTraceResult assembles the result of a single transaction trace, charging
+// its size against the aggregate budget. If the budget ran out, the result is
+// flagged as truncated, retaining whatever the tracer managed to produce unless
+// the result size itself exhausted the budget.
+func newTxTraceResult(budget *tracers.Budget, res interface{}, err error) *txTraceResult {
+	if exhausted := budget.Err(); exhausted != nil {
+		result := &txTraceResult{Error: exhausted.Error(), Truncated: true}
+		if exhausted != tracers.ErrBudgetResultSize {
+			result.Result = res
+		}
+		return result
+	}
+	if err != nil {
+		return &txTraceResult{Error: err.Error()}
+	}
+	blob, err := json.Marshal(res)
+	if err != nil {
+		return &txTraceResult{Error: err.Error()}
+	}
+	if err := budget.Charge(len(blob)); err != nil {
+		return &txTraceResult{Error: err.Error(), Truncated: true}
+	}
+	return &txTraceResult{Result: json.RawMessage(blob)}
+}
+
+// newTraceBudget creates the aggregate resource budget of a block or chain trace,
+// within the trace limits configured on the node.
+func (api *PrivateDebugAPI) newTraceBudget(ctx context.Context, config *TraceConfig) (*tracers.Budget, error) {
+	timeout, maxResult, maxHeap, err := traceBudgetLimits(api.btp.config, config)
+	if err != nil {
+		return nil, err
+	}
+	return tracers.NewBudget(ctx, timeout, maxResult, maxHeap), nil
+}
+
+// traceBudgetLimits resolves the aggregate limits of a trace. Limits not set in
+// the trace config default to the node's configured ones, and any limit is capped
+// at the node's configured maximum. Zero values are unlimited.
+func traceBudgetLimits(node *Config, config *TraceConfig) (time.Duration, uint64, uint64, error) {
+	var (
+		timeout   = node.RPCTraceTimeout
+		maxResult = node.RPCTraceResultSize
+		maxHeap   = node.RPCTraceHeapSize
+	)
+	if config != nil {
+		if config.TotalTimeout != nil {
+			var err error
+			if timeout, err = time.ParseDuration(*config.TotalTimeout); err != nil {
+				return 0, 0, 0, err
+			}
+		}
+		if config.MaxResultSize != nil {
+			maxResult = *config.MaxResultSize
+		}
+		if config.MaxHeapSize != nil {
+			maxHeap = *config.MaxHeapSize
+		}
+	}
+	if limit := node.RPCTraceTimeoutCap; limit > 0 && (timeout <= 0 || timeout > limit) {
+		timeout = limit
+	}
+	if limit := node.RPCTraceResultSizeCap; limit > 0 && (maxResult == 0 || maxResult > limit) {
+		maxResult = limit
+	}
+	if limit := node.RPCTraceHeapSizeCap; limit > 0 && (maxHeap == 0 || maxHeap > limit) {
+		maxHeap = limit
+	}
+	return timeout, maxResult, maxHeap, nil
 }
 
 // blockTraceTask represents a single block trace task when an entire chain is
@@ -182,7 +261,13 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 			}
 		}
 	}
-	// Execute all the transaction contained within the chain concurrently for each block
+	// Execute all the transaction contained within the chain concurrently for each
+	// block. The request context is cancelled as soon as the subscription is set up,
+	// so the budget is detached from it and teardown is tracked via the notifier.
+	budget, err := api.newTraceBudget(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
 	blocks := int(end.NumberU64() - origin)
 
 	threads := runtime.NumCPU()
@@ -208,21 +293,21 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 					msg, _ := tx.AsMessage(signer)
 					vmctx := core.NewEVMContext(msg, task.block.Header(), api.btp.blockchain, nil)
 
-					res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
-					if err != nil {
-						task.results[i] = &txTraceResult{Error: err.Error()}
-						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
+					res, err := api.traceTx(budget.Context(), msg, vmctx, task.statedb, config, budget)
+					if task.results[i] = newTxTraceResult(budget, res, err); task.results[i].Error != "" {
+						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", task.results[i].Error)
 						break
 					}
 					// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
 					task.statedb.Finalise(api.btp.blockchain.Config().IsEIP158(task.block.Number()))
-					task.results[i] = &txTraceResult{Result: res}
 				}
 				// Stream the result back to the user or abort on teardown
 				select {
 				case results <- task:
 				case <-notifier.Closed():
 					return
+				case <-sub.Err():
+					return
 				}
 			}
 		}()
@@ -246,19 +331,26 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 			switch {
 			case failed != nil:
 				log.Warn("Chain tracing failed", "start", start.NumberU64(), "end", end.NumberU64(), "transactions", traced, "elapsed", time.Since(begin), "err", failed)
+			case budget.Err() != nil:
+				log.Warn("Chain tracing truncated", "start", start.NumberU64(), "end", end.NumberU64(), "abort", number, "transactions", traced, "elapsed", time.Since(begin), "err", budget.Err())
 			case number < end.NumberU64():
 				log.Warn("Chain tracing aborted", "start", start.NumberU64(), "end", end.NumberU64(), "abort", number, "transactions", traced, "elapsed", time.Since(begin))
 			default:
 				log.Info("Chain tracing finished", "start", start.NumberU64(), "end", end.NumberU64(), "transactions", traced, "elapsed", time.Since(begin))
 			}
+			budget.Release()
 			close(results)
 		}()
 		// Feed all the blocks both into the tracer, as well as fast process concurrently
 		for number = start.NumberU64() + 1; number <= end.NumberU64(); number++ {
-			// Stop tracing if interruption was requested
+			// Stop tracing if interruption was requested or the budget ran out
 			select {
 			case <-notifier.Closed():
 				return
+			case <-sub.Err():
+				return
+			case <-budget.Context().Done():
+				return
 			default:
 			}
 			// Print progress logs if long enough time elapsed
@@ -285,6 +377,10 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 				case tasks <- &blockTraceTask{statedb: statedb.Copy(), block: block, rootref: proot, results: make([]*txTraceResult, len(txs))}:
 				case <-notifier.Closed():
 					return
+				case <-sub.Err():
+					return
+				case <-budget.Context().Done():
+					return
 				}
 				traced += uint64(len(txs))
 			}
@@ -405,13 +501,11 @@ func (api *PrivateDebugAPI) TraceBlockFromFile(ctx context.Context, file string,
 // EVM against a block pulled from the pool of bad ones and returns them as a JSON
 // object.
 func (api *PrivateDebugAPI) TraceBadBlock(ctx context.Context, hash common.Hash, config *TraceConfig) ([]*txTraceResult, error) {
-	blocks := api.btp.blockchain.BadBlocks()
-	for _, block := range blocks {
-		if block.Hash() == hash {
-			return api.traceBlock(ctx, block, config)
-		}
+	bad := rawdb.ReadBadBlock(api.btp.ChainDb(), hash)
+	if bad == nil {
+		return nil, fmt.Errorf("bad block %#x not found", hash)
 	}
-	return nil, fmt.Errorf("bad block %#x not found", hash)
+	return api.traceBlock(ctx, bad.Block, config)
 }
 
 // StandardTraceBlockToFile dumps the structured logs created during the
@@ -429,13 +523,11 @@ func (api *PrivateDebugAPI) StandardTraceBlockToFile(ctx context.Context, hash c
 // execution of EVM against a block pulled from the pool of bad ones to the
 // local file system and returns a list of files to the caller.
 func (api *PrivateDebugAPI) StandardTraceBadBlockToFile(ctx context.Context, hash common.Hash, config *StdTraceConfig) ([]string, error) {
-	blocks := api.btp.blockchain.BadBlocks()
-	for _, block := range blocks {
-		if block.Hash() == hash {
-			return api.standardTraceBlockToFile(ctx, block, config)
-		}
+	bad := rawdb.ReadBadBlock(api.btp.ChainDb(), hash)
+	if bad == nil {
+		return nil, fmt.Errorf("bad block %#x not found", hash)
 	}
-	return nil, fmt.Errorf("bad block %#x not found", hash)
+	return api.standardTraceBlockToFile(ctx, bad.Block, config)
 }
 
 // traceBlock configures a new tracer according to the provided configuration, and
@@ -458,6 +550,12 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	if err != nil {
 		return nil, err
 	}
+	budget, err := api.newTraceBudget(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer budget.Release()
+
 	// Execute all the transaction contained within the block concurrently
 	var (
 		signer = types.MakeSigner(api.btp.blockchain.Config(), block.Number())
@@ -482,18 +580,23 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 				msg, _ := txs[task.index].AsMessage(signer)
 				vmctx := core.NewEVMContext(msg, block.Header(), api.btp.blockchain, nil)
 
-				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
-				if err != nil {
-					results[task.index] = &txTraceResult{Error: err.Error()}
+				// Skip tracing altogether if the budget already ran out
+				if err := budget.Err(); err != nil {
+					results[task.index] = &txTraceResult{Error: err.Error(), Truncated: true}
 					continue
 				}
-				results[task.index] = &txTraceResult{Result: res}
+				res, err := api.traceTx(budget.Context(), msg, vmctx, task.statedb, config, budget)
+				results[task.index] = newTxTraceResult(budget, res, err)
 			}
 		}()
 	}
 	// Feed the transactions into the tracers and return
 	var failed error
 	for i, tx := range txs {
+		// Stop preparing states if the budget ran out, the rest will be truncated
+		if budget.Err() != nil {
+			break
+		}
 		// Send the trace task over for execution
 		jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
 
@@ -517,6 +620,12 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	if failed != nil {
 		return nil, failed
 	}
+	// Flag any transactions never scheduled due to the budget as truncated
+	for i, result := range results {
+		if result == nil {
+			results[i] = &txTraceResult{Error: budget.Err().Error(), Truncated: true}
+		}
+	}
 	return results, nil
 }
 
@@ -716,13 +825,16 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 		return nil, err
 	}
 	// Trace the transaction and return
-	return api.traceTx(ctx, msg, vmctx, statedb, config)
+	return api.traceTx(ctx, msg, vmctx, statedb, config, nil)
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
-func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig, budget *tracers.Budget) (interface{}, error) {
+	if config != nil && config.StateDiff {
+		return api.diffTx(message, vmctx, statedb)
+	}
 	// Assemble the structured logger or the JavaScript tracer
 	var (
 		tracer vm.Tracer
@@ -741,10 +853,17 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 		if tracer, err = tracers.New(*config.Tracer); err != nil {
 			return nil, err
 		}
-		// Handle timeouts and RPC cancellations
+		if budget != nil {
+			tracer.(*tracers.Tracer).SetBudget(budget)
+		}
+		// Handle timeouts, RPC cancellations and aggregate budget exhaustion
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
+			if budget != nil && budget.Err() != nil {
+				tracer.(*tracers.Tracer).Stop(budget.Err())
+				return
+			}
 			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
@@ -755,8 +874,13 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	default:
 		tracer = vm.NewStructLogger(config.LogConfig)
 	}
+	// Interrupt structured logging too once the aggregate budget runs out
+	evmTracer := tracer
+	if logger, ok := tracer.(*vm.StructLogger); ok && budget != nil {
+		evmTracer = tracers.WithBudget(logger, budget)
+	}
 	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(vmctx, statedb, api.btp.blockchain.Config(), vm.Config{Debug: true, Tracer: tracer})
+	vmenv := vm.NewEVM(vmctx, statedb, api.btp.blockchain.Config(), vm.Config{Debug: true, Tracer: evmTracer})
 
 	ret, gas, failed, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
@@ -780,6 +904,17 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	}
 }
 
+// diffTx executes the given message without tracing and reports the account
+// modifications it made, as recorded by the state journal.
+func (api *PrivateDebugAPI) diffTx(message core.Message, vmctx vm.Context, statedb *state.StateDB) (state.StateDiff, error) {
+	vmenv := vm.NewEVM(vmctx, statedb, api.btp.blockchain.Config(), vm.Config{})
+
+	if _, _, _, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas())); err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	return statedb.Diff(), nil
+}
+
 // computeTxEnv returns the execution environment of a certain transaction.
 func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, reexec uint64) (core.Message, vm.Context, *state.StateDB, error) {
 	// Create the parent state database
@@ -86,6 +86,8 @@ type btpereum struct {
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	traceIndexer  *core.ChainIndexer             // Internal transaction indexer, nil if disabled
+	debugSessions *debugSessions                 // Live step debugging sessions
 
 	APIBackend *btpAPIBackend
 
@@ -198,20 +200,30 @@ func New(ctx *node.ServiceContext, config *Config) (*btpereum, error) {
 	}
 	btp.bloomIndexer.Start(btp.blockchain)
 
+	if config.TraceIndex {
+		btp.traceIndexer = NewTraceIndexer(btp)
+		btp.traceIndexer.Start(btp.blockchain)
+	}
+	btp.debugSessions = newDebugSessions()
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = ctx.ResolvePath(config.TxPool.Snapshot)
+	}
 	btp.txPool = core.NewTxPool(config.TxPool, chainConfig, btp.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit
-	checkpoint := config.Checkpoint
-	if checkpoint == nil {
-		checkpoint = params.TrustedCheckpoints[genesisHash]
+	checkpoint, err := resolveCheckpoint(config, btp.blockchain)
+	if err != nil {
+		return nil, err
 	}
 	if btp.protocolManager, err = NewProtocolManager(chainConfig, checkpoint, config.SyncMode, config.NetworkId, btp.eventMux, btp.txPool, btp.engine, btp.blockchain, chainDb, cacheLimit, config.Whitelist); err != nil {
 		return nil, err
 	}
+	btp.protocolManager.budget = newServingBudget(config.ServeRequestRate, config.ServePeerBandwidth, config.ServeTotalBandwidth)
 	btp.miner = miner.New(btp, &config.Miner, chainConfig, btp.EventMux(), btp.engine, btp.isLocalBlock)
 	btp.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
@@ -330,6 +342,10 @@ func (s *btpereum) APIs() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   NewPrivateDebugAPI(s),
+		}, {
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewPrivateTraceAPI(s),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -505,6 +521,31 @@ func (s *btpereum) Downloader() *downloader.Downloader { return s.protocolManage
 func (s *btpereum) Synced() bool                       { return atomic.LoadUint32(&s.protocolManager.acceptTxs) == 1 }
 func (s *btpereum) ArchiveMode() bool                  { return s.config.NoPruning }
 
+// SafeHeader retrieves the most recent canonical header with at least the configured
+// number of confirmations on top of it.
+func (s *btpereum) SafeHeader() (*types.Header, error) {
+	head := s.blockchain.CurrentBlock().NumberU64()
+	if head < s.config.RPCSafeDepth {
+		return s.blockchain.Genesis().Header(), nil
+	}
+	// The chain may be rewound concurrently, leaving the number without a header
+	header := s.blockchain.GbtpeaderByNumber(head - s.config.RPCSafeDepth)
+	if header == nil {
+		return nil, errors.New("safe block unavailable")
+	}
+	return header, nil
+}
+
+// FinalizedHeader retrieves the most recent canonical header the consensus engine
+// considers final. Only clique networks support finality for now.
+func (s *btpereum) FinalizedHeader() (*types.Header, error) {
+	engine, ok := s.engine.(*clique.Clique)
+	if !ok {
+		return nil, errors.New("finality not supported by the consensus engine")
+	}
+	return engine.FinalizedHeader(s.blockchain, s.blockchain.CurrentBlock().Header())
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *btpereum) Protocols() []p2p.Protocol {
@@ -539,6 +580,7 @@ func (s *btpereum) Start(srvr *p2p.Server) error {
 		maxPeers -= s.config.LightPeers
 	}
 	// Start the networking layer and the light server if requested
+	s.protocolManager.scores.SetDatabase(srvr.NodeDB())
 	s.protocolManager.Start(maxPeers)
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
@@ -550,6 +592,10 @@ func (s *btpereum) Start(srvr *p2p.Server) error {
 // btpereum protocol.
 func (s *btpereum) Stop() error {
 	s.bloomIndexer.Close()
+	if s.traceIndexer != nil {
+		s.traceIndexer.Close()
+	}
+	s.debugSessions.close()
 	s.blockchain.Stop()
 	s.engine.Close()
 	s.protocolManager.Stop()
@@ -0,0 +1,213 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/btpereum/go-btpereum"
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/contracts/checkpointoracle/contract"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/core/vm"
+	"github.com/btpereum/go-btpereum/crypto"
+	"github.com/btpereum/go-btpereum/log"
+	"github.com/btpereum/go-btpereum/params"
+)
+
+var (
+	errCheckpointUnsigned = errors.New("checkpoint signatures given without a checkpoint")
+	errCheckpointNoOracle = errors.New("checkpoint oracle not configured")
+	errCheckpointNoAnchor = errors.New("checkpoint oracle has no registered checkpoint")
+)
+
+// resolveCheckpoint determines the trusted checkpoint the chain sync is anchored
+// to. Without checkpoint syncing enabled, the configured or hard coded checkpoint
+// is used as is. Otherwise the checkpoint must either carry enough signatures from
+// the oracle's signers, or exactly match the one registered in the oracle contract.
+//
+// Signed checkpoints are verified offline, so they are the way to anchor a fresh
+// node. The oracle contract can only be read if the local chain already has its
+// state; if it doesn't, the checkpoint is used unverified as if checkpoint syncing
+// was disabled. Any other failure to establish the anchor is a hard error.
+func resolveCheckpoint(config *Config, chain *core.BlockChain) (*params.TrustedCheckpoint, error) {
+	checkpoint := config.Checkpoint
+	if checkpoint == nil {
+		checkpoint = params.TrustedCheckpoints[chain.Genesis().Hash()]
+	}
+	if !config.CheckpointSync {
+		return checkpoint, nil
+	}
+	if config.CheckpointOracle == nil {
+		return nil, errCheckpointNoOracle
+	}
+	// If a signed checkpoint was configured, verify it offline
+	if len(config.CheckpointSignatures) > 0 {
+		if config.Checkpoint == nil {
+			return nil, errCheckpointUnsigned
+		}
+		sigs := make([][]byte, len(config.CheckpointSignatures))
+		for i, sig := range config.CheckpointSignatures {
+			sigs[i] = sig
+		}
+		if err := verifyCheckpoint(config.CheckpointOracle, config.Checkpoint, sigs); err != nil {
+			return nil, err
+		}
+		if err := checkCanonical(chain, config.Checkpoint); err != nil {
+			return nil, err
+		}
+		log.Info("Anchoring sync to signed checkpoint", "section", config.Checkpoint.SectionIndex, "head", config.Checkpoint.SectionHead)
+		return config.Checkpoint, nil
+	}
+	// Otherwise cross check the checkpoint against the oracle contract, if available
+	caller := &stateCaller{chain: chain}
+	if code, err := caller.CodeAt(context.Background(), config.CheckpointOracle.Address, nil); err != nil || len(code) == 0 {
+		log.Warn("Checkpoint oracle not in local state, sync not anchored", "oracle", config.CheckpointOracle.Address, "hint", "use --checkpoint.signatures")
+		return checkpoint, nil
+	}
+	oracle, err := contract.NewCheckpointOracleCaller(config.CheckpointOracle.Address, caller)
+	if err != nil {
+		return nil, err
+	}
+	index, hash, height, err := oracle.GetLatestCheckpoint(nil)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint oracle unavailable: %v", err)
+	}
+	if err := matchOracleCheckpoint(checkpoint, index, hash, height); err != nil {
+		return nil, err
+	}
+	if err := checkCanonical(chain, checkpoint); err != nil {
+		return nil, err
+	}
+	log.Info("Anchoring sync to oracle checkpoint", "section", checkpoint.SectionIndex, "head", checkpoint.SectionHead)
+	return checkpoint, nil
+}
+
+// matchOracleCheckpoint checks that the local checkpoint is the latest one the
+// oracle approved. Older checkpoints are rejected too, as the oracle only retains
+// the latest one there is no way to tell whbtper an older one was ever approved.
+func matchOracleCheckpoint(checkpoint *params.TrustedCheckpoint, index uint64, hash [32]byte, height *big.Int) error {
+	switch {
+	case index == 0 && height.Sign() == 0:
+		return errCheckpointNoAnchor
+	case checkpoint == nil:
+		return fmt.Errorf("oracle checkpoint #%d unknown locally, configure its content", index)
+	case checkpoint.SectionIndex != index:
+		return fmt.Errorf("local checkpoint #%d not the oracle approved #%d", checkpoint.SectionIndex, index)
+	case checkpoint.Hash() != common.Hash(hash):
+		return fmt.Errorf("local checkpoint #%d mismatches oracle: have %x, want %x", index, checkpoint.Hash(), hash)
+	}
+	return nil
+}
+
+// checkCanonical ensures that if the local chain already passed the checkpoint,
+// the checkpoint's section head is part of it.
+func checkCanonical(chain *core.BlockChain, checkpoint *params.TrustedCheckpoint) error {
+	number := (checkpoint.SectionIndex+1)*params.CHTFrequency - 1
+	if chain.CurrentHeader().Number.Uint64() < number {
+		return nil
+	}
+	if header := chain.GbtpeaderByNumber(number); header == nil || header.Hash() != checkpoint.SectionHead {
+		return fmt.Errorf("local chain diverges from checkpoint #%d at block %d", checkpoint.SectionIndex, number)
+	}
+	return nil
+}
+
+// verifyCheckpoint checks that a checkpoint is signed by at least the threshold
+// number of distinct signers of the checkpoint oracle.
+func verifyCheckpoint(config *params.CheckpointOracleConfig, checkpoint *params.TrustedCheckpoint, sigs [][]byte) error {
+	var (
+		hash    = checkpointSighash(checkpoint.SectionIndex, config.Address, checkpoint.Hash())
+		signers = make(map[common.Address]struct{})
+	)
+	for _, signer := range config.Signers {
+		signers[signer] = struct{}{}
+	}
+	approved := make(map[common.Address]struct{})
+	for i, sig := range sigs {
+		if len(sig) != 65 {
+			return fmt.Errorf("checkpoint signature %d: invalid length %d", i, len(sig))
+		}
+		// Signatures are in the "btpereum style" with v being 27/28
+		plain := common.CopyBytes(sig)
+		plain[64] -= 27
+
+		pubkey, err := crypto.SigToPub(hash, plain)
+		if err != nil {
+			return fmt.Errorf("checkpoint signature %d: %v", i, err)
+		}
+		signer := crypto.PubkeyToAddress(*pubkey)
+		if _, ok := signers[signer]; !ok {
+			return fmt.Errorf("checkpoint signature %d: unauthorized signer %x", i, signer)
+		}
+		approved[signer] = struct{}{}
+	}
+	if uint64(len(approved)) < config.Threshold {
+		return fmt.Errorf("checkpoint signed by %d signers, need %d", len(approved), config.Threshold)
+	}
+	return nil
+}
+
+// checkpointSighash calculates the hash of the data signed by the checkpoint
+// oracle signers (EIP-191 data with intended validator).
+func checkpointSighash(index uint64, oracle common.Address, hash common.Hash) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+
+	data := append([]byte{0x19, 0x00}, append(oracle[:], append(buf, hash[:]...)...)...)
+	return crypto.Keccak256(data)
+}
+
+// stateCaller is a bind.ContractCaller executing read only contract calls on top
+// of the head state of the local chain.
+type stateCaller struct {
+	chain *core.BlockChain
+}
+
+// CodeAt returns the code of the given account in the head state.
+func (c *stateCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	statedb, err := c.chain.State()
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(contract), nil
+}
+
+// CallContract executes a contract call on top of the head state.
+func (c *stateCaller) CallContract(ctx context.Context, call btpereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	head := c.chain.CurrentBlock()
+	statedb, err := c.chain.StateAt(head.Root())
+	if err != nil {
+		return nil, err
+	}
+	msg := types.NewMessage(call.From, call.To, 0, new(big.Int), math.MaxUint64/2, new(big.Int), call.Data, false)
+
+	context := core.NewEVMContext(msg, head.Header(), c.chain, nil)
+	evm := vm.NewEVM(context, statedb, c.chain.Config(), vm.Config{})
+
+	ret, _, failed, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	if err == nil && failed {
+		err = errors.New("contract call reverted")
+	}
+	return ret, err
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
+	"github.com/btpereum/go-btpereum/consensus/btpash"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/rawdb"
+	"github.com/btpereum/go-btpereum/core/vm"
+	"github.com/btpereum/go-btpereum/crypto"
+	"github.com/btpereum/go-btpereum/params"
+)
+
+// Tests that signed checkpoints are only accepted if approved by enough of the
+// oracle's signers.
+func TestVerifyCheckpoint(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	oracle := &params.CheckpointOracleConfig{
+		Address: common.HexToAddress("0xdeadbeef"),
+		Signers: []common.Address{
+			crypto.PubkeyToAddress(keys[0].PublicKey),
+			crypto.PubkeyToAddress(keys[1].PublicKey),
+		},
+		Threshold: 2,
+	}
+	checkpoint := &params.TrustedCheckpoint{
+		SectionIndex: 1,
+		SectionHead:  common.HexToHash("0x01"),
+		CHTRoot:      common.HexToHash("0x02"),
+		BloomRoot:    common.HexToHash("0x03"),
+	}
+	sign := func(key *ecdsa.PrivateKey) []byte {
+		sig, _ := crypto.Sign(checkpointSighash(checkpoint.SectionIndex, oracle.Address, checkpoint.Hash()), key)
+		sig[64] += 27
+		return sig
+	}
+	tests := []struct {
+		sigs [][]byte
+		ok   bool
+	}{
+		{[][]byte{sign(keys[0]), sign(keys[1])}, true},  // Threshold reached
+		{[][]byte{sign(keys[0])}, false},                // Threshold missed
+		{[][]byte{sign(keys[0]), sign(keys[0])}, false}, // Duplicate signer
+		{[][]byte{sign(keys[0]), sign(keys[2])}, false}, // Unauthorized signer
+		{[][]byte{sign(keys[0]), {0x01}}, false},        // Malformed signature
+	}
+	for i, tt := range tests {
+		if err := verifyCheckpoint(oracle, checkpoint, tt.sigs); (err == nil) != tt.ok {
+			t.Errorf("test %d: verification mismatch: have %v, want ok=%v", i, err, tt.ok)
+		}
+	}
+}
+
+// Tests that checkpoint syncing fails closed if the anchor cannot be established,
+// while staying a no-op if checkpoint syncing is disabled and falling back to it
+// if the oracle contract is not yet available locally.
+func TestResolveCheckpointFailsClosed(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+	)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, btpash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	checkpoint := &params.TrustedCheckpoint{SectionIndex: 1, SectionHead: genesis.Hash()}
+	oracle := &params.CheckpointOracleConfig{
+		Address:   common.HexToAddress("0xdeadbeef"),
+		Signers:   []common.Address{common.HexToAddress("0x01")},
+		Threshold: 1,
+	}
+	// Without checkpoint syncing, the configured checkpoint is used as is
+	config := &Config{Checkpoint: checkpoint, CheckpointOracle: oracle}
+	if have, err := resolveCheckpoint(config, chain); err != nil || have != checkpoint {
+		t.Fatalf("unsynced checkpoint mismatch: have %v, %v, want %v", have, err, checkpoint)
+	}
+	// With checkpoint syncing, a missing oracle must abort
+	config = &Config{Checkpoint: checkpoint, CheckpointSync: true}
+	if _, err := resolveCheckpoint(config, chain); err != errCheckpointNoOracle {
+		t.Fatalf("missing oracle error mismatch: have %v, want %v", err, errCheckpointNoOracle)
+	}
+	// With checkpoint syncing, an oracle contract not yet synced must not abort
+	config = &Config{Checkpoint: checkpoint, CheckpointOracle: oracle, CheckpointSync: true}
+	if have, err := resolveCheckpoint(config, chain); err != nil || have != checkpoint {
+		t.Fatalf("fresh node checkpoint mismatch: have %v, %v, want %v", have, err, checkpoint)
+	}
+	// With checkpoint syncing, badly signed checkpoints must abort
+	config.CheckpointSignatures = []hexutil.Bytes{make([]byte, 65)}
+	if have, err := resolveCheckpoint(config, chain); err == nil {
+		t.Fatalf("unsigned checkpoint accepted: %v", have)
+	}
+}
+
+// Tests that only the exact checkpoint approved by the oracle is accepted.
+func TestMatchOracleCheckpoint(t *testing.T) {
+	checkpoint := &params.TrustedCheckpoint{
+		SectionIndex: 2,
+		SectionHead:  common.HexToHash("0x01"),
+		CHTRoot:      common.HexToHash("0x02"),
+		BloomRoot:    common.HexToHash("0x03"),
+	}
+	tests := []struct {
+		checkpoint *params.TrustedCheckpoint
+		index      uint64
+		hash       common.Hash
+		height     *big.Int
+		ok         bool
+	}{
+		{checkpoint, 2, checkpoint.Hash(), big.NewInt(100), true},         // Exact match
+		{checkpoint, 0, common.Hash{}, new(big.Int), false},               // Nothing registered
+		{nil, 2, checkpoint.Hash(), big.NewInt(100), false},               // Unknown content
+		{checkpoint, 3, common.HexToHash("0x04"), big.NewInt(100), false}, // Outdated local checkpoint
+		{checkpoint, 1, common.HexToHash("0x04"), big.NewInt(100), false}, // Local checkpoint newer
+		{checkpoint, 2, common.HexToHash("0x04"), big.NewInt(100), false}, // Mismatching content
+	}
+	for i, tt := range tests {
+		if err := matchOracleCheckpoint(tt.checkpoint, tt.index, tt.hash, tt.height); (err == nil) != tt.ok {
+			t.Errorf("test %d: match mismatch: have %v, want ok=%v", i, err, tt.ok)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
 	"github.com/btpereum/go-btpereum/consensus/btpash"
 	"github.com/btpereum/go-btpereum/core"
 	"github.com/btpereum/go-btpereum/btp/downloader"
@@ -57,9 +58,18 @@ var DefaultConfig = Config{
 	},
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
-		Blocks:     20,
-		Percentile: 60,
+		Blocks:        20,
+		Percentile:    60,
+		MempoolBlocks: 6,
 	},
+	RPCLogsCap:            10000,
+	RPCSafeDepth:          12,
+	RPCTraceTimeout:       10 * time.Minute,
+	RPCTraceTimeoutCap:    time.Hour,
+	RPCTraceResultSize:    512 * 1024 * 1024,
+	RPCTraceResultSizeCap: 4 * 1024 * 1024 * 1024,
+	RPCTraceHeapSize:      128 * 1024 * 1024,
+	RPCTraceHeapSizeCap:   1024 * 1024 * 1024,
 }
 
 func init() {
@@ -96,10 +106,16 @@ type Config struct {
 
 	NoPruning  bool // Whbtper to disable pruning and flush everything to disk
 	NoPrefetch bool // Whbtper to disable prefetching and only load state on demand
+	TraceIndex bool // Whether to index the internal transactions of the canonical chain
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
+	// Request serving budget options, non-positive values are unlimited
+	ServeRequestRate    int `toml:",omitempty"` // Maximum number of data requests served per second per peer
+	ServePeerBandwidth  int `toml:",omitempty"` // Maximum number of response bytes served per second per peer
+	ServeTotalBandwidth int `toml:",omitempty"` // Maximum number of response bytes served per second to all peers
+
 	// Light client options
 	LightServ         int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightBandwidthIn  int  `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -147,9 +163,35 @@ type Config struct {
 	// RPCGasCap is the global gas cap for btp-call variants.
 	RPCGasCap *big.Int `toml:",omitempty"`
 
+	// RPCLogsCap is the global cap on the number of logs returned by a single
+	// log query (0 = no cap).
+	RPCLogsCap int
+
+	// RPCSafeDepth is the number of confirmations on top of the current head a
+	// block needs to be reported as safe.
+	RPCSafeDepth uint64
+
+	// RPCTraceTimeout, RPCTraceResultSize and RPCTraceHeapSize are the default
+	// aggregate limits of block and chain traces not requesting their own, while
+	// the matching caps bound any requested limit (0 = unlimited).
+	RPCTraceTimeout       time.Duration
+	RPCTraceTimeoutCap    time.Duration
+	RPCTraceResultSize    uint64
+	RPCTraceResultSizeCap uint64
+	RPCTraceHeapSize      uint64
+	RPCTraceHeapSizeCap   uint64
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint
 
 	// CheckpointOracle is the configuration for checkpoint oracle.
 	CheckpointOracle *params.CheckpointOracleConfig
+
+	// CheckpointSync anchors the chain sync to a checkpoint approved by the
+	// checkpoint oracle, either signed by its signers (CheckpointSignatures)
+	// or registered in the oracle contract.
+	CheckpointSync bool `toml:",omitempty"`
+
+	// CheckpointSignatures are the oracle signers' signatures of Checkpoint.
+	CheckpointSignatures []hexutil.Bytes `toml:",omitempty"`
 }
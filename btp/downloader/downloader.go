@@ -29,6 +29,7 @@ import (
 	"github.com/btpereum/go-btpereum/common"
 	"github.com/btpereum/go-btpereum/core/rawdb"
 	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/btp/peerscore"
 	"github.com/btpereum/go-btpereum/btpdb"
 	"github.com/btpereum/go-btpereum/event"
 	"github.com/btpereum/go-btpereum/log"
@@ -103,10 +104,11 @@ type Downloader struct {
 	mode SyncMode       // Synchronisation mode defining the strategy used (per sync cycle)
 	mux  *event.TypeMux // Event multiplexer to announce sync operation events
 
-	checkpoint uint64   // Checkpoint block number to enforce head against (e.g. fast sync)
-	genesis    uint64   // Genesis block number to limit sync to (e.g. light client CHT)
-	queue      *queue   // Scheduler for selecting the hashes to download
-	peers      *peerSet // Set of active peers from which download can proceed
+	checkpoint uint64      // Checkpoint block number to enforce head against (e.g. fast sync)
+	anchor     common.Hash // Checkpoint block hash to enforce the synced chain against (zero = unknown)
+	genesis    uint64      // Genesis block number to limit sync to (e.g. light client CHT)
+	queue      *queue      // Scheduler for selecting the hashes to download
+	peers      *peerSet    // Set of active peers from which download can proceed
 
 	stateDB    btpdb.Database  // Database to state sync into (and deduplicate via)
 	stateBloom *trie.SyncBloom // Bloom filter for fast trie node existence checks
@@ -123,6 +125,8 @@ type Downloader struct {
 	// Callbacks
 	dropPeer peerDropFn // Drops a peer for misbehaving
 
+	scores *peerscore.Tracker // Reputation tracker to report peer behaviour to (nil = no scoring)
+
 	// Status
 	synchroniseMock func(id string, hash common.Hash) error // Replacement for synchronise during testing
 	synchronising   int32
@@ -210,7 +214,7 @@ type BlockChain interface {
 }
 
 // New creates a new downloader to fetch hashes and blocks from remote peers.
-func New(checkpoint uint64, stateDb btpdb.Database, stateBloom *trie.SyncBloom, mux *event.TypeMux, chain BlockChain, lightchain LightChain, dropPeer peerDropFn) *Downloader {
+func New(checkpoint uint64, anchor common.Hash, stateDb btpdb.Database, stateBloom *trie.SyncBloom, mux *event.TypeMux, chain BlockChain, lightchain LightChain, dropPeer peerDropFn, scores *peerscore.Tracker) *Downloader {
 	if lightchain == nil {
 		lightchain = chain
 	}
@@ -219,13 +223,15 @@ func New(checkpoint uint64, stateDb btpdb.Database, stateBloom *trie.SyncBloom,
 		stateBloom:     stateBloom,
 		mux:            mux,
 		checkpoint:     checkpoint,
+		anchor:         anchor,
 		queue:          newQueue(),
-		peers:          newPeerSet(),
+		peers:          newPeerSet(scores),
 		rttEstimate:    uint64(rttMaxEstimate),
 		rttConfidence:  uint64(1000000),
 		blockchain:     chain,
 		lightchain:     lightchain,
 		dropPeer:       dropPeer,
+		scores:         scores,
 		headerCh:       make(chan dataPack, 1),
 		bodyCh:         make(chan dataPack, 1),
 		receiptCh:      make(chan dataPack, 1),
@@ -317,6 +323,21 @@ func (d *Downloader) UnregisterPeer(id string) error {
 	return nil
 }
 
+// scorePeer reports a behaviour of a peer to the reputation tracker, if any.
+func (d *Downloader) scorePeer(id string, ev peerscore.Event) {
+	if d.scores != nil {
+		d.scores.Record(id, ev)
+	}
+}
+
+// scoreLatency reports a response latency of a peer to the reputation tracker,
+// if any.
+func (d *Downloader) scoreLatency(id string, rtt time.Duration) {
+	if d.scores != nil {
+		d.scores.RecordLatency(id, rtt)
+	}
+}
+
 // Synchronise tries to sync up our local block chain with a remote peer, both
 // adding various sanity checks as well as wrapping it with various log entries.
 func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode SyncMode) error {
@@ -329,6 +350,14 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 		errEmptyHeaderSet, errPeersUnavailable, errTooOld,
 		errInvalidAncestor, errInvalidChain:
 		log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
+		switch err {
+		case errTimeout, errStallingPeer:
+			d.scorePeer(id, peerscore.Timeout)
+		case errBadPeer, errInvalidAncestor, errInvalidChain:
+			d.scorePeer(id, peerscore.InvalidBlock)
+		default:
+			d.scorePeer(id, peerscore.UselessResponse)
+		}
 		if d.dropPeer == nil {
 			// The dropPeer mbtpod is nil when `--copydb` is used for a local copy.
 			// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
@@ -623,7 +652,7 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 				return nil, errBadPeer
 			}
 			head := headers[0]
-			if (d.mode == FastSync || d.mode == LightSync) && head.Number.Uint64() < d.checkpoint {
+			if d.mode != FullSync && head.Number.Uint64() < d.checkpoint {
 				p.log.Warn("Remote head below checkpoint", "number", head.Number, "hash", head.Hash())
 				return nil, errUnsyncedPeer
 			}
@@ -998,7 +1027,7 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64)
 				if n := len(headers); n > 0 {
 					// Retrieve the current head we're at
 					head := uint64(0)
-					if d.mode == LightSync {
+					if d.mode.headersOnly() {
 						head = d.lightchain.CurrentHeader().Number.Uint64()
 					} else {
 						head = d.blockchain.CurrentFastBlock().NumberU64()
@@ -1214,13 +1243,16 @@ func (d *Downloader) fetchParts(deliveryCh chan dataPack, deliver func(dataPack)
 				// idle. If the delivery's stale, the peer should have already been idled.
 				if err != errStaleDelivery {
 					setIdle(peer, accepted)
+					d.scoreLatency(peer.id, peer.RTT())
 				}
 				// Issue a log to the user to see what's going on
 				switch {
 				case err == nil && packet.Items() == 0:
 					peer.log.Trace("Requested data not delivered", "type", kind)
+					d.scorePeer(peer.id, peerscore.UselessResponse)
 				case err == nil:
 					peer.log.Trace("Delivered new batch of data", "type", kind, "count", packet.Stats())
+					d.scorePeer(peer.id, peerscore.UsefulResponse)
 				default:
 					peer.log.Trace("Failed to deliver retrieved data", "type", kind, "err", err)
 				}
@@ -1264,6 +1296,7 @@ func (d *Downloader) fetchParts(deliveryCh chan dataPack, deliver func(dataPack)
 					// The reason the minimum threshold is 2 is because the downloader tries to estimate the bandwidth
 					// and latency of a peer separately, which requires pushing the measures capacity a bit and seeing
 					// how response times reacts, to it always requests one more than the minimum (i.e. min 2).
+					d.scorePeer(pid, peerscore.Timeout)
 					if fails > 2 {
 						peer.log.Trace("Data delivery timed out", "type", kind)
 						setIdle(peer, 0)
@@ -1414,7 +1447,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				// L: Sync begins, and finds common ancestor at 11
 				// L: Request new headers up from 11 (R's TD was higher, it must have sombtping)
 				// R: Nothing to give
-				if d.mode != LightSync {
+				if !d.mode.headersOnly() {
 					head := d.blockchain.CurrentBlock()
 					if !gotHeaders && td.Cmp(d.blockchain.GetTd(head.Hash(), head.NumberU64())) > 0 {
 						return errStallingPeer
@@ -1427,7 +1460,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				// This check cannot be executed "as is" for full imports, since blocks may still be
 				// queued for processing when the header download completes. However, as long as the
 				// peer gave us sombtping useful, we're already happy/progressed (above check).
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode != FullSync {
 					head := d.lightchain.CurrentHeader()
 					if td.Cmp(d.lightchain.GetTd(head.Hash(), head.Number.Uint64())) > 0 {
 						return errStallingPeer
@@ -1452,8 +1485,13 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 					limit = len(headers)
 				}
 				chunk := headers[:limit]
+
+				// If the chunk crosses the trusted checkpoint, ensure the chain is anchored to it
+				if err := d.checkAnchor(chunk); err != nil {
+					return err
+				}
 				// In case of header only syncing, validate the chunk immediately
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode != FullSync {
 					// Collect the yet unknown headers to mark them as uncertain
 					unknown := make([]*types.Header, 0, len(chunk))
 					for _, header := range chunk {
@@ -1461,11 +1499,18 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 							unknown = append(unknown, header)
 						}
 					}
-					// If we're importing pure headers, verify based on their recentness
+					// If we're importing pure headers, verify based on their recentness. Header
+					// syncs have no state to fall back on, so verify every seal there.
 					frequency := fsHeaderCheckFrequency
-					if chunk[len(chunk)-1].Number.Uint64()+uint64(fsHeaderForceVerify) > pivot {
+					if d.mode == HeaderSync || chunk[len(chunk)-1].Number.Uint64()+uint64(fsHeaderForceVerify) > pivot {
 						frequency = 1
 					}
+					// Headers up to a trusted checkpoint are vouched for by their hashes
+					// linking to it (a chain diverging is rejected upon reaching it), so
+					// skip their seal verification in fast sync.
+					if d.mode == FastSync && d.anchor != (common.Hash{}) && chunk[len(chunk)-1].Number.Uint64() <= d.checkpoint {
+						frequency = 0
+					}
 					if n, err := d.lightchain.InsertHeaderChain(chunk, frequency); err != nil {
 						// If some headers were inserted, add them too to the rollback list
 						if n > 0 {
@@ -1518,6 +1563,25 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 	}
 }
 
+// checkAnchor verifies that a batch of headers crossing the trusted checkpoint
+// contains the expected checkpoint header, rejecting chains that disagree.
+func (d *Downloader) checkAnchor(headers []*types.Header) error {
+	if d.anchor == (common.Hash{}) {
+		return nil
+	}
+	for _, header := range headers {
+		if header.Number.Uint64() != d.checkpoint {
+			continue
+		}
+		if hash := header.Hash(); hash != d.anchor {
+			log.Warn("Checkpoint mismatch, rejecting chain", "number", d.checkpoint, "hash", hash, "want", d.anchor)
+			return errInvalidChain
+		}
+		break
+	}
+	return nil
+}
+
 // processFullSyncContent takes fetch results from the queue and imports them into the chain.
 func (d *Downloader) processFullSyncContent() error {
 	for {
@@ -1550,9 +1614,21 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 		"firstnum", first.Number, "firsthash", first.Hash(),
 		"lastnum", last.Number, "lasthash", last.Hash(),
 	)
+	// Tag the blocks with the peer delivering them for bad block reports, falling
+	// back to the master peer for the ones assembled from the headers only
+	d.cancelLock.RLock()
+	master := d.cancelPeer
+	d.cancelLock.RUnlock()
+
 	blocks := make([]*types.Block, len(results))
 	for i, result := range results {
 		blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
+
+		peer := result.Peer
+		if peer == "" {
+			peer = master
+		}
+		blocks[i].ReceivedFrom = peer
 	}
 	if index, err := d.blockchain.InsertChain(blocks); err != nil {
 		if index < len(results) {
@@ -30,8 +30,11 @@ import (
 	"github.com/btpereum/go-btpereum/common"
 	"github.com/btpereum/go-btpereum/core/rawdb"
 	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/btp/peerscore"
 	"github.com/btpereum/go-btpereum/btpdb"
 	"github.com/btpereum/go-btpereum/event"
+	"github.com/btpereum/go-btpereum/log"
+	"github.com/btpereum/go-btpereum/p2p/enode"
 	"github.com/btpereum/go-btpereum/trie"
 )
 
@@ -86,7 +89,7 @@ func newTester() *downloadTester {
 	tester.stateDb = rawdb.NewMemoryDatabase()
 	tester.stateDb.Put(testGenesis.Root().Bytes(), []byte{0x00})
 
-	tester.downloader = New(0, tester.stateDb, trie.NewSyncBloom(1, tester.stateDb), new(event.TypeMux), tester, nil, tester.dropPeer)
+	tester.downloader = New(0, common.Hash{}, tester.stateDb, trie.NewSyncBloom(1, tester.stateDb), new(event.TypeMux), tester, nil, tester.dropPeer, nil)
 	return tester
 }
 
@@ -464,7 +467,7 @@ func assertOwnForkedChain(t *testing.T, tester *downloadTester, common int, leng
 		blocks += length - common
 		receipts += length - common
 	}
-	if tester.downloader.mode == LightSync {
+	if tester.downloader.mode.headersOnly() {
 		blocks, receipts = 1, 1
 	}
 	if hs := len(tester.ownHeaders) + len(tester.ancientHeaders) - 1; hs != headers {
@@ -481,12 +484,13 @@ func assertOwnForkedChain(t *testing.T, tester *downloadTester, common int, leng
 // Tests that simple synchronization against a canonical chain works correctly.
 // In this test common ancestor lookup should be short circuited and not require
 // binary searching.
-func TestCanonicalSynchronisation62(t *testing.T)      { testCanonicalSynchronisation(t, 62, FullSync) }
-func TestCanonicalSynchronisation63Full(t *testing.T)  { testCanonicalSynchronisation(t, 63, FullSync) }
-func TestCanonicalSynchronisation63Fast(t *testing.T)  { testCanonicalSynchronisation(t, 63, FastSync) }
-func TestCanonicalSynchronisation64Full(t *testing.T)  { testCanonicalSynchronisation(t, 64, FullSync) }
-func TestCanonicalSynchronisation64Fast(t *testing.T)  { testCanonicalSynchronisation(t, 64, FastSync) }
-func TestCanonicalSynchronisation64Light(t *testing.T) { testCanonicalSynchronisation(t, 64, LightSync) }
+func TestCanonicalSynchronisation62(t *testing.T)       { testCanonicalSynchronisation(t, 62, FullSync) }
+func TestCanonicalSynchronisation63Full(t *testing.T)   { testCanonicalSynchronisation(t, 63, FullSync) }
+func TestCanonicalSynchronisation63Fast(t *testing.T)   { testCanonicalSynchronisation(t, 63, FastSync) }
+func TestCanonicalSynchronisation64Full(t *testing.T)   { testCanonicalSynchronisation(t, 64, FullSync) }
+func TestCanonicalSynchronisation64Fast(t *testing.T)   { testCanonicalSynchronisation(t, 64, FastSync) }
+func TestCanonicalSynchronisation64Light(t *testing.T)  { testCanonicalSynchronisation(t, 64, LightSync) }
+func TestCanonicalSynchronisation64Header(t *testing.T) { testCanonicalSynchronisation(t, 64, HeaderSync) }
 
 func testCanonicalSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
@@ -503,6 +507,15 @@ func testCanonicalSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 		t.Fatalf("failed to synchronise blocks: %v", err)
 	}
 	assertOwnChain(t, tester, chain.len())
+
+	// Imported blocks must be tagged with their delivering peer for bad block reports
+	if mode == FullSync {
+		for _, block := range tester.ownBlocks {
+			if block.NumberU64() > 0 && block.ReceivedFrom != "peer" {
+				t.Errorf("block #%d: origin mismatch: have %v, want %v", block.NumberU64(), block.ReceivedFrom, "peer")
+			}
+		}
+	}
 }
 
 // Tests that if a large batch of blocks are being downloaded, it is throttled
@@ -589,12 +602,13 @@ func testThrottling(t *testing.T, protocol int, mode SyncMode) {
 // Tests that simple synchronization against a forked chain works correctly. In
 // this test common ancestor lookup should *not* be short circuited, and a full
 // binary search should be executed.
-func TestForkedSync62(t *testing.T)      { testForkedSync(t, 62, FullSync) }
-func TestForkedSync63Full(t *testing.T)  { testForkedSync(t, 63, FullSync) }
-func TestForkedSync63Fast(t *testing.T)  { testForkedSync(t, 63, FastSync) }
-func TestForkedSync64Full(t *testing.T)  { testForkedSync(t, 64, FullSync) }
-func TestForkedSync64Fast(t *testing.T)  { testForkedSync(t, 64, FastSync) }
-func TestForkedSync64Light(t *testing.T) { testForkedSync(t, 64, LightSync) }
+func TestForkedSync62(t *testing.T)       { testForkedSync(t, 62, FullSync) }
+func TestForkedSync63Full(t *testing.T)   { testForkedSync(t, 63, FullSync) }
+func TestForkedSync63Fast(t *testing.T)   { testForkedSync(t, 63, FastSync) }
+func TestForkedSync64Full(t *testing.T)   { testForkedSync(t, 64, FullSync) }
+func TestForkedSync64Fast(t *testing.T)   { testForkedSync(t, 64, FastSync) }
+func TestForkedSync64Light(t *testing.T)  { testForkedSync(t, 64, LightSync) }
+func TestForkedSync64Header(t *testing.T) { testForkedSync(t, 64, HeaderSync) }
 
 func testForkedSync(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
@@ -1623,12 +1637,13 @@ func TestRemoteHeaderRequestSpan(t *testing.T) {
 
 // Tests that peers below a pre-configured checkpoint block are prevented from
 // being fast-synced from, avoiding potential cheap eclipse attacks.
-func TestCheckpointEnforcement62(t *testing.T)      { testCheckpointEnforcement(t, 62, FullSync) }
-func TestCheckpointEnforcement63Full(t *testing.T)  { testCheckpointEnforcement(t, 63, FullSync) }
-func TestCheckpointEnforcement63Fast(t *testing.T)  { testCheckpointEnforcement(t, 63, FastSync) }
-func TestCheckpointEnforcement64Full(t *testing.T)  { testCheckpointEnforcement(t, 64, FullSync) }
-func TestCheckpointEnforcement64Fast(t *testing.T)  { testCheckpointEnforcement(t, 64, FastSync) }
-func TestCheckpointEnforcement64Light(t *testing.T) { testCheckpointEnforcement(t, 64, LightSync) }
+func TestCheckpointEnforcement62(t *testing.T)       { testCheckpointEnforcement(t, 62, FullSync) }
+func TestCheckpointEnforcement63Full(t *testing.T)   { testCheckpointEnforcement(t, 63, FullSync) }
+func TestCheckpointEnforcement63Fast(t *testing.T)   { testCheckpointEnforcement(t, 63, FastSync) }
+func TestCheckpointEnforcement64Full(t *testing.T)   { testCheckpointEnforcement(t, 64, FullSync) }
+func TestCheckpointEnforcement64Fast(t *testing.T)   { testCheckpointEnforcement(t, 64, FastSync) }
+func TestCheckpointEnforcement64Light(t *testing.T)  { testCheckpointEnforcement(t, 64, LightSync) }
+func TestCheckpointEnforcement64Header(t *testing.T) { testCheckpointEnforcement(t, 64, HeaderSync) }
 
 func testCheckpointEnforcement(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
@@ -1644,15 +1659,74 @@ func testCheckpointEnforcement(t *testing.T, protocol int, mode SyncMode) {
 	tester.newPeer("peer", protocol, chain)
 
 	var expect error
-	if mode == FastSync || mode == LightSync {
+	if mode != FullSync {
 		expect = errUnsyncedPeer
 	}
 	if err := tester.sync("peer", nil, mode); err != expect {
 		t.Fatalf("block sync error mismatch: have %v, want %v", err, expect)
 	}
-	if mode == FastSync || mode == LightSync {
+	if mode != FullSync {
 		assertOwnChain(t, tester, 1)
 	} else {
 		assertOwnChain(t, tester, chain.len())
 	}
 }
+
+// Tests that chains not containing the trusted checkpoint block are rejected,
+// avoiding syncing to a long fake chain.
+func TestCheckpointAnchor63Full(t *testing.T) { testCheckpointAnchor(t, 63, FullSync) }
+func TestCheckpointAnchor63Fast(t *testing.T) { testCheckpointAnchor(t, 63, FastSync) }
+
+func testCheckpointAnchor(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	tester.newPeer("peer", protocol, chain)
+
+	// Anchor the sync to a checkpoint hash not on the peer's chain
+	tester.downloader.checkpoint = uint64(chain.len()) / 2
+	tester.downloader.anchor = common.Hash{0x01}
+
+	if err := tester.sync("peer", nil, mode); err != errInvalidChain {
+		t.Fatalf("block sync error mismatch: have %v, want %v", err, errInvalidChain)
+	}
+	// Anchor it to the correct checkpoint hash, sync should succeed
+	tester.downloader.anchor = chain.headerm[chain.chain[tester.downloader.checkpoint]].Hash()
+	tester.newPeer("honest", protocol, chain)
+
+	if err := tester.sync("honest", nil, mode); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, chain.len())
+}
+
+// Tests that idle peers with a low reputation are only assigned requests after
+// all the well behaving ones, irrelevant of their measured throughput.
+func TestLowScorePeerDeprioritised(t *testing.T) {
+	scores := peerscore.New(nil)
+	ps := newPeerSet(scores)
+
+	for _, id := range []string{"good", "bad"} {
+		scores.Register(id, enode.ID{})
+		if err := ps.Register(newPeerConnection(id, 63, nil, log.New())); err != nil {
+			t.Fatalf("failed to register peer %s: %v", id, err)
+		}
+	}
+	ps.Peer("good").headerThroughput = 1
+	ps.Peer("bad").headerThroughput = 100
+
+	idle, _ := ps.HeaderIdlePeers()
+	if idle[0].id != "bad" {
+		t.Fatalf("fastest peer not prioritised: have %s, want %s", idle[0].id, "bad")
+	}
+	for i := 0; i < 3; i++ {
+		scores.Record("bad", peerscore.InvalidBlock)
+	}
+	idle, _ = ps.HeaderIdlePeers()
+	if idle[0].id != "good" || idle[1].id != "bad" {
+		t.Fatalf("low score peer not deprioritised: have %s, %s", idle[0].id, idle[1].id)
+	}
+}
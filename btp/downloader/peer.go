@@ -29,6 +29,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/btpereum/go-btpereum/btp/peerscore"
 	"github.com/btpereum/go-btpereum/common"
 	"github.com/btpereum/go-btpereum/event"
 	"github.com/btpereum/go-btpereum/log"
@@ -277,6 +278,14 @@ func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *f
 		"miss", len(p.lacking), "rtt", p.rtt)
 }
 
+// RTT retrieves the measured request round trip time of the peer.
+func (p *peerConnection) RTT() time.Duration {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.rtt
+}
+
 // HeaderCapacity retrieves the peers header download allowance based on its
 // previously discovered throughput.
 func (p *peerConnection) HeaderCapacity(targetRTT time.Duration) int {
@@ -343,15 +352,17 @@ func (p *peerConnection) Lacks(hash common.Hash) bool {
 // download procedure.
 type peerSet struct {
 	peers        map[string]*peerConnection
+	scores       *peerscore.Tracker // Reputation tracker to deprioritise misbehaving peers (nil = disabled)
 	newPeerFeed  event.Feed
 	peerDropFeed event.Feed
 	lock         sync.RWMutex
 }
 
 // newPeerSet creates a new peer set top track the active download sources.
-func newPeerSet() *peerSet {
+func newPeerSet(scores *peerscore.Tracker) *peerSet {
 	return &peerSet{
-		peers: make(map[string]*peerConnection),
+		peers:  make(map[string]*peerConnection),
+		scores: scores,
 	}
 }
 
@@ -517,7 +528,8 @@ func (ps *peerSet) NodeDataIdlePeers() ([]*peerConnection, int) {
 
 // idlePeers retrieves a flat list of all currently idle peers satisfying the
 // protocol version constraints, using the provided function to check idleness.
-// The resulting set of peers are sorted by their measure throughput.
+// The resulting set of peers are sorted by their measure throughput, with peers
+// of low reputation pushed to the end of the list.
 func (ps *peerSet) idlePeers(minProtocol, maxProtocol int, idleCheck func(*peerConnection) bool, throughput func(*peerConnection) float64) ([]*peerConnection, int) {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
@@ -531,9 +543,13 @@ func (ps *peerSet) idlePeers(minProtocol, maxProtocol int, idleCheck func(*peerC
 			total++
 		}
 	}
+	low := func(p *peerConnection) bool {
+		return ps.scores != nil && ps.scores.Low(p.id)
+	}
 	for i := 0; i < len(idle); i++ {
 		for j := i + 1; j < len(idle); j++ {
-			if throughput(idle[i]) < throughput(idle[j]) {
+			lowi, lowj := low(idle[i]), low(idle[j])
+			if (lowi && !lowj) || (lowi == lowj && throughput(idle[i]) < throughput(idle[j])) {
 				idle[i], idle[j] = idle[j], idle[i]
 			}
 		}
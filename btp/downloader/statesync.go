@@ -25,6 +25,7 @@ import (
 	"github.com/btpereum/go-btpereum/common"
 	"github.com/btpereum/go-btpereum/core/rawdb"
 	"github.com/btpereum/go-btpereum/core/state"
+	"github.com/btpereum/go-btpereum/btp/peerscore"
 	"github.com/btpereum/go-btpereum/btpdb"
 	"github.com/btpereum/go-btpereum/log"
 	"github.com/btpereum/go-btpereum/trie"
@@ -307,6 +308,9 @@ func (s *stateSync) loop() (err error) {
 		case req := <-s.deliver:
 			// Response, disconnect or timeout triggered, drop the peer if stalling
 			log.Trace("Received node data response", "peer", req.peer.id, "count", len(req.response), "dropped", req.dropped, "timeout", !req.dropped && req.timedOut())
+			if !req.dropped && req.timedOut() {
+				s.d.scorePeer(req.peer.id, peerscore.Timeout)
+			}
 			if len(req.items) <= 2 && !req.dropped && req.timedOut() {
 				// 2 items are the minimum requested, if even that times out, we've no use of
 				// this peer at the moment.
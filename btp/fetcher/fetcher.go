@@ -26,6 +26,7 @@ import (
 	"github.com/btpereum/go-btpereum/common/prque"
 	"github.com/btpereum/go-btpereum/consensus"
 	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/btp/peerscore"
 	"github.com/btpereum/go-btpereum/log"
 )
 
@@ -128,6 +129,9 @@ type Fetcher struct {
 	queues map[string]int          // Per peer block counts to prevent memory exhaustion
 	queued map[common.Hash]*inject // Set of already queued blocks (to dedupe imports)
 
+	// Propagation tracing
+	propagation *propagationLog // Announcement, arrival and import times of recent blocks
+
 	// Callbacks
 	getBlock       blockRetrievalFn   // Retrieves a block from the local chain
 	verifyHeader   headerVerifierFn   // Checks if a block's headers have a valid proof of work
@@ -135,6 +139,7 @@ type Fetcher struct {
 	chainHeight    chainHeightFn      // Retrieves the current chain's height
 	insertChain    chainInsertFn      // Injects a batch of blocks into the chain
 	dropPeer       peerDropFn         // Drops a peer for misbehaving
+	scores         *peerscore.Tracker // Reputation tracker to report peer behaviour to (nil = no scoring)
 
 	// Testing hooks
 	announceChangeHook func(common.Hash, bool) // Mbtpod to call upon adding or deleting a hash from the announce list
@@ -145,7 +150,7 @@ type Fetcher struct {
 }
 
 // New creates a block fetcher to retrieve blocks based on hash announcements.
-func New(getBlock blockRetrievalFn, verifyHeader headerVerifierFn, broadcastBlock blockBroadcasterFn, chainHeight chainHeightFn, insertChain chainInsertFn, dropPeer peerDropFn) *Fetcher {
+func New(getBlock blockRetrievalFn, verifyHeader headerVerifierFn, broadcastBlock blockBroadcasterFn, chainHeight chainHeightFn, insertChain chainInsertFn, dropPeer peerDropFn, scores *peerscore.Tracker) *Fetcher {
 	return &Fetcher{
 		notify:         make(chan *announce),
 		inject:         make(chan *inject),
@@ -161,15 +166,23 @@ func New(getBlock blockRetrievalFn, verifyHeader headerVerifierFn, broadcastBloc
 		queue:          prque.New(nil),
 		queues:         make(map[string]int),
 		queued:         make(map[common.Hash]*inject),
+		propagation:    newPropagationLog(propagationRecords),
 		getBlock:       getBlock,
 		verifyHeader:   verifyHeader,
 		broadcastBlock: broadcastBlock,
 		chainHeight:    chainHeight,
 		insertChain:    insertChain,
 		dropPeer:       dropPeer,
+		scores:         scores,
 	}
 }
 
+// Propagation retrieves the propagation records of the recently announced or
+// received blocks, newest first.
+func (f *Fetcher) Propagation() []*PropagationRecord {
+	return f.propagation.list()
+}
+
 // Start boots up the announcement based synchroniser, accepting and processing
 // hash notifications and block fetches until termination requested.
 func (f *Fetcher) Start() {
@@ -283,6 +296,7 @@ func (f *Fetcher) loop() {
 		// Clean up any expired block fetches
 		for hash, announce := range f.fetching {
 			if time.Since(announce.time) > fetchTimeout {
+				f.scorePeer(announce.origin, peerscore.Timeout)
 				f.forgbtpash(hash)
 			}
 		}
@@ -343,6 +357,7 @@ func (f *Fetcher) loop() {
 			}
 			f.announces[notification.origin] = count
 			f.announced[notification.hash] = append(f.announced[notification.hash], notification)
+			f.propagation.announced(notification.origin, notification.hash, notification.number, notification.time)
 			if f.announceChangeHook != nil && len(f.announced[notification.hash]) == 1 {
 				f.announceChangeHook(notification.hash, true)
 			}
@@ -448,6 +463,7 @@ func (f *Fetcher) loop() {
 					// If the delivered header does not match the promised number, drop the announcer
 					if header.Number.Uint64() != announce.number {
 						log.Trace("Invalid block number fetched", "peer", announce.origin, "hash", header.Hash(), "announced", announce.number, "provided", header.Number)
+						f.scorePeer(announce.origin, peerscore.InvalidBlock)
 						f.dropPeer(announce.origin)
 						f.forgbtpash(hash)
 						continue
@@ -614,6 +630,8 @@ func (f *Fetcher) enqueue(peer string, block *types.Block) {
 		f.forgbtpash(hash)
 		return
 	}
+	f.propagation.received(peer, hash, block.NumberU64(), time.Now())
+
 	// Schedule the block for future importing
 	if _, ok := f.queued[hash]; !ok {
 		op := &inject{
@@ -636,6 +654,10 @@ func (f *Fetcher) enqueue(peer string, block *types.Block) {
 func (f *Fetcher) insert(peer string, block *types.Block) {
 	hash := block.Hash()
 
+	// Tag blocks retrieved after an announcement with their origin for bad block reports
+	if block.ReceivedFrom == nil {
+		block.ReceivedFrom = peer
+	}
 	// Run the import on a new thread
 	log.Debug("Importing propagated block", "peer", peer, "number", block.Number(), "hash", hash)
 	go func() {
@@ -660,6 +682,7 @@ func (f *Fetcher) insert(peer string, block *types.Block) {
 		default:
 			// Sombtping went very wrong, drop the peer
 			log.Debug("Propagated block verification failed", "peer", peer, "number", block.Number(), "hash", hash, "err", err)
+			f.scorePeer(peer, peerscore.InvalidBlock)
 			f.dropPeer(peer)
 			return
 		}
@@ -668,7 +691,9 @@ func (f *Fetcher) insert(peer string, block *types.Block) {
 			log.Debug("Propagated block import failed", "peer", peer, "number", block.Number(), "hash", hash, "err", err)
 			return
 		}
-		// If import succeeded, broadcast the block
+		// If import succeeded, credit the origin and broadcast the block
+		f.propagation.imported(hash, block.NumberU64(), time.Now())
+		f.scorePeer(peer, peerscore.UsefulResponse)
 		propAnnounceOutTimer.UpdateSince(block.ReceivedAt)
 		go f.broadcastBlock(block, false)
 
@@ -732,3 +757,10 @@ func (f *Fetcher) forgetBlock(hash common.Hash) {
 		delete(f.queued, hash)
 	}
 }
+
+// scorePeer reports a behaviour of a peer to the reputation tracker, if any.
+func (f *Fetcher) scorePeer(id string, ev peerscore.Event) {
+	if f.scores != nil {
+		f.scores.Record(id, ev)
+	}
+}
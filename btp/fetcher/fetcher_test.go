@@ -92,7 +92,7 @@ func newTester() *fetcherTester {
 		blocks: map[common.Hash]*types.Block{genesis.Hash(): genesis},
 		drops:  make(map[string]bool),
 	}
-	tester.fetcher = New(tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertChain, tester.dropPeer)
+	tester.fetcher = New(tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertChain, tester.dropPeer, nil)
 	tester.fetcher.Start()
 
 	return tester
@@ -788,3 +788,53 @@ func TestBlockMemoryExhaustionAttack(t *testing.T) {
 	}
 	verifyImportDone(t, imported)
 }
+
+// Tests that the propagation of announced and imported blocks is recorded.
+func TestPropagationRecords(t *testing.T) {
+	hashes, blocks := makeChain(1, 0, genesis)
+
+	tester := newTester()
+	headerFetcher := tester.makeHeaderFetcher("first", blocks, -gatherSlack)
+	bodyFetcher := tester.makeBodyFetcher("first", blocks, 0)
+
+	imported := make(chan *types.Block)
+	tester.fetcher.importedHook = func(block *types.Block) { imported <- block }
+
+	tester.fetcher.Notify("first", hashes[0], 1, time.Now().Add(-arriveTimeout), headerFetcher, bodyFetcher)
+	tester.fetcher.Notify("second", hashes[0], 1, time.Now().Add(-arriveTimeout), headerFetcher, bodyFetcher)
+	verifyImportEvent(t, imported, true)
+
+	records := tester.fetcher.Propagation()
+	if len(records) != 1 {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), 1)
+	}
+	rec := records[0]
+	if rec.Hash != hashes[0] || rec.Number != 1 {
+		t.Fatalf("record block mismatch: have #%d [%x], want #%d [%x]", rec.Number, rec.Hash, 1, hashes[0])
+	}
+	if len(rec.Announcers) != 2 || rec.Announcers[0] != "first" || rec.Announcers[1] != "second" {
+		t.Fatalf("announcers mismatch: have %v, want [first second]", rec.Announcers)
+	}
+	if rec.Origin != "first" {
+		t.Fatalf("origin mismatch: have %s, want first", rec.Origin)
+	}
+	if rec.Announced.IsZero() || rec.Received.Before(rec.Announced) || rec.Imported.Before(rec.Received) {
+		t.Fatalf("propagation times out of order: announced %v, received %v, imported %v", rec.Announced, rec.Received, rec.Imported)
+	}
+}
+
+// Tests that the propagation log retains only a limited number of records,
+// evicting the oldest ones first.
+func TestPropagationLogEviction(t *testing.T) {
+	plog := newPropagationLog(2)
+	for i := byte(1); i <= 3; i++ {
+		plog.received("peer", common.Hash{i}, uint64(i), time.Now())
+	}
+	records := plog.list()
+	if len(records) != 2 {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), 2)
+	}
+	if records[0].Hash != (common.Hash{3}) || records[1].Hash != (common.Hash{2}) {
+		t.Fatalf("retained records mismatch: have %x, %x", records[0].Hash, records[1].Hash)
+	}
+}
@@ -40,4 +40,8 @@ var (
 	headerFilterOutMeter = metrics.NewRegisteredMeter("btp/fetcher/filter/headers/out", nil)
 	bodyFilterInMeter    = metrics.NewRegisteredMeter("btp/fetcher/filter/bodies/in", nil)
 	bodyFilterOutMeter   = metrics.NewRegisteredMeter("btp/fetcher/filter/bodies/out", nil)
+
+	propFetchDelayHist  = metrics.NewRegisteredHistogram("btp/fetcher/prop/delay/fetch", nil, metrics.NewExpDecaySample(1028, 0.015))
+	propImportDelayHist = metrics.NewRegisteredHistogram("btp/fetcher/prop/delay/import", nil, metrics.NewExpDecaySample(1028, 0.015))
+	propTotalDelayHist  = metrics.NewRegisteredHistogram("btp/fetcher/prop/delay/total", nil, metrics.NewExpDecaySample(1028, 0.015))
 )
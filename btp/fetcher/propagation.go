@@ -0,0 +1,146 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+)
+
+// propagationRecords is the number of recent blocks to keep propagation records
+// for. Older records are evicted in arrival order.
+const propagationRecords = 1024
+
+// PropagationRecord contains the timing of a single block's propagation to the
+// local node: when it was first heard of, when its full content first arrived
+// and when it was imported into the chain.
+type PropagationRecord struct {
+	Hash       common.Hash `json:"hash"`       // Hash of the propagated block
+	Number     uint64      `json:"number"`     // Number of the propagated block (0 = unknown)
+	Announced  time.Time   `json:"announced"`  // Time of the first announcement (zero = never announced)
+	Received   time.Time   `json:"received"`   // Time the full block first arrived (zero = not yet)
+	Imported   time.Time   `json:"imported"`   // Time the block was imported by the fetcher (zero = not yet)
+	Origin     string      `json:"origin"`     // Peer delivering the full block first
+	Announcers []string    `json:"announcers"` // Peers announcing the block, in arrival order
+}
+
+// propagationLog is a bounded ring of the propagation records of recent blocks.
+type propagationLog struct {
+	records map[common.Hash]*PropagationRecord // Propagation records by block hash
+	ring    []common.Hash                      // Tracked hashes in arrival order, used for eviction
+	next    int                                // Index of the next ring slot to fill
+	lock    sync.RWMutex
+}
+
+// newPropagationLog creates a propagation log retaining the given number of records.
+func newPropagationLog(limit int) *propagationLog {
+	return &propagationLog{
+		records: make(map[common.Hash]*PropagationRecord),
+		ring:    make([]common.Hash, limit),
+	}
+}
+
+// record retrieves the propagation record of a block, creating it and evicting
+// the oldest one if the block is not yet tracked. The lock must be held.
+func (l *propagationLog) record(hash common.Hash, number uint64) *PropagationRecord {
+	if rec, ok := l.records[hash]; ok {
+		if rec.Number == 0 {
+			rec.Number = number
+		}
+		return rec
+	}
+	delete(l.records, l.ring[l.next])
+	l.ring[l.next] = hash
+	l.next = (l.next + 1) % len(l.ring)
+
+	rec := &PropagationRecord{Hash: hash, Number: number}
+	l.records[hash] = rec
+	return rec
+}
+
+// announced registers an announcement of a block by a remote peer.
+func (l *propagationLog) announced(peer string, hash common.Hash, number uint64, at time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	rec := l.record(hash, number)
+	for _, announcer := range rec.Announcers {
+		if announcer == peer {
+			return
+		}
+	}
+	rec.Announcers = append(rec.Announcers, peer)
+	if rec.Announced.IsZero() {
+		rec.Announced = at
+	}
+}
+
+// received registers the arrival of a full block from a remote peer.
+func (l *propagationLog) received(peer string, hash common.Hash, number uint64, at time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	rec := l.record(hash, number)
+	if !rec.Received.IsZero() {
+		return
+	}
+	rec.Received, rec.Origin = at, peer
+	if !rec.Announced.IsZero() {
+		propFetchDelayHist.Update(int64(at.Sub(rec.Announced) / time.Millisecond))
+	}
+}
+
+// imported registers the successful import of a block.
+func (l *propagationLog) imported(hash common.Hash, number uint64, at time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	rec := l.record(hash, number)
+	if !rec.Imported.IsZero() {
+		return
+	}
+	rec.Imported = at
+	if !rec.Received.IsZero() {
+		propImportDelayHist.Update(int64(at.Sub(rec.Received) / time.Millisecond))
+	}
+	first := rec.Received
+	if !rec.Announced.IsZero() && (first.IsZero() || rec.Announced.Before(first)) {
+		first = rec.Announced
+	}
+	if !first.IsZero() {
+		propTotalDelayHist.Update(int64(at.Sub(first) / time.Millisecond))
+	}
+}
+
+// list returns a copy of the tracked propagation records, newest first.
+func (l *propagationLog) list() []*PropagationRecord {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	records := make([]*PropagationRecord, 0, len(l.records))
+	for i := 1; i <= len(l.ring); i++ {
+		hash := l.ring[(l.next-i+len(l.ring))%len(l.ring)]
+		if rec, ok := l.records[hash]; ok {
+			cpy := *rec
+			cpy.Announcers = append([]string{}, rec.Announcers...)
+			records = append(records, &cpy)
+		}
+	}
+	return records
+}
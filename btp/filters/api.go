@@ -28,6 +28,7 @@ import (
 	btpereum "github.com/btpereum/go-btpereum"
 	"github.com/btpereum/go-btpereum/common"
 	"github.com/btpereum/go-btpereum/common/hexutil"
+	"github.com/btpereum/go-btpereum/core"
 	"github.com/btpereum/go-btpereum/core/types"
 	"github.com/btpereum/go-btpereum/btpdb"
 	"github.com/btpereum/go-btpereum/event"
@@ -168,6 +169,106 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// txChange is the JSON representation of a transaction pool state transition.
+type txChange struct {
+	Hash        common.Hash  `json:"hash"`
+	Status      string       `json:"status"`
+	Replacement *common.Hash `json:"replacement,omitempty"`
+	Reason      string       `json:"reason,omitempty"`
+}
+
+// TxPoolLifecycle creates a subscription that is triggered each time a transaction
+// in the transaction pool changes state: gets queued, promoted to pending, replaced,
+// dropped or included in a block.
+func (api *PublicFilterAPI) TxPoolLifecycle(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		txChanges := make(chan []core.TxChange, 128)
+		changesSub := api.events.SubscribeTxLifecycle(txChanges)
+
+		for {
+			select {
+			case changes := <-txChanges:
+				for _, c := range changes {
+					change := &txChange{Hash: c.Hash, Status: c.Status.String(), Reason: c.Reason}
+					if c.Status == core.TxReplaced {
+						replacement := c.Replacement
+						change.Replacement = &replacement
+					}
+					notifier.Notify(rpcSub.ID, change)
+				}
+			case <-rpcSub.Err():
+				changesSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				changesSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// reorg is the JSON representation of a canonical chain reorganisation.
+type reorg struct {
+	AncestorHash   common.Hash    `json:"ancestorHash"`
+	AncestorNumber hexutil.Uint64 `json:"ancestorNumber"`
+	Depth          hexutil.Uint64 `json:"depth"`
+	Dropped        []common.Hash  `json:"dropped"`
+	Added          []common.Hash  `json:"added"`
+	DroppedTxs     []common.Hash  `json:"droppedTransactions"`
+}
+
+// Reorgs creates a subscription that is triggered each time the canonical chain
+// is reorganised, reporting the common ancestor, the dropped and added blocks and
+// the dropped transactions that weren't re-included by the new chain.
+func (api *PublicFilterAPI) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ReorgEvent, 10)
+		reorgsSub := api.events.SubscribeReorgs(reorgs)
+
+		for {
+			select {
+			case ev := <-reorgs:
+				r := &reorg{
+					AncestorHash:   ev.Ancestor.Hash(),
+					AncestorNumber: hexutil.Uint64(ev.Ancestor.Number.Uint64()),
+					Depth:          hexutil.Uint64(ev.Depth),
+					Dropped:        ev.Dropped,
+					Added:          ev.Added,
+					DroppedTxs:     make([]common.Hash, 0, len(ev.DroppedTxs)),
+				}
+				for _, tx := range ev.DroppedTxs {
+					r.DroppedTxs = append(r.DroppedTxs, tx.Hash())
+				}
+				notifier.Notify(rpcSub.ID, r)
+			case <-rpcSub.Err():
+				reorgsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				reorgsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with btp_getFilterChanges.
 //
@@ -324,29 +425,21 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 //
 // https://github.com/btpereum/wiki/wiki/JSON-RPC#btp_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
-	var filter *Filter
-	if crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if crit.FromBlock != nil {
-			begin = crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if crit.ToBlock != nil {
-			end = crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
-	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return returnLogs(logs), err
+	return api.cappedLogs(ctx, api.newLogFilter(crit))
+}
+
+// LogsPage is a limited set of logs matching a filter, along with the cursor to
+// retrieve the next page with. The cursor is nil if no more logs are available.
+type LogsPage struct {
+	Logs   []*types.Log `json:"logs"`
+	Cursor *LogCursor   `json:"cursor"`
+}
+
+// GetLogsPage returns at most limit logs matching the given argument that are
+// stored within the state, starting at the given cursor of a previous page.
+// The limit is capped by the server, a zero limit requests the maximum.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, limit hexutil.Uint, cursor *LogCursor) (*LogsPage, error) {
+	return api.pagedLogs(ctx, api.newLogFilter(crit), int(limit), cursor)
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -378,32 +471,73 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if !found || f.typ != LogsSubscription {
 		return nil, fmt.Errorf("filter not found")
 	}
+	return api.cappedLogs(ctx, api.newLogFilter(f.crit))
+}
+
+// GetFilterLogsPage returns at most limit logs for the filter with the given id,
+// starting at the given cursor of a previous page.
+func (api *PublicFilterAPI) GetFilterLogsPage(ctx context.Context, id rpc.ID, limit hexutil.Uint, cursor *LogCursor) (*LogsPage, error) {
+	api.filtersMu.Lock()
+	f, found := api.filters[id]
+	api.filtersMu.Unlock()
+
+	if !found || f.typ != LogsSubscription {
+		return nil, fmt.Errorf("filter not found")
+	}
+	return api.pagedLogs(ctx, api.newLogFilter(f.crit), int(limit), cursor)
+}
 
-	var filter *Filter
-	if f.crit.BlockHash != nil {
+// newLogFilter constructs a single-shot filter retrieving the stored logs
+// matching the given criteria.
+func (api *PublicFilterAPI) newLogFilter(crit FilterCriteria) *Filter {
+	if crit.BlockHash != nil {
 		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *f.crit.BlockHash, f.crit.Addresses, f.crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if f.crit.FromBlock != nil {
-			begin = f.crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if f.crit.ToBlock != nil {
-			end = f.crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
+		return NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
 	}
-	// Run the filter and return all the logs
+	// Convert the RPC block numbers into internal representations
+	begin := rpc.LatestBlockNumber.Int64()
+	if crit.FromBlock != nil {
+		begin = crit.FromBlock.Int64()
+	}
+	end := rpc.LatestBlockNumber.Int64()
+	if crit.ToBlock != nil {
+		end = crit.ToBlock.Int64()
+	}
+	// Construct the range filter
+	return NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
+}
+
+// cappedLogs runs the filter and returns all the logs, failing if there are more
+// of them than the server side cap.
+func (api *PublicFilterAPI) cappedLogs(ctx context.Context, filter *Filter) ([]*types.Log, error) {
+	limit := api.backend.RPCLogsCap()
+	filter.SetPage(nil, limit)
+
 	logs, err := filter.Logs(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if filter.Next() != nil {
+		return nil, fmt.Errorf("query returned more than %d results, use paginated retrieval", limit)
+	}
 	return returnLogs(logs), nil
 }
 
+// pagedLogs runs the filter from the given cursor and returns a single page of
+// logs, the size of which is capped by the server.
+func (api *PublicFilterAPI) pagedLogs(ctx context.Context, filter *Filter, limit int, cursor *LogCursor) (*LogsPage, error) {
+	if logsCap := api.backend.RPCLogsCap(); logsCap > 0 && (limit <= 0 || limit > logsCap) {
+		limit = logsCap
+	}
+	filter.SetPage(cursor, limit)
+
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &LogsPage{Logs: returnLogs(logs), Cursor: filter.Next()}, nil
+}
+
 // GetFilterChanges returns the logs for the filter with the given id since
 // last time it was called. This can be used for polling.
 //
@@ -456,14 +590,39 @@ func returnLogs(logs []*types.Log) []*types.Log {
 	return logs
 }
 
+// criteriaBlockNumber is a block number accepting the "safe" and "finalized" tags
+// on top of the ones understood by rpc.BlockNumber.
+type criteriaBlockNumber rpc.BlockNumber
+
+// UnmarshalJSON parses the given JSON fragment into a block number.
+func (bn *criteriaBlockNumber) UnmarshalJSON(data []byte) error {
+	var tag string
+	if err := json.Unmarshal(data, &tag); err == nil {
+		switch tag {
+		case "safe":
+			*bn = criteriaBlockNumber(rpc.SafeBlockNumber)
+			return nil
+		case "finalized":
+			*bn = criteriaBlockNumber(rpc.FinalizedBlockNumber)
+			return nil
+		}
+	}
+	var number rpc.BlockNumber
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*bn = criteriaBlockNumber(number)
+	return nil
+}
+
 // UnmarshalJSON sets *args fields with given data.
 func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 	type input struct {
-		BlockHash *common.Hash     `json:"blockHash"`
-		FromBlock *rpc.BlockNumber `json:"fromBlock"`
-		ToBlock   *rpc.BlockNumber `json:"toBlock"`
-		Addresses interface{}      `json:"address"`
-		Topics    []interface{}    `json:"topics"`
+		BlockHash *common.Hash         `json:"blockHash"`
+		FromBlock *criteriaBlockNumber `json:"fromBlock"`
+		ToBlock   *criteriaBlockNumber `json:"toBlock"`
+		Addresses interface{}          `json:"address"`
+		Topics    []interface{}        `json:"topics"`
 	}
 
 	var raw input
@@ -479,11 +638,11 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		args.BlockHash = raw.BlockHash
 	} else {
 		if raw.FromBlock != nil {
-			args.FromBlock = big.NewInt(raw.FromBlock.Int64())
+			args.FromBlock = big.NewInt(int64(*raw.FromBlock))
 		}
 
 		if raw.ToBlock != nil {
-			args.ToBlock = big.NewInt(raw.ToBlock.Int64())
+			args.ToBlock = big.NewInt(int64(*raw.ToBlock))
 		}
 	}
 
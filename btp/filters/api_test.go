@@ -67,6 +67,21 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected ToBlock %d, got %d", toBlock, test1.ToBlock)
 	}
 
+	// safe and finalized tags
+	var test1b FilterCriteria
+	if err := json.Unmarshal([]byte(`{"fromBlock":"finalized","toBlock":"safe"}`), &test1b); err != nil {
+		t.Fatal(err)
+	}
+	if test1b.FromBlock.Int64() != rpc.FinalizedBlockNumber.Int64() {
+		t.Fatalf("expected FromBlock %d, got %d", rpc.FinalizedBlockNumber, test1b.FromBlock)
+	}
+	if test1b.ToBlock.Int64() != rpc.SafeBlockNumber.Int64() {
+		t.Fatalf("expected ToBlock %d, got %d", rpc.SafeBlockNumber, test1b.ToBlock)
+	}
+	if err := json.Unmarshal([]byte(`{"fromBlock":"unsafe"}`), &test1b); err == nil {
+		t.Fatal("expected error for unknown block tag")
+	}
+
 	// single address
 	var test2 FilterCriteria
 	vector = fmt.Sprintf(`{"address": "%s"}`, address0.Hex())
@@ -0,0 +1,65 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
+)
+
+// cursorLength is the length of an encoded log cursor: block number, block hash
+// and log index.
+const cursorLength = 8 + common.HashLength + 4
+
+var errInvalidCursor = errors.New("invalid or outdated log cursor")
+
+// LogCursor is an opaque position within the results of a log filter, marking
+// the log a paginated retrieval continues from. The cursor is bound to the hash
+// of the block, becoming invalid if the block is reorged out of the chain.
+type LogCursor struct {
+	number uint64      // Number of the block containing the next log
+	hash   common.Hash // Hash of the block containing the next log
+	index  uint        // Index of the next log within the block
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c LogCursor) MarshalText() ([]byte, error) {
+	blob := make([]byte, cursorLength)
+	binary.BigEndian.PutUint64(blob, c.number)
+	copy(blob[8:], c.hash[:])
+	binary.BigEndian.PutUint32(blob[8+common.HashLength:], uint32(c.index))
+
+	return hexutil.Bytes(blob).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *LogCursor) UnmarshalText(input []byte) error {
+	var blob hexutil.Bytes
+	if err := blob.UnmarshalText(input); err != nil {
+		return err
+	}
+	if len(blob) != cursorLength {
+		return errInvalidCursor
+	}
+	c.number = binary.BigEndian.Uint64(blob)
+	c.hash = common.BytesToHash(blob[8 : 8+common.HashLength])
+	c.index = uint(binary.BigEndian.Uint32(blob[8+common.HashLength:]))
+	return nil
+}
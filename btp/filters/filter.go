@@ -39,12 +39,16 @@ type Backend interface {
 	GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
 
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeTxLifecycleEvent(chan<- core.TxLifecycleEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
+
+	RPCLogsCap() int
 }
 
 // Filter can be used to retrieve and filter logs.
@@ -59,6 +63,10 @@ type Filter struct {
 	begin, end int64       // Range interval if filtering multiple blocks
 
 	matcher *bloombits.Matcher
+
+	limit  int        // Maximum number of logs to return (0 = unlimited)
+	cursor *LogCursor // Position to continue the retrieval from, if any
+	next   *LogCursor // Position of the first log not returned due to the limit
 }
 
 // NewRangeFilter creates a new filter which uses a bloom filter on blocks to
@@ -114,9 +122,24 @@ func newFilter(backend Backend, addresses []common.Address, topics [][]common.Ha
 	}
 }
 
+// SetPage limits the number of logs returned by Logs, continuing the retrieval
+// from the given cursor if it's non-nil. If more logs are available than the
+// limit, the cursor of the next page is returned by Next.
+func (f *Filter) SetPage(cursor *LogCursor, limit int) {
+	f.cursor, f.limit = cursor, limit
+}
+
+// Next returns the cursor to continue the retrieval from after the last call
+// to Logs hit the limit, or nil if all the matching logs have been returned.
+func (f *Filter) Next() *LogCursor {
+	return f.next
+}
+
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
+	f.next = nil
+
 	// If we're doing singleton block filtering, execute and return
 	if f.block != (common.Hash{}) {
 		header, err := f.backend.HeaderByHash(ctx, f.block)
@@ -126,7 +149,11 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		if header == nil {
 			return nil, errors.New("unknown block")
 		}
-		return f.blockLogs(ctx, header)
+		if f.cursor != nil && f.cursor.hash != f.block {
+			return nil, errInvalidCursor
+		}
+		logs, err := f.blockLogs(ctx, header)
+		return f.page(logs), err
 	}
 	// Figure out the limits of the filter range
 	header, _ := f.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
@@ -135,6 +162,14 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	head := header.Number.Uint64()
 
+	// Resolve the safe and finalized tags against the current chain
+	for _, number := range []*int64{&f.begin, &f.end} {
+		resolved, err := f.resolveTag(ctx, *number)
+		if err != nil {
+			return nil, err
+		}
+		*number = resolved
+	}
 	if f.begin == -1 {
 		f.begin = int64(head)
 	}
@@ -142,6 +177,12 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
+	if f.cursor != nil {
+		if f.cursor.number < uint64(f.begin) || f.cursor.number > end {
+			return nil, errInvalidCursor
+		}
+		f.begin = int64(f.cursor.number)
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
@@ -155,12 +196,48 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 			logs, err = f.indexedLogs(ctx, indexed-1)
 		}
 		if err != nil {
-			return logs, err
+			return f.page(logs), err
+		}
+		if f.full(logs) {
+			return f.page(logs), nil
 		}
 	}
 	rest, err := f.unindexedLogs(ctx, end)
 	logs = append(logs, rest...)
-	return logs, err
+	return f.page(logs), err
+}
+
+// full reports whether more logs have been gathered than the limit of the
+// filter, meaning the retrieval can be stopped.
+func (f *Filter) full(logs []*types.Log) bool {
+	return f.limit > 0 && len(logs) > f.limit
+}
+
+// page truncates the gathered logs to the limit of the filter, remembering the
+// position of the first dropped log as the cursor of the next page.
+func (f *Filter) page(logs []*types.Log) []*types.Log {
+	if !f.full(logs) {
+		return logs
+	}
+	next := logs[f.limit]
+	f.next = &LogCursor{number: next.BlockNumber, hash: next.BlockHash, index: next.Index}
+	return logs[:f.limit]
+}
+
+// resolveTag resolves the safe and finalized block number tags into the number of
+// the block they currently reference, leaving any other number untouched.
+func (f *Filter) resolveTag(ctx context.Context, number int64) (int64, error) {
+	if number != rpc.SafeBlockNumber.Int64() && number != rpc.FinalizedBlockNumber.Int64() {
+		return number, nil
+	}
+	header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, errors.New("unknown block")
+	}
+	return header.Number.Int64(), nil
 }
 
 // indexedLogs returns the logs matching the filter criteria based on the bloom
@@ -202,7 +279,9 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			if err != nil {
 				return logs, err
 			}
-			logs = append(logs, found...)
+			if logs = append(logs, found...); f.full(logs) {
+				return logs, nil
+			}
 
 		case <-ctx.Done():
 			return logs, ctx.Err()
@@ -224,7 +303,10 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 		if err != nil {
 			return logs, err
 		}
-		logs = append(logs, found...)
+		if logs = append(logs, found...); f.full(logs) {
+			f.begin++
+			return logs, nil
+		}
 	}
 	return logs, nil
 }
@@ -267,6 +349,15 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 			}
 			logs = filterLogs(unfiltered, nil, nil, f.addresses, f.topics)
 		}
+		// Drop the logs already returned in a previous page
+		if f.cursor != nil && f.cursor.number == header.Number.Uint64() {
+			if f.cursor.hash != header.Hash() {
+				return nil, errInvalidCursor
+			}
+			for len(logs) > 0 && logs[0].Index < f.cursor.index {
+				logs = logs[1:]
+			}
+		}
 		return logs, nil
 	}
 	return nil, nil
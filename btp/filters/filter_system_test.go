@@ -43,9 +43,11 @@ type testBackend struct {
 	db         btpdb.Database
 	sections   uint64
 	txFeed     *event.Feed
+	changeFeed *event.Feed
 	rmLogsFeed *event.Feed
 	logsFeed   *event.Feed
 	chainFeed  *event.Feed
+	reorgFeed  *event.Feed
 }
 
 func (b *testBackend) ChainDb() btpdb.Database {
@@ -61,14 +63,26 @@ func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumbe
 		hash common.Hash
 		num  uint64
 	)
-	if blockNr == rpc.LatestBlockNumber {
+	switch blockNr {
+	case rpc.LatestBlockNumber:
 		hash = rawdb.ReadHeadBlockHash(b.db)
 		number := rawdb.ReadHeaderNumber(b.db, hash)
 		if number == nil {
 			return nil, nil
 		}
 		num = *number
-	} else {
+	case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+		// Resolve the tags to fixed depths below the head: safe 1, finalized 2
+		number := rawdb.ReadHeaderNumber(b.db, rawdb.ReadHeadBlockHash(b.db))
+		if number == nil {
+			return nil, nil
+		}
+		num = *number - 1
+		if blockNr == rpc.FinalizedBlockNumber {
+			num--
+		}
+		hash = rawdb.ReadCanonicalHash(b.db, num)
+	default:
 		num = uint64(blockNr)
 		hash = rawdb.ReadCanonicalHash(b.db, num)
 	}
@@ -108,6 +122,14 @@ func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Sub
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return b.changeFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription {
+	return b.reorgFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -120,6 +142,10 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) RPCLogsCap() int {
+	return 0
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -166,7 +192,7 @@ func TestBlockSubscription(t *testing.T) {
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend     = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, btpash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
@@ -223,7 +249,7 @@ func TestPendingTxFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
@@ -273,6 +299,83 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestTxLifecycleSubscription tests whbtper transaction lifecycle subscriptions
+// receive all the transaction state transitions posted by the pool.
+func TestTxLifecycleSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db         = rawdb.NewMemoryDatabase()
+		changeFeed = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, new(event.Feed), changeFeed, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false)
+
+		changes = []core.TxChange{
+			{Hash: common.HexToHash("0x01"), Status: core.TxQueued},
+			{Hash: common.HexToHash("0x01"), Status: core.TxPromoted},
+			{Hash: common.HexToHash("0x01"), Status: core.TxReplaced, Replacement: common.HexToHash("0x02")},
+			{Hash: common.HexToHash("0x02"), Status: core.TxDropped, Reason: core.DropCapacity},
+		}
+	)
+	ch := make(chan []core.TxChange)
+	sub := api.events.SubscribeTxLifecycle(ch)
+	defer sub.Unsubscribe()
+
+	changeFeed.Send(core.TxLifecycleEvent{Changes: changes[:2]})
+	changeFeed.Send(core.TxLifecycleEvent{Changes: changes[2:]})
+
+	var received []core.TxChange
+	for len(received) < len(changes) {
+		select {
+		case batch := <-ch:
+			received = append(received, batch...)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for changes, received %d/%d", len(received), len(changes))
+		}
+	}
+	for i, change := range received {
+		if change != changes[i] {
+			t.Errorf("change %d mismatch: have %+v, want %+v", i, change, changes[i])
+		}
+	}
+}
+
+// TestReorgsSubscription tests whbtper reorg subscriptions receive the chain
+// reorganisations posted by the backend.
+func TestReorgsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux       = new(event.TypeMux)
+		db        = rawdb.NewMemoryDatabase()
+		reorgFeed = new(event.Feed)
+		backend   = &testBackend{mux, db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), reorgFeed}
+		api       = NewPublicFilterAPI(backend, false)
+
+		reorg = core.ReorgEvent{
+			Ancestor: &types.Header{Number: big.NewInt(1)},
+			Dropped:  []common.Hash{common.HexToHash("0x02")},
+			Added:    []common.Hash{common.HexToHash("0x03"), common.HexToHash("0x04")},
+			Depth:    1,
+		}
+	)
+	ch := make(chan core.ReorgEvent)
+	sub := api.events.SubscribeReorgs(ch)
+	defer sub.Unsubscribe()
+
+	reorgFeed.Send(reorg)
+
+	select {
+	case ev := <-ch:
+		if ev.Ancestor.Hash() != reorg.Ancestor.Hash() || ev.Depth != reorg.Depth || len(ev.Dropped) != 1 || len(ev.Added) != 2 {
+			t.Errorf("reorg mismatch: have %+v, want %+v", ev, reorg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for reorg")
+	}
+}
+
 // TestLogFilterCreation test whbtper a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -283,7 +386,7 @@ func TestLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -298,6 +401,9 @@ func TestLogFilterCreation(t *testing.T) {
 			{FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())}, true},
 			// new mined and pending blocks
 			{FilterCriteria{FromBlock: big.NewInt(rpc.LatestBlockNumber.Int64()), ToBlock: big.NewInt(rpc.PendingBlockNumber.Int64())}, true},
+			// "mined" block range to the safe and finalized tags
+			{FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(rpc.SafeBlockNumber.Int64())}, true},
+			{FilterCriteria{FromBlock: big.NewInt(rpc.FinalizedBlockNumber.Int64()), ToBlock: big.NewInt(rpc.SafeBlockNumber.Int64())}, true},
 			// from block "higher" than to block
 			{FilterCriteria{FromBlock: big.NewInt(2), ToBlock: big.NewInt(1)}, false},
 			// from block "higher" than to block
@@ -306,6 +412,8 @@ func TestLogFilterCreation(t *testing.T) {
 			{FilterCriteria{FromBlock: big.NewInt(rpc.PendingBlockNumber.Int64()), ToBlock: big.NewInt(100)}, false},
 			// from block "higher" than to block
 			{FilterCriteria{FromBlock: big.NewInt(rpc.PendingBlockNumber.Int64()), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())}, false},
+			// from block "higher" than to block
+			{FilterCriteria{FromBlock: big.NewInt(rpc.SafeBlockNumber.Int64()), ToBlock: big.NewInt(100)}, false},
 		}
 	)
 
@@ -332,7 +440,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 	)
 
@@ -359,7 +467,7 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 		blockHash  = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
 	)
@@ -389,7 +497,7 @@ func TestLogFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -508,7 +616,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
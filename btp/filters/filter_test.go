@@ -31,6 +31,7 @@ import (
 	"github.com/btpereum/go-btpereum/crypto"
 	"github.com/btpereum/go-btpereum/event"
 	"github.com/btpereum/go-btpereum/params"
+	"github.com/btpereum/go-btpereum/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -56,7 +57,7 @@ func BenchmarkFilters(b *testing.B) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr1      = crypto.PubkeyToAddress(key1.PublicKey)
 		addr2      = common.BytesToAddress([]byte("jeff"))
@@ -115,7 +116,7 @@ func TestFilters(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr       = crypto.PubkeyToAddress(key1.PublicKey)
 
@@ -204,6 +205,18 @@ func TestFilters(t *testing.T) {
 		t.Errorf("expected log[0].Topics[0] to be %x, got %x", hash3, logs[0].Topics[0])
 	}
 
+	// The safe (#999) and finalized (#998) tags are resolved by the backend
+	filter = NewRangeFilter(backend, 0, rpc.SafeBlockNumber.Int64(), []common.Address{addr}, [][]common.Hash{{hash1, hash2, hash3, hash4}})
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 3 {
+		t.Error("expected 3 log, got", len(logs))
+	}
+	filter = NewRangeFilter(backend, rpc.FinalizedBlockNumber.Int64(), -1, []common.Address{addr}, [][]common.Hash{{hash1, hash2, hash3, hash4}})
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 2 {
+		t.Error("expected 2 log, got", len(logs))
+	}
+
 	filter = NewRangeFilter(backend, 1, 10, nil, [][]common.Hash{{hash1, hash2}})
 
 	logs, _ = filter.Logs(context.Background())
@@ -234,3 +247,83 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+func TestFilterPagination(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filtertest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		db, _   = rawdb.NewLevelDBDatabase(dir, 0, 0, "")
+		backend = &testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	defer db.Close()
+
+	// Create a chain with three matching logs in each block
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, btpash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr}, {Address: addr}, {Address: addr}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Retrieve all the logs in pages crossing block boundaries
+	var (
+		logs   []*types.Log
+		cursor *LogCursor
+		pages  int
+	)
+	for {
+		filter := NewRangeFilter(backend, 0, -1, []common.Address{addr}, nil)
+		filter.SetPage(cursor, 4)
+
+		page, err := filter.Logs(context.Background())
+		if err != nil {
+			t.Fatalf("page %d: failed to retrieve logs: %v", pages, err)
+		}
+		logs, pages = append(logs, page...), pages+1
+
+		if filter.Next() == nil {
+			break
+		}
+		if len(page) != 4 {
+			t.Fatalf("page %d: log count mismatch: have %d, want %d", pages, len(page), 4)
+		}
+		// Pass the cursor through its opaque encoding
+		blob, err := filter.Next().MarshalText()
+		if err != nil {
+			t.Fatalf("page %d: failed to encode cursor: %v", pages, err)
+		}
+		cursor = new(LogCursor)
+		if err := cursor.UnmarshalText(blob); err != nil {
+			t.Fatalf("page %d: failed to decode cursor: %v", pages, err)
+		}
+	}
+	if pages != 8 {
+		t.Errorf("page count mismatch: have %d, want %d", pages, 8)
+	}
+	if len(logs) != 30 {
+		t.Fatalf("log count mismatch: have %d, want %d", len(logs), 30)
+	}
+	for i, log := range logs {
+		if log.BlockNumber != uint64(i/3+1) || log.Index != uint(i%3) {
+			t.Errorf("log %d: position mismatch: have %d/%d, want %d/%d", i, log.BlockNumber, log.Index, i/3+1, i%3)
+		}
+	}
+	// Ensure a cursor pointing to a block not in the chain is rejected
+	filter := NewRangeFilter(backend, 0, -1, []common.Address{addr}, nil)
+	filter.SetPage(&LogCursor{number: 5, hash: common.Hash{0x01}}, 4)
+	if _, err := filter.Logs(context.Background()); err != errInvalidCursor {
+		t.Errorf("stale cursor error mismatch: have %v, want %v", err, errInvalidCursor)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/rpc"
+)
+
+const (
+	// maxFeeHistory is the maximum number of blocks a single fee history may span.
+	maxFeeHistory = 1024
+
+	// feeCacheSize is the number of processed blocks to keep fee data cached for.
+	feeCacheSize = 2048
+)
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errMissingBlock      = errors.New("missing block data")
+)
+
+// txFee is the gas used by a single transaction and the price it paid for it.
+type txFee struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+// blockFees is the fee data of a single processed block.
+type blockFees struct {
+	gasUsed  uint64
+	gasLimit uint64
+	txs      []txFee // Transactions of the block, sorted by ascending price
+}
+
+// FeeHistory returns the fee data of a range of consecutive blocks ending with
+// lastBlock: the ratio of the gas used to the gas limit of each block, and the
+// given percentiles of the gas prices paid in each block, weighted by the gas
+// used by the transactions. The number of the oldest block is also returned.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, []float64, [][]*big.Int, error) {
+	if blocks < 1 {
+		return new(big.Int), nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 {
+			return nil, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < percentiles[i-1] {
+			return nil, nil, nil, fmt.Errorf("%v: #%d:%f > #%d:%f", errInvalidPercentile, i-1, percentiles[i-1], i, p)
+		}
+	}
+	// Resolve the last block of the range, treating pending as the latest
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if head == nil {
+		return nil, nil, nil, errMissingBlock
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	var (
+		ratios  = make([]float64, blocks)
+		rewards [][]*big.Int
+	)
+	if len(percentiles) > 0 {
+		rewards = make([][]*big.Int, blocks)
+	}
+	for i := 0; i < blocks; i++ {
+		fees, err := gpo.blockFees(ctx, oldest+uint64(i))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if fees.gasLimit > 0 {
+			ratios[i] = float64(fees.gasUsed) / float64(fees.gasLimit)
+		}
+		if rewards != nil {
+			rewards[i] = fees.percentiles(percentiles)
+		}
+	}
+	return new(big.Int).SetUint64(oldest), ratios, rewards, nil
+}
+
+// blockFees retrieves the fee data of a canonical block, processing and caching
+// it if not yet available.
+func (gpo *Oracle) blockFees(ctx context.Context, number uint64) (*blockFees, error) {
+	header, err := gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errMissingBlock
+	}
+	hash := header.Hash()
+	if cached, ok := gpo.feeCache.Get(hash); ok {
+		return cached.(*blockFees), nil
+	}
+	fees := &blockFees{gasUsed: header.GasUsed, gasLimit: header.GasLimit}
+	if header.TxHash != types.EmptyRootHash {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		receipts, err := gpo.backend.GetReceipts(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil || block.Hash() != hash || len(receipts) != len(block.Transactions()) {
+			return nil, errMissingBlock
+		}
+		fees.txs = make([]txFee, len(receipts))
+		for i, tx := range block.Transactions() {
+			fees.txs[i] = txFee{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()}
+		}
+		sort.Slice(fees.txs, func(i, j int) bool {
+			return fees.txs[i].price.Cmp(fees.txs[j].price) < 0
+		})
+	}
+	gpo.feeCache.Add(hash, fees)
+	return fees, nil
+}
+
+// percentiles calculates the gas prices at the given percentiles of the gas used
+// within the block. Empty blocks report zero prices.
+func (fees *blockFees) percentiles(percentiles []float64) []*big.Int {
+	prices := make([]*big.Int, len(percentiles))
+	if len(fees.txs) == 0 {
+		for i := range prices {
+			prices[i] = new(big.Int)
+		}
+		return prices
+	}
+	var (
+		idx     int
+		sumUsed = fees.txs[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(fees.gasUsed) * p / 100)
+		for sumUsed < threshold && idx < len(fees.txs)-1 {
+			idx++
+			sumUsed += fees.txs[idx].gasUsed
+		}
+		prices[i] = new(big.Int).Set(fees.txs[idx].price)
+	}
+	return prices
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/internal/btpapi"
+	"github.com/btpereum/go-btpereum/params"
+	"github.com/btpereum/go-btpereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// feeTestBackend is a chain of pre-built blocks and receipts serving the fee
+// history queries of the oracle, counting the block bodies it had to serve.
+type feeTestBackend struct {
+	btpapi.Backend
+
+	blocks   []*types.Block
+	receipts []types.Receipts
+	bodies   int
+}
+
+func (b *feeTestBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(len(b.blocks) - 1)
+	}
+	if number < 0 || int(number) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[number].Header(), nil
+}
+
+func (b *feeTestBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(len(b.blocks) - 1)
+	}
+	if number < 0 || int(number) >= len(b.blocks) {
+		return nil, nil
+	}
+	b.bodies++
+	return b.blocks[number], nil
+}
+
+func (b *feeTestBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	for i, block := range b.blocks {
+		if block.Hash() == hash {
+			return b.receipts[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// newFeeTestBackend creates a chain of an empty genesis block followed by the
+// given blocks, each described by the gas used and price of its transactions.
+func newFeeTestBackend(blocks [][]txFee) *feeTestBackend {
+	backend := &feeTestBackend{
+		blocks:   []*types.Block{types.NewBlock(&types.Header{Number: new(big.Int), GasLimit: params.GenesisGasLimit}, nil, nil, nil)},
+		receipts: []types.Receipts{nil},
+	}
+	for i, fees := range blocks {
+		var (
+			txs      []*types.Transaction
+			receipts types.Receipts
+			gasUsed  uint64
+		)
+		for j, fee := range fees {
+			txs = append(txs, types.NewTransaction(uint64(j), common.Address{}, new(big.Int), fee.gasUsed, fee.price, nil))
+			receipts = append(receipts, &types.Receipt{GasUsed: fee.gasUsed})
+			gasUsed += fee.gasUsed
+		}
+		header := &types.Header{
+			ParentHash: backend.blocks[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			GasLimit:   params.GenesisGasLimit,
+			GasUsed:    gasUsed,
+		}
+		backend.blocks = append(backend.blocks, types.NewBlock(header, txs, nil, receipts))
+		backend.receipts = append(backend.receipts, receipts)
+	}
+	return backend
+}
+
+// Tests that the fee percentiles of a block are weighted by the gas used by its
+// transactions, not by their count.
+func TestBlockFeesPercentiles(t *testing.T) {
+	// Create a block whose cheapest and most expensive transactions use little
+	// gas, and the second cheapest one uses most of the block
+	fees := &blockFees{
+		gasUsed:  210000,
+		gasLimit: 420000,
+		txs: []txFee{
+			{gasUsed: 21000, price: big.NewInt(1)},
+			{gasUsed: 126000, price: big.NewInt(2)},
+			{gasUsed: 42000, price: big.NewInt(3)},
+			{gasUsed: 21000, price: big.NewInt(4)},
+		},
+	}
+	tests := []struct {
+		percentile float64
+		price      int64
+	}{
+		{0, 1},
+		{10, 1},
+		{11, 2},
+		{50, 2},
+		{70, 2},
+		{71, 3},
+		{90, 3},
+		{91, 4},
+		{100, 4},
+	}
+	percentiles := make([]float64, len(tests))
+	for i, tt := range tests {
+		percentiles[i] = tt.percentile
+	}
+	prices := fees.percentiles(percentiles)
+	for i, tt := range tests {
+		if prices[i].Int64() != tt.price {
+			t.Errorf("percentile %v: price mismatch: have %v, want %v", tt.percentile, prices[i], tt.price)
+		}
+	}
+	// Empty blocks should report zero prices for all percentiles
+	empty := new(blockFees).percentiles(percentiles)
+	for i, price := range empty {
+		if price.Sign() != 0 {
+			t.Errorf("percentile %v: empty block price mismatch: have %v, want 0", percentiles[i], price)
+		}
+	}
+}
+
+// Tests that fee histories are calculated from the blocks in range and that the
+// processed blocks are cached and not retrieved again.
+func TestFeeHistoryCache(t *testing.T) {
+	backend := newFeeTestBackend([][]txFee{
+		{{gasUsed: 21000, price: big.NewInt(3)}, {gasUsed: 63000, price: big.NewInt(1)}},
+		{{gasUsed: 100000, price: big.NewInt(5)}, {gasUsed: 21000, price: big.NewInt(2)}, {gasUsed: 21000, price: big.NewInt(9)}},
+	})
+	cache, _ := lru.New(feeCacheSize)
+	gpo := &Oracle{backend: backend, feeCache: cache}
+
+	for i := 0; i < 2; i++ {
+		oldest, ratios, rewards, err := gpo.FeeHistory(context.Background(), 3, rpc.LatestBlockNumber, []float64{10, 50})
+		if err != nil {
+			t.Fatalf("query %d: failed to retrieve fee history: %v", i, err)
+		}
+		if oldest.Uint64() != 0 {
+			t.Errorf("query %d: oldest block mismatch: have %v, want 0", i, oldest)
+		}
+		wantRatios := []float64{0, float64(84000) / float64(params.GenesisGasLimit), float64(142000) / float64(params.GenesisGasLimit)}
+		for j, ratio := range ratios {
+			if ratio != wantRatios[j] {
+				t.Errorf("query %d, block %d: gas ratio mismatch: have %v, want %v", i, j, ratio, wantRatios[j])
+			}
+		}
+		wantRewards := [][]int64{{0, 0}, {1, 1}, {2, 5}}
+		for j, reward := range rewards {
+			for k, price := range reward {
+				if price.Int64() != wantRewards[j][k] {
+					t.Errorf("query %d, block %d, percentile %d: reward mismatch: have %v, want %v", i, j, k, price, wantRewards[j][k])
+				}
+			}
+		}
+		// Only the two non-empty blocks should ever be retrieved, and only once
+		if backend.bodies != 2 {
+			t.Errorf("query %d: block retrievals mismatch: have %d, want %d", i, backend.bodies, 2)
+		}
+		if cache.Len() != 3 {
+			t.Errorf("query %d: cached blocks mismatch: have %d, want %d", i, cache.Len(), 3)
+		}
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/btpereum/go-btpereum/internal/btpapi"
 	"github.com/btpereum/go-btpereum/params"
 	"github.com/btpereum/go-btpereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 var maxPrice = big.NewInt(500 * params.GWei)
@@ -35,6 +36,9 @@ type Config struct {
 	Blocks     int
 	Percentile int
 	Default    *big.Int `toml:",omitempty"`
+
+	Mempool       bool // Whbtper to also account for the pending pool in price suggestions
+	MempoolBlocks int  // Number of upcoming blocks the pending pool is spread across (slow inclusion)
 }
 
 // Oracle recommends gas prices based on the content of recent
@@ -48,6 +52,15 @@ type Oracle struct {
 
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
+
+	mempool       bool
+	mempoolBlocks int
+
+	lastPoolHead   common.Hash       // Head block of the cached pool based suggestions
+	lastPoolGen    uint64            // Pool generation of the cached pool based suggestions
+	lastPoolPrices *PriceSuggestions // Cached pool based suggestions, nil if none
+
+	feeCache *lru.Cache // Cache of processed block fee data, by block hash
 }
 
 // NewOracle returns a new oracle.
@@ -63,18 +76,39 @@ func NewOracle(backend btpapi.Backend, params Config) *Oracle {
 	if percent > 100 {
 		percent = 100
 	}
+	mempoolBlocks := params.MempoolBlocks
+	if mempoolBlocks < 1 {
+		mempoolBlocks = 1
+	}
+	feeCache, _ := lru.New(feeCacheSize)
 	return &Oracle{
-		backend:     backend,
-		lastPrice:   params.Default,
-		checkBlocks: blocks,
-		maxEmpty:    blocks / 2,
-		maxBlocks:   blocks * 5,
-		percentile:  percent,
+		backend:       backend,
+		lastPrice:     params.Default,
+		checkBlocks:   blocks,
+		maxEmpty:      blocks / 2,
+		maxBlocks:     blocks * 5,
+		percentile:    percent,
+		mempool:       params.Mempool,
+		mempoolBlocks: mempoolBlocks,
+		feeCache:      feeCache,
 	}
 }
 
-// SuggestPrice returns the recommended gas price.
+// SuggestPrice returns the recommended gas price. In mempool mode, this is the
+// standard price of SuggestPrices.
 func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	if !gpo.mempool {
+		return gpo.suggestBlockPrice(ctx)
+	}
+	prices, err := gpo.SuggestPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return prices.Standard, nil
+}
+
+// suggestBlockPrice returns the gas price recommended by the recent blocks.
+func (gpo *Oracle) suggestBlockPrice(ctx context.Context) (*big.Int, error) {
 	gpo.cacheLock.RLock()
 	lastHead := gpo.lastHead
 	lastPrice := gpo.lastPrice
@@ -0,0 +1,126 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/rpc"
+)
+
+// poolGenerationBackend is implemented by backends able to report a counter of
+// the changes to their transaction pool, allowing pool based price suggestions
+// to be reused until either the pool or the chain head changes.
+type poolGenerationBackend interface {
+	TxPoolGeneration() uint64
+}
+
+// PriceSuggestions are the gas prices estimated to be needed for a transaction
+// to be included within increasing numbers of blocks.
+type PriceSuggestions struct {
+	Fast     *big.Int // Price for inclusion within the next block
+	Standard *big.Int // Price for inclusion within half of the target blocks
+	Slow     *big.Int // Price for inclusion within the target blocks
+}
+
+// SuggestPrices returns the recommended gas prices for a fast, standard and slow
+// inclusion. In mempool mode the prices also account for the transactions in
+// the pending pool competing for the next blocks, otherwise all levels equal
+// the price suggested by recent blocks.
+func (gpo *Oracle) SuggestPrices(ctx context.Context) (*PriceSuggestions, error) {
+	price, err := gpo.suggestBlockPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !gpo.mempool {
+		return &PriceSuggestions{Fast: price, Standard: price, Slow: price}, nil
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	// Reuse the last suggestions if neither the head nor the pool changed since.
+	// The generation is retrieved before the pool content, so a concurrent change
+	// can only cause a superfluous recalculation, never a stale cache.
+	var (
+		headHash     = head.Hash()
+		gen, tracked = uint64(0), false
+	)
+	if backend, ok := gpo.backend.(poolGenerationBackend); ok {
+		gen, tracked = backend.TxPoolGeneration(), true
+	}
+	if tracked {
+		gpo.cacheLock.RLock()
+		lastHead, lastGen, lastPrices := gpo.lastPoolHead, gpo.lastPoolGen, gpo.lastPoolPrices
+		gpo.cacheLock.RUnlock()
+
+		if lastPrices != nil && headHash == lastHead && gen == lastGen {
+			return lastPrices, nil
+		}
+	}
+	pending, err := gpo.backend.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*types.Transaction, len(pending))
+	copy(txs, pending)
+	sort.Sort(sort.Reverse(transactionsByGasPrice(txs)))
+
+	standard := (gpo.mempoolBlocks + 1) / 2
+	prices := &PriceSuggestions{
+		Fast:     maxBig(price, poolPrice(txs, head.GasLimit, 1)),
+		Standard: maxBig(price, poolPrice(txs, head.GasLimit, standard)),
+		Slow:     maxBig(price, poolPrice(txs, head.GasLimit, gpo.mempoolBlocks)),
+	}
+	if tracked {
+		gpo.cacheLock.Lock()
+		gpo.lastPoolHead, gpo.lastPoolGen, gpo.lastPoolPrices = headHash, gen, prices
+		gpo.cacheLock.Unlock()
+	}
+	return prices, nil
+}
+
+// poolPrice estimates the gas price needed to be included within the given
+// number of blocks, assuming the miners include the pending transactions (sorted
+// by descending price) in price order. If the pool doesn't fill the blocks, nil
+// is returned.
+func poolPrice(txs []*types.Transaction, gasLimit uint64, blocks int) *big.Int {
+	var (
+		capacity = gasLimit * uint64(blocks)
+		gas      uint64
+	)
+	for _, tx := range txs {
+		if gas += tx.Gas(); gas >= capacity {
+			if price := tx.GasPrice(); price.Cmp(maxPrice) < 0 {
+				return price
+			}
+			return new(big.Int).Set(maxPrice)
+		}
+	}
+	return nil
+}
+
+// maxBig returns the larger of the two numbers, treating nil as the smallest.
+func maxBig(a, b *big.Int) *big.Int {
+	if b == nil || (a != nil && a.Cmp(b) >= 0) {
+		return a
+	}
+	return b
+}
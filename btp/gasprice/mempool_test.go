@@ -0,0 +1,63 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/params"
+)
+
+// Tests that the pool based price estimation picks the price of the transaction
+// filling up the requested number of blocks, if the pool fills them at all.
+func TestPoolPrice(t *testing.T) {
+	// Create a pool of transactions using one third of a block each, sorted by
+	// descending price as the oracle does
+	var (
+		gasLimit = 3 * params.TxGas
+		txs      []*types.Transaction
+	)
+	for i := 0; i < 9; i++ {
+		price := big.NewInt(int64(100 - i))
+		txs = append(txs, types.NewTransaction(uint64(i), common.Address{}, new(big.Int), params.TxGas, price, nil))
+	}
+	expensive := types.NewTransaction(0, common.Address{}, new(big.Int), gasLimit, new(big.Int).Add(maxPrice, common.Big1), nil)
+
+	tests := []struct {
+		txs    []*types.Transaction
+		blocks int
+		want   *big.Int
+	}{
+		{nil, 1, nil},                                  // Empty pool
+		{txs[:2], 1, nil},                              // Pool not filling a single block
+		{txs[:3], 1, big.NewInt(98)},                   // Pool filling exactly one block
+		{txs, 1, big.NewInt(98)},                       // Pool filling several blocks, next block
+		{txs, 2, big.NewInt(95)},                       // Pool filling several blocks, two blocks
+		{txs, 3, big.NewInt(92)},                       // Pool filling several blocks, all of them
+		{txs, 4, nil},                                  // Pool filling fewer blocks than requested
+		{[]*types.Transaction{expensive}, 1, maxPrice}, // Prices capped at the maximum
+	}
+	for i, tt := range tests {
+		have := poolPrice(tt.txs, gasLimit, tt.blocks)
+		if (have == nil) != (tt.want == nil) || (have != nil && have.Cmp(tt.want) != 0) {
+			t.Errorf("test %d: price mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
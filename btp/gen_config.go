@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
 	"github.com/btpereum/go-btpereum/consensus/btpash"
 	"github.com/btpereum/go-btpereum/core"
 	"github.com/btpereum/go-btpereum/btp/downloader"
@@ -23,7 +24,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
 		NoPrefetch              bool
+		TraceIndex              bool
 		Whitelist               map[uint64]common.Hash `toml:"-"`
+		ServeRequestRate        int                    `toml:",omitempty"`
+		ServePeerBandwidth      int                    `toml:",omitempty"`
+		ServeTotalBandwidth     int                    `toml:",omitempty"`
 		LightServ               int                    `toml:",omitempty"`
 		LightBandwidthIn        int                    `toml:",omitempty"`
 		LightBandwidthOut       int                    `toml:",omitempty"`
@@ -47,8 +52,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		EVMInterpreter          string
 		ConstantinopleOverride  *big.Int
 		RPCGasCap               *big.Int `toml:",omitempty"`
+		RPCLogsCap              int
+		RPCSafeDepth            uint64
+		RPCTraceTimeout         time.Duration
+		RPCTraceTimeoutCap      time.Duration
+		RPCTraceResultSize      uint64
+		RPCTraceResultSizeCap   uint64
+		RPCTraceHeapSize        uint64
+		RPCTraceHeapSizeCap     uint64
 		Checkpoint              *params.TrustedCheckpoint
 		CheckpointOracle        *params.CheckpointOracleConfig
+		CheckpointSync          bool            `toml:",omitempty"`
+		CheckpointSignatures    []hexutil.Bytes `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -56,7 +71,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.TraceIndex = c.TraceIndex
 	enc.Whitelist = c.Whitelist
+	enc.ServeRequestRate = c.ServeRequestRate
+	enc.ServePeerBandwidth = c.ServePeerBandwidth
+	enc.ServeTotalBandwidth = c.ServeTotalBandwidth
 	enc.LightServ = c.LightServ
 	enc.LightBandwidthIn = c.LightBandwidthIn
 	enc.LightBandwidthOut = c.LightBandwidthOut
@@ -79,8 +98,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.EWASMInterpreter = c.EWASMInterpreter
 	enc.EVMInterpreter = c.EVMInterpreter
 	enc.RPCGasCap = c.RPCGasCap
+	enc.RPCLogsCap = c.RPCLogsCap
+	enc.RPCSafeDepth = c.RPCSafeDepth
+	enc.RPCTraceTimeout = c.RPCTraceTimeout
+	enc.RPCTraceTimeoutCap = c.RPCTraceTimeoutCap
+	enc.RPCTraceResultSize = c.RPCTraceResultSize
+	enc.RPCTraceResultSizeCap = c.RPCTraceResultSizeCap
+	enc.RPCTraceHeapSize = c.RPCTraceHeapSize
+	enc.RPCTraceHeapSizeCap = c.RPCTraceHeapSizeCap
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
+	enc.CheckpointSync = c.CheckpointSync
+	enc.CheckpointSignatures = c.CheckpointSignatures
 	return &enc, nil
 }
 
@@ -92,7 +121,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
 		NoPrefetch              *bool
+		TraceIndex              *bool
 		Whitelist               map[uint64]common.Hash `toml:"-"`
+		ServeRequestRate        *int                   `toml:",omitempty"`
+		ServePeerBandwidth      *int                   `toml:",omitempty"`
+		ServeTotalBandwidth     *int                   `toml:",omitempty"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightBandwidthIn        *int                   `toml:",omitempty"`
 		LightBandwidthOut       *int                   `toml:",omitempty"`
@@ -115,8 +148,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		EWASMInterpreter        *string
 		EVMInterpreter          *string
 		RPCGasCap               *big.Int `toml:",omitempty"`
+		RPCLogsCap              *int
+		RPCSafeDepth            *uint64
+		RPCTraceTimeout         *time.Duration
+		RPCTraceTimeoutCap      *time.Duration
+		RPCTraceResultSize      *uint64
+		RPCTraceResultSizeCap   *uint64
+		RPCTraceHeapSize        *uint64
+		RPCTraceHeapSizeCap     *uint64
 		Checkpoint              *params.TrustedCheckpoint
 		CheckpointOracle        *params.CheckpointOracleConfig
+		CheckpointSync          *bool           `toml:",omitempty"`
+		CheckpointSignatures    []hexutil.Bytes `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -137,9 +180,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.TraceIndex != nil {
+		c.TraceIndex = *dec.TraceIndex
+	}
 	if dec.Whitelist != nil {
 		c.Whitelist = dec.Whitelist
 	}
+	if dec.ServeRequestRate != nil {
+		c.ServeRequestRate = *dec.ServeRequestRate
+	}
+	if dec.ServePeerBandwidth != nil {
+		c.ServePeerBandwidth = *dec.ServePeerBandwidth
+	}
+	if dec.ServeTotalBandwidth != nil {
+		c.ServeTotalBandwidth = *dec.ServeTotalBandwidth
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -206,11 +261,41 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCGasCap != nil {
 		c.RPCGasCap = dec.RPCGasCap
 	}
+	if dec.RPCLogsCap != nil {
+		c.RPCLogsCap = *dec.RPCLogsCap
+	}
+	if dec.RPCSafeDepth != nil {
+		c.RPCSafeDepth = *dec.RPCSafeDepth
+	}
+	if dec.RPCTraceTimeout != nil {
+		c.RPCTraceTimeout = *dec.RPCTraceTimeout
+	}
+	if dec.RPCTraceTimeoutCap != nil {
+		c.RPCTraceTimeoutCap = *dec.RPCTraceTimeoutCap
+	}
+	if dec.RPCTraceResultSize != nil {
+		c.RPCTraceResultSize = *dec.RPCTraceResultSize
+	}
+	if dec.RPCTraceResultSizeCap != nil {
+		c.RPCTraceResultSizeCap = *dec.RPCTraceResultSizeCap
+	}
+	if dec.RPCTraceHeapSize != nil {
+		c.RPCTraceHeapSize = *dec.RPCTraceHeapSize
+	}
+	if dec.RPCTraceHeapSizeCap != nil {
+		c.RPCTraceHeapSizeCap = *dec.RPCTraceHeapSizeCap
+	}
 	if dec.Checkpoint != nil {
 		c.Checkpoint = dec.Checkpoint
 	}
 	if dec.CheckpointOracle != nil {
 		c.CheckpointOracle = dec.CheckpointOracle
 	}
+	if dec.CheckpointSync != nil {
+		c.CheckpointSync = *dec.CheckpointSync
+	}
+	if dec.CheckpointSignatures != nil {
+		c.CheckpointSignatures = dec.CheckpointSignatures
+	}
 	return nil
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/btpereum/go-btpereum/core/types"
 	"github.com/btpereum/go-btpereum/btp/downloader"
 	"github.com/btpereum/go-btpereum/btp/fetcher"
+	"github.com/btpereum/go-btpereum/btp/peerscore"
 	"github.com/btpereum/go-btpereum/btpdb"
 	"github.com/btpereum/go-btpereum/event"
 	"github.com/btpereum/go-btpereum/log"
@@ -65,8 +66,9 @@ func errResp(code errCode, format string, v ...interface{}) error {
 type ProtocolManager struct {
 	networkID uint64
 
-	fastSync  uint32 // Flag whbtper fast sync is enabled (gets disabled if we already have blocks)
-	acceptTxs uint32 // Flag whbtper we're considered synchronised (enables transaction processing)
+	fastSync   uint32 // Flag whbtper fast sync is enabled (gets disabled if we already have blocks)
+	headerSync bool   // Flag whbtper only the header chain is synced, without bodies or state
+	acceptTxs  uint32 // Flag whbtper we're considered synchronised (enables transaction processing)
 
 	checkpointNumber uint64      // Block number for the sync progress validator to cross reference
 	checkpointHash   common.Hash // Block hash for the sync progress validator to cross reference
@@ -78,6 +80,8 @@ type ProtocolManager struct {
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
+	scores     *peerscore.Tracker
+	budget     *servingBudget
 
 	eventMux      *event.TypeMux
 	txsCh         chan core.NewTxsEvent
@@ -107,13 +111,20 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 		txpool:      txpool,
 		blockchain:  blockchain,
 		peers:       newPeerSet(),
+		scores:      peerscore.New(nil),
+		budget:      newServingBudget(0, 0, 0),
 		whitelist:   whitelist,
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
 	}
-	if mode == downloader.FullSync {
+	switch mode {
+	case downloader.HeaderSync:
+		// Header syncing never imports blocks, so it can't switch to any other mode
+		manager.headerSync = true
+
+	case downloader.FullSync:
 		// The database seems empty as the current block is the genesis. Yet the fast
 		// block is ahead, so fast sync was enabled for this node at a certain point.
 		// The scenarios where this can happen is
@@ -127,7 +138,8 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 			manager.fastSync = uint32(1)
 			log.Warn("Switch sync mode from full sync to fast sync")
 		}
-	} else {
+
+	default:
 		if blockchain.CurrentBlock().NumberU64() > 0 {
 			// Print warning log if database is not empty to run fast sync.
 			log.Warn("Switch sync mode from fast sync to full sync")
@@ -149,7 +161,7 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 	if atomic.LoadUint32(&manager.fastSync) == 1 {
 		stateBloom = trie.NewSyncBloom(uint64(cacheLimit), chaindb)
 	}
-	manager.downloader = downloader.New(manager.checkpointNumber, chaindb, stateBloom, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(manager.checkpointNumber, manager.checkpointHash, chaindb, stateBloom, manager.eventMux, blockchain, nil, manager.removePeer, manager.scores)
 
 	// Construct the fetcher (short sync)
 	validator := func(header *types.Header) error {
@@ -169,6 +181,11 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 			log.Warn("Unsynced yet, discarded propagated block", "number", blocks[0].Number(), "hash", blocks[0].Hash())
 			return 0, nil
 		}
+		// Header syncing nodes don't keep any block bodies or state to import onto
+		if manager.headerSync {
+			log.Debug("Header syncing, discarded propagated block", "number", blocks[0].Number(), "hash", blocks[0].Hash())
+			return 0, nil
+		}
 		// If fast sync is running, deny importing weird blocks. This is a problematic
 		// clause when starting up a new network, because fast-syncing miners might not
 		// accept each others' blocks until a restart. Unfortunately we haven't figured
@@ -184,7 +201,7 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 		}
 		return n, err
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer, manager.scores)
 
 	return manager, nil
 }
@@ -215,7 +232,9 @@ func (pm *ProtocolManager) makeProtocol(version uint) p2p.Protocol {
 		},
 		PeerInfo: func(id enode.ID) interface{} {
 			if p := pm.peers.Peer(fmt.Sprintf("%x", id[:8])); p != nil {
-				return p.Info()
+				info := p.Info()
+				info.Reputation = pm.scores.Info(p.id)
+				return info
 			}
 			return nil
 		},
@@ -235,6 +254,7 @@ func (pm *ProtocolManager) removePeer(id string) {
 	if err := pm.peers.Unregister(id); err != nil {
 		log.Error("Peer removal failed", "peer", id, "err", err)
 	}
+	pm.scores.Unregister(id)
 	// Hard disconnect at the networking layer
 	if peer != nil {
 		peer.Peer.Disconnect(p2p.DiscUselessPeer)
@@ -284,7 +304,9 @@ func (pm *ProtocolManager) Stop() {
 }
 
 func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return newPeer(pv, p, newMeteredMsgWriter(rw))
+	peer := newPeer(pv, p, newMeteredMsgWriter(rw))
+	peer.budget = pm.budget.newPeerBudget()
+	return peer
 }
 
 // handle is the callback invoked to manage the life cycle of an btp peer. When
@@ -316,6 +338,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		p.Log().Error("btpereum peer registration failed", "err", err)
 		return err
 	}
+	pm.scores.Register(p.id, p.ID())
 	defer pm.removePeer(p.id)
 
 	// Register the peer in the downloader. If the downloader considers it banned, we disconnect
@@ -335,6 +358,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		// Start a timer to disconnect if the peer doesn't reply in time
 		p.syncDrop = time.AfterFunc(syncChallengeTimeout, func() {
 			p.Log().Warn("Checkpoint challenge timed out, dropping", "addr", p.RemoteAddr(), "type", p.Name())
+			pm.scores.Record(p.id, peerscore.Timeout)
 			pm.removePeer(p.id)
 		})
 		// Make sure it's cleaned up if the peer dies off
@@ -386,6 +410,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&query); err != nil {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
+		pm.budget.wait(p.budget, pm.quitSync)
+
 		hashMode := query.Origin.Hash != (common.Hash{})
 		first := true
 		maxNonCanonical := uint64(100)
@@ -465,6 +491,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				query.Origin.Number += query.Skip + 1
 			}
 		}
+		pm.budget.charge(p.budget, int(bytes))
 		return p.SendBlockHeaders(headers)
 
 	case msg.Code == BlockHeadersMsg:
@@ -498,6 +525,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 				// Validate the header and either drop the peer or continue
 				if headers[0].Hash() != pm.checkpointHash {
+					pm.scores.Record(p.id, peerscore.InvalidBlock)
 					return errors.New("checkpoint hash mismatch")
 				}
 				return nil
@@ -506,6 +534,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if want, ok := pm.whitelist[headers[0].Number.Uint64()]; ok {
 				if hash := headers[0].Hash(); want != hash {
 					p.Log().Info("Whitelist mismatch, dropping peer", "number", headers[0].Number.Uint64(), "hash", hash, "want", want)
+					pm.scores.Record(p.id, peerscore.InvalidBlock)
 					return errors.New("whitelist block mismatch")
 				}
 				p.Log().Debug("Whitelist block verified", "number", headers[0].Number.Uint64(), "hash", want)
@@ -526,6 +555,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if _, err := msgStream.List(); err != nil {
 			return err
 		}
+		pm.budget.wait(p.budget, pm.quitSync)
+
 		// Gather blocks until the fetch or network limits is reached
 		var (
 			hash   common.Hash
@@ -545,6 +576,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				bytes += len(data)
 			}
 		}
+		pm.budget.charge(p.budget, bytes)
 		return p.SendBlockBodiesRLP(bodies)
 
 	case msg.Code == BlockBodiesMsg:
@@ -579,6 +611,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if _, err := msgStream.List(); err != nil {
 			return err
 		}
+		pm.budget.wait(p.budget, pm.quitSync)
+
 		// Gather state data until the fetch or network limits is reached
 		var (
 			hash  common.Hash
@@ -598,6 +632,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				bytes += len(entry)
 			}
 		}
+		pm.budget.charge(p.budget, bytes)
 		return p.SendNodeData(data)
 
 	case p.version >= btp63 && msg.Code == NodeDataMsg:
@@ -617,6 +652,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if _, err := msgStream.List(); err != nil {
 			return err
 		}
+		pm.budget.wait(p.budget, pm.quitSync)
+
 		// Gather state data until the fetch or network limits is reached
 		var (
 			hash     common.Hash
@@ -645,6 +682,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				bytes += len(encoded)
 			}
 		}
+		pm.budget.charge(p.budget, bytes)
 		return p.SendReceiptsRLP(receipts)
 
 	case p.version >= btp63 && msg.Code == ReceiptsMsg:
@@ -685,6 +723,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
 		if err := request.sanityCheck(); err != nil {
+			pm.scores.Record(p.id, peerscore.InvalidBlock)
 			return err
 		}
 		request.Block.ReceivedAt = msg.ReceivedAt
@@ -54,6 +54,10 @@ var (
 	miscInTrafficMeter        = metrics.NewRegisteredMeter("btp/misc/in/traffic", nil)
 	miscOutPacketsMeter       = metrics.NewRegisteredMeter("btp/misc/out/packets", nil)
 	miscOutTrafficMeter       = metrics.NewRegisteredMeter("btp/misc/out/traffic", nil)
+
+	serveBytesMeter     = metrics.NewRegisteredMeter("btp/serve/bytes", nil)
+	serveThrottledMeter = metrics.NewRegisteredMeter("btp/serve/throttled", nil)
+	serveDelayTimer     = metrics.NewRegisteredTimer("btp/serve/delay", nil)
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	mapset "github.com/deckarep/golang-set"
+	"github.com/btpereum/go-btpereum/btp/peerscore"
 	"github.com/btpereum/go-btpereum/common"
 	"github.com/btpereum/go-btpereum/core/types"
 	"github.com/btpereum/go-btpereum/p2p"
@@ -61,9 +62,10 @@ const (
 // PeerInfo represents a short summary of the btpereum sub-protocol metadata known
 // about a connected peer.
 type PeerInfo struct {
-	Version    int      `json:"version"`    // btpereum protocol version negotiated
-	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
-	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	Version    int             `json:"version"`              // btpereum protocol version negotiated
+	Difficulty *big.Int        `json:"difficulty"`           // Total difficulty of the peer's blockchain
+	Head       string          `json:"head"`                 // SHA3 hash of the peer's best owned block
+	Reputation *peerscore.Info `json:"reputation,omitempty"` // Reputation score and misbehaviour statistics of the peer
 }
 
 // propEvent is a block propagation, waiting for its turn in the broadcast queue.
@@ -80,6 +82,7 @@ type peer struct {
 
 	version  int         // Protocol version negotiated
 	syncDrop *time.Timer // Timed connection dropper if sync progress isn't validated in time
+	budget   *peerBudget // Allowance for serving the data requests of the peer
 
 	head common.Hash
 	td   *big.Int
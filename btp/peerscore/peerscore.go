@@ -0,0 +1,247 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package peerscore implements a reputation tracker for btp peers, recording
+// misbehaviour and responsiveness across connections.
+package peerscore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/btpereum/go-btpereum/log"
+	"github.com/btpereum/go-btpereum/p2p/enode"
+)
+
+const (
+	MaxScore = 100  // Upper bound of a peer's reputation
+	MinScore = -100 // Lower bound of a peer's reputation
+
+	// LowScore is the reputation below which a peer is deprioritised for
+	// request assignment. It is shared with the dial threshold of the p2p server.
+	LowScore = enode.LowNodeScore
+
+	slowResponse      = 5 * time.Second // Latency above which responses are penalised
+	measurementImpact = 0.1             // The impact a single latency measurement has on the average
+)
+
+// Event is a scored peer behaviour.
+type Event int
+
+const (
+	// Timeout is recorded when a peer fails to answer a request in time.
+	Timeout Event = iota
+
+	// InvalidBlock is recorded when a peer delivers a header or block failing
+	// validation.
+	InvalidBlock
+
+	// UselessResponse is recorded when a peer delivers nothing of what was
+	// requested.
+	UselessResponse
+
+	// UsefulResponse is recorded when a peer delivers valid requested data.
+	UsefulResponse
+)
+
+// weights is the score change caused by each event.
+var weights = map[Event]int64{
+	Timeout:         -10,
+	InvalidBlock:    -50,
+	UselessResponse: -5,
+	UsefulResponse:  1,
+}
+
+// String implements fmt.Stringer.
+func (ev Event) String() string {
+	switch ev {
+	case Timeout:
+		return "timeout"
+	case InvalidBlock:
+		return "invalid block"
+	case UselessResponse:
+		return "useless response"
+	case UsefulResponse:
+		return "useful response"
+	default:
+		return "unknown"
+	}
+}
+
+// Info is the summary of a tracked peer's reputation.
+type Info struct {
+	Score     int64         `json:"score"`     // Current reputation score of the peer
+	Latency   time.Duration `json:"latency"`   // Averaged response latency of the peer
+	Timeouts  uint64        `json:"timeouts"`  // Number of requests timed out during this session
+	Invalid   uint64        `json:"invalid"`   // Number of invalid blocks delivered during this session
+	Useless   uint64        `json:"useless"`   // Number of empty responses delivered during this session
+	Responses uint64        `json:"responses"` // Number of useful responses delivered during this session
+}
+
+// entry is the reputation state of a single connected peer.
+type entry struct {
+	node enode.ID // Node identifier to persist the score under
+	info Info
+}
+
+// Tracker keeps the reputation scores of the connected peers, loading them
+// from and persisting them into the node database so that bad behaviour is
+// remembered across connections.
+type Tracker struct {
+	db    *enode.DB         // Node database to persist scores into (nil = in-memory only)
+	peers map[string]*entry // Reputation of the currently connected peers
+	lock  sync.RWMutex
+}
+
+// New creates a peer reputation tracker. The node database may be nil, in
+// which case scores are only kept for the lifetime of the connections.
+func New(db *enode.DB) *Tracker {
+	return &Tracker{
+		db:    db,
+		peers: make(map[string]*entry),
+	}
+}
+
+// SetDatabase sets the node database to persist scores into. It is meant to
+// be called once the p2p server has been started and its database opened.
+func (t *Tracker) SetDatabase(db *enode.DB) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.db = db
+}
+
+// Register starts tracking a peer, restoring its previously persisted score.
+func (t *Tracker) Register(id string, node enode.ID) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	e := &entry{node: node}
+	if t.db != nil {
+		e.info.Score = t.db.NodeScore(node)
+	}
+	t.peers[id] = e
+}
+
+// Unregister stops tracking a peer, persisting its final score.
+func (t *Tracker) Unregister(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if e, ok := t.peers[id]; ok {
+		t.persist(e)
+		delete(t.peers, id)
+	}
+}
+
+// Record adjusts the score of a peer based on an observed behaviour.
+func (t *Tracker) Record(id string, ev Event) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	e, ok := t.peers[id]
+	if !ok {
+		return
+	}
+	switch ev {
+	case Timeout:
+		e.info.Timeouts++
+	case InvalidBlock:
+		e.info.Invalid++
+	case UselessResponse:
+		e.info.Useless++
+	case UsefulResponse:
+		e.info.Responses++
+	}
+	t.adjust(e, weights[ev])
+
+	// Penalties are persisted right away, so a peer can't escape them by
+	// reconnecting before a graceful unregistration
+	if weights[ev] < 0 {
+		log.Trace("Peer penalised", "peer", id, "event", ev, "score", e.info.Score)
+		t.persist(e)
+	}
+}
+
+// RecordLatency folds a response latency measurement into the average of a
+// peer, penalising it if the responses are consistently slow.
+func (t *Tracker) RecordLatency(id string, rtt time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	e, ok := t.peers[id]
+	if !ok {
+		return
+	}
+	if e.info.Latency == 0 {
+		e.info.Latency = rtt
+	} else {
+		e.info.Latency = time.Duration((1-measurementImpact)*float64(e.info.Latency) + measurementImpact*float64(rtt))
+	}
+	if e.info.Latency > slowResponse {
+		t.adjust(e, -1)
+	}
+}
+
+// Score retrieves the current reputation score of a peer.
+func (t *Tracker) Score(id string) int64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if e, ok := t.peers[id]; ok {
+		return e.info.Score
+	}
+	return 0
+}
+
+// Low returns whbtper the peer's reputation is below the deprioritisation
+// threshold.
+func (t *Tracker) Low(id string) bool {
+	return t.Score(id) < LowScore
+}
+
+// Info retrieves the reputation summary of a peer, or nil if it's unknown.
+func (t *Tracker) Info(id string) *Info {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if e, ok := t.peers[id]; ok {
+		info := e.info
+		return &info
+	}
+	return nil
+}
+
+// adjust changes the score of a peer by delta, keeping it within bounds.
+func (t *Tracker) adjust(e *entry, delta int64) {
+	e.info.Score += delta
+	if e.info.Score > MaxScore {
+		e.info.Score = MaxScore
+	}
+	if e.info.Score < MinScore {
+		e.info.Score = MinScore
+	}
+}
+
+// persist writes the score of a peer into the node database, if available.
+func (t *Tracker) persist(e *entry) {
+	if t.db == nil {
+		return
+	}
+	if err := t.db.UpdateNodeScore(e.node, e.info.Score); err != nil {
+		log.Warn("Failed to persist peer score", "id", e.node, "err", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package peerscore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btpereum/go-btpereum/p2p/enode"
+)
+
+// Tests that events adjust the score of a peer within the allowed bounds.
+func TestScoreBounds(t *testing.T) {
+	tracker := New(nil)
+	tracker.Register("peer", enode.ID{1})
+
+	tracker.Record("peer", Timeout)
+	if score := tracker.Score("peer"); score != weights[Timeout] {
+		t.Fatalf("score mismatch: have %d, want %d", score, weights[Timeout])
+	}
+	for i := 0; i < 10; i++ {
+		tracker.Record("peer", InvalidBlock)
+	}
+	if score := tracker.Score("peer"); score != MinScore {
+		t.Fatalf("score below bounds: have %d, want %d", score, MinScore)
+	}
+	if !tracker.Low("peer") {
+		t.Fatalf("penalised peer not reported low")
+	}
+	for i := 0; i < 1000; i++ {
+		tracker.Record("peer", UsefulResponse)
+	}
+	if score := tracker.Score("peer"); score != MaxScore {
+		t.Fatalf("score above bounds: have %d, want %d", score, MaxScore)
+	}
+	info := tracker.Info("peer")
+	if info.Timeouts != 1 || info.Invalid != 10 || info.Responses != 1000 {
+		t.Fatalf("event counters mismatch: %+v", info)
+	}
+}
+
+// Tests that slow responses are penalised.
+func TestScoreLatency(t *testing.T) {
+	tracker := New(nil)
+	tracker.Register("peer", enode.ID{1})
+
+	tracker.RecordLatency("peer", time.Second)
+	if score := tracker.Score("peer"); score != 0 {
+		t.Fatalf("fast response penalised: score %d", score)
+	}
+	tracker.RecordLatency("peer", 100*slowResponse)
+	if score := tracker.Score("peer"); score != -1 {
+		t.Fatalf("slow response not penalised: score %d", score)
+	}
+}
+
+// Tests that scores are persisted into the node database and restored on
+// reconnection.
+func TestScorePersistence(t *testing.T) {
+	db, err := enode.OpenDB("")
+	if err != nil {
+		t.Fatalf("failed to open node database: %v", err)
+	}
+	defer db.Close()
+
+	tracker := New(db)
+	tracker.Register("peer", enode.ID{1})
+	tracker.Record("peer", InvalidBlock)
+	tracker.Record("peer", UsefulResponse)
+	tracker.Unregister("peer")
+
+	if score := tracker.Score("peer"); score != 0 {
+		t.Fatalf("unregistered peer still scored: %d", score)
+	}
+	want := weights[InvalidBlock] + weights[UsefulResponse]
+	if score := db.NodeScore(enode.ID{1}); score != want {
+		t.Fatalf("persisted score mismatch: have %d, want %d", score, want)
+	}
+	tracker.Register("peer", enode.ID{1})
+	if score := tracker.Score("peer"); score != want {
+		t.Fatalf("restored score mismatch: have %d, want %d", score, want)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"sync"
+	"time"
+)
+
+// maxServeDelay is the maximum time a single data request is held back for by
+// the serving budget. Waiting any longer would just make the remote side time
+// out the request, wasting the data we eventually send.
+const maxServeDelay = 3 * time.Second
+
+// tokenBucket is a simple rate limiter, refilling at a constant rate up to one
+// second worth of tokens. Takes beyond the available tokens are allowed, but
+// put the bucket into debt that needs to be waited out.
+//
+// A nil bucket is unlimited.
+type tokenBucket struct {
+	rate  float64   // Number of tokens refilled per second
+	avail float64   // Number of tokens currently available (negative = debt)
+	last  time.Time // Time instance of the last refill
+	lock  sync.Mutex
+}
+
+// newTokenBucket creates a token bucket refilling at the given rate per second,
+// or nil (unlimited) if the rate is not positive.
+func newTokenBucket(rate int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		rate:  float64(rate),
+		avail: float64(rate),
+		last:  time.Now(),
+	}
+}
+
+// take withdraws the given amount of tokens from the bucket and returns how long
+// the caller needs to wait until the bucket is out of debt.
+func (b *tokenBucket) take(amount int) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	b.avail += now.Sub(b.last).Seconds() * b.rate
+	if b.avail > b.rate {
+		b.avail = b.rate
+	}
+	b.last = now
+	b.avail -= float64(amount)
+
+	if b.avail >= 0 {
+		return 0
+	}
+	return time.Duration(-b.avail / b.rate * float64(time.Second))
+}
+
+// servingBudget limits the rate at which data requests of remote peers are
+// served, both individually and in aggregate. Instead of dropping peers that
+// request too much, their requests are answered more slowly: the handler of an
+// over-budget peer waits before serving its next request, which in turn makes
+// the remote downloader lower its throughput estimate for us.
+type servingBudget struct {
+	peerRequests int          // Maximum number of requests served per second per peer
+	peerBytes    int          // Maximum number of response bytes served per second per peer
+	totalBytes   *tokenBucket // Global bucket for the response bytes served to all peers
+}
+
+// newServingBudget creates a request serving budget. Non-positive limits are
+// considered unlimited.
+func newServingBudget(peerRequests, peerBytes, totalBytes int) *servingBudget {
+	return &servingBudget{
+		peerRequests: peerRequests,
+		peerBytes:    peerBytes,
+		totalBytes:   newTokenBucket(totalBytes),
+	}
+}
+
+// peerBudget is the serving allowance of a single connected peer.
+type peerBudget struct {
+	requests *tokenBucket // Bucket for the number of requests served
+	bytes    *tokenBucket // Bucket for the response bytes served
+}
+
+// newPeerBudget creates the serving allowance for a newly connected peer.
+func (b *servingBudget) newPeerBudget() *peerBudget {
+	return &peerBudget{
+		requests: newTokenBucket(b.peerRequests),
+		bytes:    newTokenBucket(b.peerBytes),
+	}
+}
+
+// wait accounts for a new data request of a peer and blocks until the peer's
+// and the global budget allow it to be served, or the quit channel is closed.
+func (b *servingBudget) wait(p *peerBudget, quit chan struct{}) {
+	delay := p.requests.take(1)
+	if d := p.bytes.take(0); d > delay {
+		delay = d
+	}
+	if d := b.totalBytes.take(0); d > delay {
+		delay = d
+	}
+	if delay == 0 {
+		return
+	}
+	if delay > maxServeDelay {
+		delay = maxServeDelay
+	}
+	serveThrottledMeter.Mark(1)
+	serveDelayTimer.Update(delay)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-quit:
+	}
+}
+
+// charge withdraws the size of a response from the peer's and the global budget,
+// delaying the peer's subsequent requests if over the limits.
+func (b *servingBudget) charge(p *peerBudget, bytes int) {
+	serveBytesMeter.Mark(int64(bytes))
+
+	p.bytes.take(bytes)
+	b.totalBytes.take(bytes)
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the token bucket allows bursts up to its rate and requests waiting
+// out any debt accumulated beyond.
+func TestTokenBucket(t *testing.T) {
+	// Unlimited buckets should never throttle
+	var unlimited *tokenBucket
+	if delay := unlimited.take(1000000); delay != 0 {
+		t.Fatalf("unlimited bucket throttled: %v", delay)
+	}
+	if b := newTokenBucket(0); b != nil {
+		t.Fatalf("zero rate bucket not unlimited")
+	}
+	// Limited buckets should allow a one second burst, then enforce the rate
+	b := newTokenBucket(10)
+	for i := 0; i < 10; i++ {
+		if delay := b.take(1); delay != 0 {
+			t.Fatalf("take %d: throttled within burst: %v", i, delay)
+		}
+	}
+	if delay := b.take(5); delay < 400*time.Millisecond || delay > 500*time.Millisecond {
+		t.Fatalf("debt delay mismatch: have %v, want ~500ms", delay)
+	}
+}
+
+// Tests that peers over their serving budget are held back, but never for more
+// than the maximum allowance.
+func TestServingBudgetWait(t *testing.T) {
+	budget := newServingBudget(0, 1000, 0)
+	peer := budget.newPeerBudget()
+	quit := make(chan struct{})
+
+	start := time.Now()
+	budget.wait(peer, quit)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("in-budget request delayed: %v", elapsed)
+	}
+	budget.charge(peer, 1200)
+
+	start = time.Now()
+	budget.wait(peer, quit)
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("over-budget request not delayed: %v", elapsed)
+	}
+	// Huge debts should be capped to the maximum delay, and quitting should abort
+	budget.charge(peer, 1000000)
+	close(quit)
+
+	start = time.Now()
+	budget.wait(peer, quit)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("wait not aborted on quit: %v", elapsed)
+	}
+}
@@ -45,7 +45,7 @@ type txsync struct {
 // syncTransactions starts sending all currently pending transactions to the given peer.
 func (pm *ProtocolManager) syncTransactions(p *peer) {
 	var txs types.Transactions
-	pending, _ := pm.txpool.Pending()
+	pending, _ := pm.txpool.Propagatable()
 	for _, batch := range pending {
 		txs = append(txs, batch...)
 	}
@@ -166,6 +166,11 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	if peer == nil {
 		return
 	}
+	// Header syncing nodes track only the header chain, sync it and bail out
+	if pm.headerSync {
+		pm.synchroniseHeaders(peer)
+		return
+	}
 	// Make sure the peer's TD is higher than our own
 	currentBlock := pm.blockchain.CurrentBlock()
 	td := pm.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64())
@@ -214,3 +219,17 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		go pm.BroadcastBlock(head, false)
 	}
 }
+
+// synchroniseHeaders tries to sync up our local header chain with a remote peer.
+// Since no blocks are imported, the node never starts accepting transactions nor
+// announces blocks to its peers.
+func (pm *ProtocolManager) synchroniseHeaders(peer *peer) {
+	currentHeader := pm.blockchain.CurrentHeader()
+	td := pm.blockchain.GetTd(currentHeader.Hash(), currentHeader.Number.Uint64())
+
+	pHead, pTd := peer.Head()
+	if pTd.Cmp(td) <= 0 {
+		return
+	}
+	pm.downloader.Synchronise(peer.id, pHead, pTd, downloader.HeaderSync)
+}
@@ -0,0 +1,375 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/rawdb"
+	"github.com/btpereum/go-btpereum/core/state"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/core/vm"
+	"github.com/btpereum/go-btpereum/btp/tracers"
+	"github.com/btpereum/go-btpereum/btpdb"
+	"github.com/btpereum/go-btpereum/log"
+	"github.com/btpereum/go-btpereum/rlp"
+	"github.com/btpereum/go-btpereum/rpc"
+)
+
+const (
+	// traceIndexSectionSize is the number of blocks indexed within a single trace
+	// index section.
+	traceIndexSectionSize = 256
+
+	// traceIndexConfirms is the number of confirmation blocks before a section is
+	// considered final and indexed.
+	traceIndexConfirms = 64
+
+	// traceIndexThrottling is the time to wait between processing two consecutive
+	// index sections, as tracing is heavy on both CPU and disk.
+	traceIndexThrottling = 100 * time.Millisecond
+)
+
+var errTraceIndexDisabled = errors.New("trace index disabled")
+
+// internalTx is an internal call or value transfer made by a transaction, as
+// stored in the trace index.
+type internalTx struct {
+	BlockNumber  uint64
+	TxHash       common.Hash
+	TxIndex      uint64
+	TraceAddress []uint64 // Position of the call within the call tree of the transaction
+	Type         vm.OpCode
+	From         common.Address
+	To           common.Address
+	Value        *big.Int
+	Error        string
+}
+
+// internalTxResult is the RPC representation of an internal transaction.
+type internalTxResult struct {
+	BlockNumber         hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash     common.Hash    `json:"transactionHash"`
+	TransactionPosition hexutil.Uint64 `json:"transactionPosition"`
+	TraceAddress        []uint64       `json:"traceAddress"`
+	Type                string         `json:"type"`
+	From                common.Address `json:"from"`
+	To                  common.Address `json:"to"`
+	Value               *hexutil.Big   `json:"value"`
+	Error               string         `json:"error,omitempty"`
+}
+
+// collectInternalTxs gathers all the internal calls from the call tree of a
+// transaction, excluding the top level call made by the transaction itself.
+func collectInternalTxs(frame *tracers.CallFrame, address []uint64, number uint64, hash common.Hash, index uint64, txs []*internalTx) []*internalTx {
+	if len(address) > 0 {
+		txs = append(txs, &internalTx{
+			BlockNumber:  number,
+			TxHash:       hash,
+			TxIndex:      index,
+			TraceAddress: append([]uint64{}, address...),
+			Type:         frame.Type,
+			From:         frame.From,
+			To:           frame.To,
+			Value:        traceValue(frame.Value),
+			Error:        frame.Error,
+		})
+	}
+	for i, call := range frame.Calls {
+		txs = collectInternalTxs(call, append(address, uint64(i)), number, hash, index, txs)
+	}
+	return txs
+}
+
+// txCallTracer is a vm.Tracer collecting the call trees of all the transactions
+// executed with it, running a new native call tracer for each of them.
+type txCallTracer struct {
+	tracer *tracers.CallTracer
+	frames []*tracers.CallFrame
+}
+
+// CaptureStart implements vm.Tracer, starting the trace of a new transaction.
+func (t *txCallTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.tracer = tracers.NewCallTracer()
+	return t.tracer.CaptureStart(from, to, create, input, gas, value)
+}
+
+// CaptureState implements vm.Tracer.
+func (t *txCallTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return t.tracer.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *txCallTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return t.tracer.CaptureFault(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+}
+
+// CaptureEnd implements vm.Tracer, storing the call tree of the finished transaction.
+func (t *txCallTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	if err := t.tracer.CaptureEnd(output, gasUsed, d, err); err != nil {
+		return err
+	}
+	frame, err := t.tracer.Result()
+	if err != nil {
+		return err
+	}
+	t.frames = append(t.frames, frame)
+	return nil
+}
+
+// TraceIndexer implements a core.ChainIndexer, tracing the transactions of the
+// canonical chain and indexing the internal transactions by the accounts taking
+// part in them.
+type TraceIndexer struct {
+	btp     *btpereum
+	debug   *PrivateDebugAPI
+	db      btpdb.Database // Database instance to write index data into
+	section uint64         // Section is the section number being processed currently
+	head    common.Hash    // Head is the hash of the last header processed
+
+	statedb *state.StateDB // State after the last processed block, nil if unknown
+	proot   common.Hash    // Root of the state referenced in the trie cache
+
+	txs map[common.Address][]*internalTx // Internal transactions of the section, by account
+}
+
+// NewTraceIndexer returns a chain indexer that traces the canonical blocks and
+// indexes the internal transactions by account.
+func NewTraceIndexer(btp *btpereum) *core.ChainIndexer {
+	backend := &TraceIndexer{
+		btp:   btp,
+		debug: NewPrivateDebugAPI(btp),
+		db:    btp.chainDb,
+	}
+	table := rawdb.NewTable(btp.chainDb, string(rawdb.TraceIndexPrefix))
+
+	return core.NewChainIndexer(btp.chainDb, table, backend, traceIndexSectionSize, traceIndexConfirms, traceIndexThrottling, "traces")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new trace index section.
+// If the section doesn't continue the previously processed one (e.g. after a
+// reorg rolled the indexer back), the running state is discarded.
+func (t *TraceIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	if lastSectionHead != t.head {
+		t.dropState()
+	}
+	t.section, t.head = section, common.Hash{}
+	t.txs = make(map[common.Address][]*internalTx)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, tracing the transactions of a new
+// block and gathering its internal transactions into the index.
+func (t *TraceIndexer) Process(ctx context.Context, header *types.Header) error {
+	block := t.btp.blockchain.GetBlock(header.Hash(), header.Number.Uint64())
+	if block == nil {
+		return fmt.Errorf("block #%d [%x…] not found", header.Number, header.Hash().Bytes()[:4])
+	}
+	if err := t.loadState(block); err != nil {
+		return err
+	}
+	if block.NumberU64() > 0 {
+		// Execute the block on top of the running state, tracing all transactions
+		tracer := new(txCallTracer)
+		if _, _, _, err := t.btp.blockchain.Processor().Process(block, t.statedb, vm.Config{Debug: true, Tracer: tracer}); err != nil {
+			return fmt.Errorf("processing block %d failed: %v", block.NumberU64(), err)
+		}
+		if len(tracer.frames) != len(block.Transactions()) {
+			return fmt.Errorf("block %d: trace count mismatch: have %d, want %d", block.NumberU64(), len(tracer.frames), len(block.Transactions()))
+		}
+		for i, tx := range block.Transactions() {
+			for _, itx := range collectInternalTxs(tracer.frames[i], nil, block.NumberU64(), tx.Hash(), uint64(i), nil) {
+				t.txs[itx.From] = append(t.txs[itx.From], itx)
+				if itx.To != itx.From {
+					t.txs[itx.To] = append(t.txs[itx.To], itx)
+				}
+			}
+		}
+		if err := t.commitState(block); err != nil {
+			t.dropState()
+			return err
+		}
+	}
+	t.head = header.Hash()
+	return nil
+}
+
+// loadState ensures the running state is the state of the parent of the given
+// block, regenerating it if needed. As the state of every indexed section head
+// is persisted, at most a section needs to be re-executed, even when resuming
+// on a pruned node.
+func (t *TraceIndexer) loadState(block *types.Block) error {
+	if t.statedb != nil {
+		return nil
+	}
+	if block.NumberU64() == 0 {
+		statedb, err := state.New(block.Root(), state.NewDatabaseWithCache(t.db, 16))
+		if err != nil {
+			return err
+		}
+		t.statedb, t.proot = statedb, common.Hash{}
+		return nil
+	}
+	parent := t.btp.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, err := t.debug.computeStateDB(parent, traceIndexSectionSize)
+	if err != nil {
+		return err
+	}
+	// Pin the parent state in the trie cache until the next block is committed
+	statedb.Database().TrieDB().Reference(parent.Root(), common.Hash{})
+	t.statedb, t.proot = statedb, parent.Root()
+	return nil
+}
+
+// dropState discards the running state, releasing it from the trie cache.
+func (t *TraceIndexer) dropState() {
+	if t.statedb != nil && t.proot != (common.Hash{}) {
+		t.statedb.Database().TrieDB().Dereference(t.proot)
+	}
+	t.statedb, t.proot = nil, common.Hash{}
+}
+
+// commitState writes the modifications of a processed block into the running
+// state, ensuring they match the state root of the block.
+func (t *TraceIndexer) commitState(block *types.Block) error {
+	root, err := t.statedb.Commit(t.btp.blockchain.Config().IsEIP158(block.Number()))
+	if err != nil {
+		return err
+	}
+	if root != block.Root() {
+		return fmt.Errorf("block %d: state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+	}
+	if err := t.statedb.Reset(root); err != nil {
+		return fmt.Errorf("state reset after block %d failed: %v", block.NumberU64(), err)
+	}
+	database := t.statedb.Database().TrieDB()
+	database.Reference(root, common.Hash{})
+	if t.proot != (common.Hash{}) {
+		database.Dereference(t.proot)
+	}
+	t.proot = root
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the internal transactions
+// of the finished section into the database. The state of the section head is
+// flushed first, so the next section can always be resumed from it.
+func (t *TraceIndexer) Commit() error {
+	if t.proot != (common.Hash{}) {
+		if err := t.statedb.Database().TrieDB().Commit(t.proot, false); err != nil {
+			return fmt.Errorf("section %d state flush failed: %v", t.section, err)
+		}
+	}
+	batch := t.db.NewBatch()
+	for address, txs := range t.txs {
+		blob, err := rlp.EncodeToBytes(txs)
+		if err != nil {
+			return err
+		}
+		rawdb.WriteInternalTxs(batch, address, t.section, t.head, blob)
+	}
+	log.Debug("Indexed internal transactions", "section", t.section, "accounts", len(t.txs))
+	return batch.Write()
+}
+
+// InternalTransactions returns the internal calls and value transfers made from
+// or to the given account within a range of blocks. The indexed part of the range
+// is served from the trace index, the rest is traced on the fly.
+func (api *PrivateTraceAPI) InternalTransactions(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, reexec *uint64) ([]*internalTxResult, error) {
+	if api.btp.traceIndexer == nil {
+		return nil, errTraceIndexDisabled
+	}
+	from, err := api.blockByNumber(fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockByNumber(toBlock)
+	if err != nil {
+		return nil, err
+	}
+	begin, end := from.NumberU64(), to.NumberU64()
+	if begin > end {
+		return nil, fmt.Errorf("invalid block range: fromBlock #%d after toBlock #%d", begin, end)
+	}
+	var txs []*internalTx
+
+	// Gather all the indexed internal transactions first
+	sections, _, _ := api.btp.traceIndexer.Sections()
+	for section := begin / traceIndexSectionSize; section < sections && section*traceIndexSectionSize <= end; section++ {
+		head := rawdb.ReadCanonicalHash(api.btp.chainDb, (section+1)*traceIndexSectionSize-1)
+		blob := rawdb.ReadInternalTxs(api.btp.chainDb, address, section, head)
+		if len(blob) == 0 {
+			continue
+		}
+		var stored []*internalTx
+		if err := rlp.DecodeBytes(blob, &stored); err != nil {
+			return nil, err
+		}
+		for _, tx := range stored {
+			if tx.BlockNumber >= begin && tx.BlockNumber <= end {
+				txs = append(txs, tx)
+			}
+		}
+	}
+	// Trace the blocks not yet indexed
+	if indexed := sections * traceIndexSectionSize; begin < indexed {
+		begin = indexed
+	}
+	for number := begin; number <= end; number++ {
+		block := api.btp.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		frames, err := api.traceBlockFrames(ctx, block, traceReexec(reexec))
+		if err != nil {
+			return nil, err
+		}
+		for i, tx := range block.Transactions() {
+			for _, itx := range collectInternalTxs(frames[i], nil, number, tx.Hash(), uint64(i), nil) {
+				if itx.From == address || itx.To == address {
+					txs = append(txs, itx)
+				}
+			}
+		}
+	}
+	results := make([]*internalTxResult, len(txs))
+	for i, tx := range txs {
+		results[i] = &internalTxResult{
+			BlockNumber:         hexutil.Uint64(tx.BlockNumber),
+			TransactionHash:     tx.TxHash,
+			TransactionPosition: hexutil.Uint64(tx.TxIndex),
+			TraceAddress:        tx.TraceAddress,
+			Type:                strings.ToLower(tx.Type.String()),
+			From:                tx.From,
+			To:                  tx.To,
+			Value:               (*hexutil.Big)(tx.Value),
+			Error:               tx.Error,
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/btpereum/go-btpereum/core/vm"
+)
+
+var (
+	// ErrBudgetTimeout is returned if the total wall time allowance of a trace
+	// budget was exceeded.
+	ErrBudgetTimeout = errors.New("trace time budget exceeded")
+
+	// ErrBudgetResultSize is returned if the total result size allowance of a
+	// trace budget was exceeded.
+	ErrBudgetResultSize = errors.New("trace result size budget exceeded")
+
+	// ErrBudgetHeapSize is returned if the total JavaScript heap allowance of a
+	// trace budget was exceeded.
+	ErrBudgetHeapSize = errors.New("tracer heap size budget exceeded")
+)
+
+// Budget is an aggregate resource allowance shared by all the tracers of a
+// single block or chain trace. Once any of its limits is exceeded, the budget
+// is exhausted and every tracer attached to it is interrupted.
+type Budget struct {
+	ctx    context.Context    // Context cancelled when the budget is exhausted
+	cancel context.CancelFunc // Cancellation function to release the budget
+
+	maxResult uint64 // Maximum total size of the trace results (0 = unlimited)
+	maxHeap   uint64 // Maximum total size of the live tracer heaps (0 = unlimited)
+
+	result uint64 // Total size of the trace results so far (atomic)
+	heap   uint64 // Sampled total size of the live tracer heaps (atomic)
+
+	reason error      // Reason why the budget was exhausted
+	lock   sync.Mutex // Lock protecting the exhaustion reason
+}
+
+// NewBudget creates a trace budget derived from the given context. A zero timeout
+// or size limit disables the corresponding check.
+func NewBudget(ctx context.Context, timeout time.Duration, maxResult, maxHeap uint64) *Budget {
+	budget := &Budget{
+		maxResult: maxResult,
+		maxHeap:   maxHeap,
+	}
+	if timeout > 0 {
+		budget.ctx, budget.cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		budget.ctx, budget.cancel = context.WithCancel(ctx)
+	}
+	return budget
+}
+
+// Context returns a context which is cancelled when the budget is exhausted or
+// released.
+func (b *Budget) Context() context.Context {
+	return b.ctx
+}
+
+// Release frees up the resources associated with the budget. It must be called
+// once all the tracers attached to the budget finished.
+func (b *Budget) Release() {
+	b.cancel()
+}
+
+// Err returns the reason why the budget was exhausted, or nil if it still has
+// allowance left.
+func (b *Budget) Err() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.reason == nil {
+		switch b.ctx.Err() {
+		case nil:
+		case context.DeadlineExceeded:
+			b.reason = ErrBudgetTimeout
+		default:
+			b.reason = b.ctx.Err()
+		}
+	}
+	return b.reason
+}
+
+// exhaust marks the budget exhausted for the given reason and interrupts all the
+// tracers attached to it.
+func (b *Budget) exhaust(reason error) {
+	b.lock.Lock()
+	if b.reason == nil {
+		b.reason = reason
+	}
+	b.lock.Unlock()
+
+	b.cancel()
+}
+
+// Charge accounts the size of a trace result against the budget, returning an
+// error if the result no longer fits.
+func (b *Budget) Charge(size int) error {
+	if total := atomic.AddUint64(&b.result, uint64(size)); b.maxResult > 0 && total > b.maxResult {
+		b.exhaust(ErrBudgetResultSize)
+	}
+	return b.Err()
+}
+
+// chargeHeap adjusts the sampled heap size of the live tracers by the given
+// delta, exhausting the budget if the total exceeds the allowance.
+func (b *Budget) chargeHeap(delta int64) {
+	if total := atomic.AddUint64(&b.heap, uint64(delta)); delta > 0 && b.maxHeap > 0 && total > b.maxHeap {
+		b.exhaust(ErrBudgetHeapSize)
+	}
+}
+
+// fits reports whbtper a result of the given size would still fit into the result
+// allowance of the budget, without charging it.
+func (b *Budget) fits(size uint64) bool {
+	return b.maxResult == 0 || atomic.LoadUint64(&b.result)+size <= b.maxResult
+}
+
+// structLogSize approximates the JSON encoded size of a structured log entry: a
+// fixed overhead plus the hex encoding of every stack item, memory word and
+// storage slot captured in it.
+func structLogSize(log *vm.StructLog) uint64 {
+	return 128 + 68*uint64(len(log.Stack)) + 68*uint64(len(log.Memory)/32) + 136*uint64(len(log.Storage))
+}
+
+// budgetLogger is a structured logger bound to an aggregate trace budget. The
+// logs it collects are only charged once the transaction is traced, so it tracks
+// their approximate size on the fly and aborts the EVM as soon as they no longer
+// fit, or the budget is exhausted by other means.
+type budgetLogger struct {
+	*vm.StructLogger
+
+	budget *Budget
+	size   uint64 // Approximate encoded size of the logs collected so far
+}
+
+// WithBudget binds a structured logger to an aggregate trace budget.
+func WithBudget(logger *vm.StructLogger, budget *Budget) vm.Tracer {
+	return &budgetLogger{StructLogger: logger, budget: budget}
+}
+
+// CaptureState implements the Tracer interface, collecting the structured log of
+// the current step if the budget still allows it.
+func (l *budgetLogger) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if l.budget.ctx.Err() != nil {
+		env.Cancel()
+		return l.budget.Err()
+	}
+	if err := l.StructLogger.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err); err != nil {
+		return err
+	}
+	logs := l.StructLogs()
+	if l.size += structLogSize(&logs[len(logs)-1]); !l.budget.fits(l.size) {
+		l.budget.exhaust(ErrBudgetResultSize)
+		env.Cancel()
+		return ErrBudgetResultSize
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core/vm"
+)
+
+// CallFrame is a single call made during the execution of a transaction, along
+// with all the calls it made in turn.
+type CallFrame struct {
+	Type    vm.OpCode      // Opcode that initiated the call (CALL, CREATE, SELFDESTRUCT, ...)
+	From    common.Address // Account making the call
+	To      common.Address // Account being called, created or receiving the refund
+	Value   *big.Int       // Value transferred by the call (nil for delegate and static calls)
+	Gas     uint64         // Gas available to the call
+	GasUsed uint64         // Gas consumed by the call
+	Input   []byte         // Call data or contract init code
+	Output  []byte         // Returned data or the code of the created contract
+	Error   string         // Failure of the call, empty if successful
+	Calls   []*CallFrame   // Calls made by this call, in execution order
+
+	gasIn   uint64 // Gas available to the caller before the call
+	gasCost uint64 // Gas cost of the calling opcode
+	outOff  uint64 // Memory offset the caller expects the output at
+	outLen  uint64 // Memory size the caller expects the output in
+	gasSet  bool   // Whether the true allowance of the call is known
+}
+
+// CallTracer is a native transaction tracer that extracts all the internal calls
+// made by a transaction into a call tree. It mirrors the JavaScript callTracer,
+// but is fast enough to trace whole block ranges.
+type CallTracer struct {
+	callstack []*CallFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether execution just descended into an inner call
+}
+
+// NewCallTracer creates a new native call tracer.
+func NewCallTracer() *CallTracer {
+	return &CallTracer{callstack: []*CallFrame{{}}}
+}
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing
+// operation.
+func (t *CallTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	root := t.callstack[0]
+	root.Type = vm.CALL
+	if create {
+		root.Type = vm.CREATE
+	}
+	root.From, root.To = from, to
+	root.Input = common.CopyBytes(input)
+	root.Gas, root.gasSet = gas, true
+	root.Value = new(big.Int).Set(value)
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM
+// execution.
+func (t *CallTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil {
+		return t.CaptureFault(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		// A new contract is being created, add to the call stack
+		t.callstack = append(t.callstack, &CallFrame{
+			Type:    op,
+			From:    contract.Address(),
+			Input:   memorySlice(memory, stack.Back(1), stack.Back(2)),
+			Value:   new(big.Int).Set(stack.Back(0)),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// A contract is being self destructed, gather that as a subcall too
+		top := t.callstack[len(t.callstack)-1]
+		top.Calls = append(top.Calls, &CallFrame{
+			Type:  op,
+			From:  contract.Address(),
+			To:    common.BigToAddress(stack.Back(0)),
+			Value: new(big.Int).Set(env.StateDB.GetBalance(contract.Address())),
+		})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.BigToAddress(stack.Back(1))
+		if _, ok := vm.PrecompiledContractsByzantium[to]; ok {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		call := &CallFrame{
+			Type:    op,
+			From:    contract.Address(),
+			To:      to,
+			Input:   memorySlice(memory, stack.Back(2+off), stack.Back(3+off)),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  stack.Back(4 + off).Uint64(),
+			outLen:  stack.Back(5 + off).Uint64(),
+		}
+		if off == 1 {
+			call.Value = new(big.Int).Set(stack.Back(2))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve its true allowance,
+	// which may differ from the requested one (2300 stipend, 63/64 rule).
+	if t.descended {
+		if depth >= len(t.callstack) {
+			t.callstack[len(t.callstack)-1].Gas = gas
+			t.callstack[len(t.callstack)-1].gasSet = true
+		}
+		t.descended = false
+	}
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// An inner call returned, pop it off and gather the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stack.Back(0)
+		if call.Type == vm.CREATE || call.Type == vm.CREATE2 {
+			call.GasUsed = call.gasIn - call.gasCost - gas
+			if ret.Sign() != 0 {
+				call.To = common.BigToAddress(ret)
+				call.Output = env.StateDB.GetCode(call.To)
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else {
+			// Calls failing before execution (e.g. insufficient balance) never
+			// set their allowance, but still report the failure on the stack
+			if call.gasSet {
+				call.GasUsed = call.gasIn - call.gasCost + call.Gas - gas
+				if ret.Sign() != 0 {
+					call.Output = memorySlice(memory, new(big.Int).SetUint64(call.outOff), new(big.Int).SetUint64(call.outLen))
+				}
+			}
+			if ret.Sign() == 0 && call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		top := t.callstack[len(t.callstack)-1]
+		top.Calls = append(top.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *CallTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return nil
+	}
+	// Pop off the just failed call, consuming all its gas
+	call := t.callstack[len(t.callstack)-1]
+	call.Error = err.Error()
+	if call.gasSet {
+		call.GasUsed = call.Gas
+	}
+	if len(t.callstack) > 1 {
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		top := t.callstack[len(t.callstack)-1]
+		top.Calls = append(top.Calls, call)
+	}
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *CallTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	root := t.callstack[0]
+	root.GasUsed = gasUsed
+	if err != nil && root.Error == "" {
+		root.Error = err.Error()
+	}
+	if root.Error == "" {
+		root.Output = common.CopyBytes(output)
+	}
+	return nil
+}
+
+// Result returns the call tree of the traced transaction.
+func (t *CallTracer) Result() (*CallFrame, error) {
+	if len(t.callstack) != 1 {
+		return nil, errors.New("incomplete call trace")
+	}
+	return t.callstack[0], nil
+}
+
+// memorySlice returns a copy of the given memory region, or nil if the region is
+// out of bounds.
+func memorySlice(memory *vm.Memory, offset, size *big.Int) []byte {
+	length := uint64(memory.Len())
+	if !offset.IsUint64() || !size.IsUint64() || offset.Uint64() > length || size.Uint64() > length-offset.Uint64() {
+		return nil
+	}
+	return memory.Get(offset.Int64(), size.Int64())
+}
@@ -0,0 +1,272 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
+	"github.com/btpereum/go-btpereum/core/vm"
+)
+
+// GasProfile is the gas usage of every contract executed while profiling.
+type GasProfile map[common.Address]*ContractProfile
+
+// ContractProfile is the aggregated gas usage of a single contract's code.
+type ContractProfile struct {
+	Gas          uint64                         `json:"gas"`          // Gas burnt by the contract's own instructions
+	Steps        uint64                         `json:"steps"`        // Number of instructions executed
+	Calls        uint64                         `json:"calls"`        // Number of times the contract was entered
+	Selectors    map[string]*SelectorProfile    `json:"selectors"`    // Gas usage per 4-byte function selector
+	Instructions map[uint64]*InstructionProfile `json:"instructions"` // Gas usage per program counter
+}
+
+// SelectorProfile is the aggregated gas usage of a single contract function.
+type SelectorProfile struct {
+	Gas   uint64 `json:"gas"`   // Gas burnt by the instructions of the function
+	Steps uint64 `json:"steps"` // Number of instructions executed
+	Calls uint64 `json:"calls"` // Number of times the function was called
+}
+
+// InstructionProfile is the aggregated gas usage of a single instruction.
+type InstructionProfile struct {
+	Op    string `json:"op"`    // Opcode at the program counter
+	Gas   uint64 `json:"gas"`   // Gas burnt by the instruction
+	Count uint64 `json:"count"` // Number of times the instruction was executed
+}
+
+// profileKey identifies an instruction of a contract function.
+type profileKey struct {
+	contract common.Address
+	selector string
+	pc       uint64
+}
+
+// profileSample is the aggregated gas usage of an instruction.
+type profileSample struct {
+	op    vm.OpCode
+	gas   uint64
+	count uint64
+}
+
+// callKey identifies a function of a contract.
+type callKey struct {
+	contract common.Address
+	selector string
+}
+
+// profileFrame is the profiling state of a single call frame.
+type profileFrame struct {
+	contract common.Address // Address of the code being executed
+	selector string         // Function selector the frame was called with
+
+	pending bool      // Whether an instruction is waiting for its gas to be known
+	pc      uint64    // Program counter of the pending instruction
+	op      vm.OpCode // Opcode of the pending instruction
+	gas     uint64    // Gas available before the pending instruction
+	cost    uint64    // Gas cost reported for the pending instruction
+
+	children uint64 // Gas consumed by inner calls since the pending instruction
+	total    uint64 // Gas consumed by the frame, including inner calls
+}
+
+// GasProfiler is a native tracer aggregating the gas burnt by each instruction
+// of each contract across any number of transactions. Instructions spawning an
+// inner call are only charged for their own overhead, the gas consumed by the
+// inner call is attributed to the callee.
+//
+// The profiler is not safe for concurrent use, transactions need to be traced
+// sequentially.
+type GasProfiler struct {
+	samples map[profileKey]*profileSample // Aggregated usage per instruction
+	calls   map[callKey]uint64            // Number of calls per function
+	frames  []*profileFrame               // Current call stack of the EVM execution
+}
+
+// NewGasProfiler creates a new, empty gas profiler.
+func NewGasProfiler() *GasProfiler {
+	return &GasProfiler{
+		samples: make(map[profileKey]*profileSample),
+		calls:   make(map[callKey]uint64),
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the profiling of a
+// new transaction.
+func (p *GasProfiler) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	p.frames = p.frames[:0]
+	return nil
+}
+
+// CaptureState implements the Tracer interface to profile a single step of VM
+// execution.
+func (p *GasProfiler) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// Close any frames returned from and open a frame if a call was entered
+	for len(p.frames) > depth {
+		p.pop()
+	}
+	if len(p.frames) < depth {
+		frame := &profileFrame{
+			contract: contract.Address(),
+			selector: selector(contract.Input),
+		}
+		if contract.CodeAddr != nil {
+			frame.contract = *contract.CodeAddr
+		}
+		p.frames = append(p.frames, frame)
+		p.calls[callKey{frame.contract, frame.selector}]++
+	}
+	frame := p.frames[len(p.frames)-1]
+
+	// Charge the previous instruction of the frame with the gas it really used
+	if frame.pending {
+		used := frame.cost
+		if spent := frame.gas - gas; frame.gas >= gas && spent >= frame.children {
+			used = spent - frame.children
+		}
+		p.commit(frame, used)
+	}
+	frame.pending = true
+	frame.pc, frame.op, frame.gas, frame.cost = pc, op, gas, cost
+
+	return nil
+}
+
+// CaptureFault implements the Tracer interface. The faulting instruction was
+// already captured by CaptureState, so there's nothing left to do.
+func (p *GasProfiler) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the Tracer interface, closing all the frames of the
+// finished transaction.
+func (p *GasProfiler) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	for len(p.frames) > 0 {
+		p.pop()
+	}
+	return nil
+}
+
+// pop closes the innermost call frame, charging its consumption to the caller.
+func (p *GasProfiler) pop() {
+	frame := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+
+	if frame.pending {
+		p.commit(frame, frame.cost)
+	}
+	if len(p.frames) > 0 {
+		p.frames[len(p.frames)-1].children += frame.total
+	}
+}
+
+// commit charges the pending instruction of a frame with the given gas.
+func (p *GasProfiler) commit(frame *profileFrame, gas uint64) {
+	key := profileKey{frame.contract, frame.selector, frame.pc}
+
+	sample := p.samples[key]
+	if sample == nil {
+		sample = &profileSample{op: frame.op}
+		p.samples[key] = sample
+	}
+	sample.gas += gas
+	sample.count++
+
+	frame.total += gas + frame.children
+	frame.children, frame.pending = 0, false
+}
+
+// Profile returns the gas usage aggregated so far, broken down per contract,
+// function selector and instruction.
+func (p *GasProfiler) Profile() GasProfile {
+	result := make(GasProfile)
+
+	contract := func(addr common.Address) *ContractProfile {
+		if prof, ok := result[addr]; ok {
+			return prof
+		}
+		prof := &ContractProfile{
+			Selectors:    make(map[string]*SelectorProfile),
+			Instructions: make(map[uint64]*InstructionProfile),
+		}
+		result[addr] = prof
+		return prof
+	}
+	function := func(prof *ContractProfile, sel string) *SelectorProfile {
+		if fn, ok := prof.Selectors[sel]; ok {
+			return fn
+		}
+		fn := new(SelectorProfile)
+		prof.Selectors[sel] = fn
+		return fn
+	}
+	for key, calls := range p.calls {
+		prof := contract(key.contract)
+		prof.Calls += calls
+		function(prof, key.selector).Calls += calls
+	}
+	for key, sample := range p.samples {
+		prof := contract(key.contract)
+		prof.Gas += sample.gas
+		prof.Steps += sample.count
+
+		fn := function(prof, key.selector)
+		fn.Gas += sample.gas
+		fn.Steps += sample.count
+
+		ins, ok := prof.Instructions[key.pc]
+		if !ok {
+			ins = &InstructionProfile{Op: sample.op.String()}
+			prof.Instructions[key.pc] = ins
+		}
+		ins.Gas += sample.gas
+		ins.Count += sample.count
+	}
+	return result
+}
+
+// WritePprof writes the gas usage aggregated so far as a gzipped protobuf pprof
+// profile, usable by `go tool pprof`. Every instruction is a location within an
+// opcode function, nested into its contract function and the contract itself.
+func (p *GasProfiler) WritePprof(w io.Writer) error {
+	prof := newPprofProfile()
+	for key, sample := range p.samples {
+		var (
+			contract = key.contract.Hex()
+			function = fmt.Sprintf("%s.%s", contract, key.selector)
+		)
+		prof.sample([]uint64{
+			prof.location(fmt.Sprintf("%s:%d", function, key.pc), sample.op.String(), key.pc),
+			prof.location(function, function, 0),
+			prof.location(contract, contract, 0),
+		}, []int64{int64(sample.gas), int64(sample.count)})
+	}
+	return prof.write(w, [][2]string{{"gas", "count"}, {"steps", "count"}}, [2]string{"gas", "count"}, 1)
+}
+
+// selector returns the 4-byte function selector of the given call data, or as
+// much of it as is available for fallback calls.
+func selector(input []byte) string {
+	if len(input) > 4 {
+		input = input[:4]
+	}
+	return hexutil.Encode(input)
+}
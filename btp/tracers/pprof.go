@@ -0,0 +1,182 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// Field numbers of the pprof protobuf profile format, as defined by
+// https://github.com/google/pprof/blob/master/proto/profile.proto.
+const (
+	pprofProfileSampleType = 1
+	pprofProfileSample     = 2
+	pprofProfileLocation   = 4
+	pprofProfileFunction   = 5
+	pprofProfileStrings    = 6
+	pprofProfilePeriodType = 11
+	pprofProfilePeriod     = 12
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocation = 1
+	pprofSampleValue    = 2
+
+	pprofLocationID      = 1
+	pprofLocationAddress = 3
+	pprofLocationLine    = 4
+
+	pprofLineFunction = 1
+
+	pprofFunctionID         = 1
+	pprofFunctionName       = 2
+	pprofFunctionSystemName = 3
+)
+
+// pprofProfile is a minimal builder of pprof profiles, supporting only the parts
+// needed by the gas profiler: flat functions without source positions, and
+// locations holding a single line each.
+type pprofProfile struct {
+	strings   []string         // String table, the first entry is always empty
+	stringIDs map[string]int64 // Index of each string in the string table
+
+	functions map[string]uint64 // Identifier of each function by name
+	locations map[string]uint64 // Identifier of each location by caller given key
+
+	body []byte // Encoded functions, locations and samples
+}
+
+// newPprofProfile creates an empty pprof profile.
+func newPprofProfile() *pprofProfile {
+	return &pprofProfile{
+		strings:   []string{""},
+		stringIDs: map[string]int64{"": 0},
+		functions: make(map[string]uint64),
+		locations: make(map[string]uint64),
+	}
+}
+
+// str interns a string into the string table, returning its index.
+func (p *pprofProfile) str(s string) int64 {
+	if id, ok := p.stringIDs[s]; ok {
+		return id
+	}
+	id := int64(len(p.strings))
+	p.strings = append(p.strings, s)
+	p.stringIDs[s] = id
+	return id
+}
+
+// location returns the identifier of the location with the given key, creating
+// it within the named function at the given address if not yet known.
+func (p *pprofProfile) location(key string, function string, addr uint64) uint64 {
+	if id, ok := p.locations[key]; ok {
+		return id
+	}
+	fn, ok := p.functions[function]
+	if !ok {
+		fn = uint64(len(p.functions) + 1)
+		p.functions[function] = fn
+
+		name := p.str(function)
+		var msg []byte
+		msg = appendProtoVarint(msg, pprofFunctionID, fn)
+		msg = appendProtoVarint(msg, pprofFunctionName, uint64(name))
+		msg = appendProtoVarint(msg, pprofFunctionSystemName, uint64(name))
+		p.body = appendProtoBytes(p.body, pprofProfileFunction, msg)
+	}
+	id := uint64(len(p.locations) + 1)
+	p.locations[key] = id
+
+	var msg []byte
+	msg = appendProtoVarint(msg, pprofLocationID, id)
+	msg = appendProtoVarint(msg, pprofLocationAddress, addr)
+	msg = appendProtoBytes(msg, pprofLocationLine, appendProtoVarint(nil, pprofLineFunction, fn))
+	p.body = appendProtoBytes(p.body, pprofProfileLocation, msg)
+
+	return id
+}
+
+// sample adds a sample with the given call stack, innermost location first.
+func (p *pprofProfile) sample(locations []uint64, values []int64) {
+	var packed []byte
+	for _, id := range locations {
+		packed = appendUvarint(packed, id)
+	}
+	msg := appendProtoBytes(nil, pprofSampleLocation, packed)
+
+	packed = packed[:0]
+	for _, value := range values {
+		packed = appendUvarint(packed, uint64(value))
+	}
+	msg = appendProtoBytes(msg, pprofSampleValue, packed)
+
+	p.body = appendProtoBytes(p.body, pprofProfileSample, msg)
+}
+
+// write encodes the profile with the given sample types (type and unit pairs)
+// and writes it gzip compressed, the format expected by `go tool pprof`.
+func (p *pprofProfile) write(w io.Writer, sampleTypes [][2]string, periodType [2]string, period int64) error {
+	valueType := func(typ [2]string) []byte {
+		msg := appendProtoVarint(nil, pprofValueTypeType, uint64(p.str(typ[0])))
+		return appendProtoVarint(msg, pprofValueTypeUnit, uint64(p.str(typ[1])))
+	}
+	var blob []byte
+	for _, typ := range sampleTypes {
+		blob = appendProtoBytes(blob, pprofProfileSampleType, valueType(typ))
+	}
+	blob = append(blob, p.body...)
+	blob = appendProtoBytes(blob, pprofProfilePeriodType, valueType(periodType))
+	blob = appendProtoVarint(blob, pprofProfilePeriod, uint64(period))
+
+	// The string table goes last, as encoding the above may still intern strings
+	for _, s := range p.strings {
+		blob = appendProtoBytes(blob, pprofProfileStrings, []byte(s))
+	}
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(blob); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// appendProtoVarint appends a varint protobuf field to the buffer. Zero values
+// are omitted, as is the default in proto3.
+func appendProtoVarint(buf []byte, field int, value uint64) []byte {
+	if value == 0 {
+		return buf
+	}
+	buf = appendUvarint(buf, uint64(field)<<3)
+	return appendUvarint(buf, value)
+}
+
+// appendProtoBytes appends a length delimited protobuf field to the buffer.
+func appendProtoBytes(buf []byte, field int, data []byte) []byte {
+	buf = appendUvarint(buf, uint64(field)<<3|2)
+	buf = appendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// appendUvarint appends the varint encoding of a value to the buffer.
+func appendUvarint(buf []byte, value uint64) []byte {
+	var enc [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(enc[:], value)
+	return append(buf, enc[:n]...)
+}
@@ -0,0 +1,360 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
+	"github.com/btpereum/go-btpereum/core/vm"
+)
+
+// ErrDebuggerClosed is returned if a command is issued to a step debugger that
+// was already closed.
+var ErrDebuggerClosed = errors.New("debugger closed")
+
+// debugMode is the condition on which a resumed execution pauses again.
+type debugMode int
+
+const (
+	debugStepInto debugMode = iota // Pause at the next instruction
+	debugStepOver                  // Pause at the next instruction of the same or an outer call
+	debugStepOut                   // Pause at the next instruction of an outer call
+	debugContinue                  // Pause only at breakpoints
+)
+
+// Breakpoint is a condition pausing the execution whenever all of its set fields
+// match the instruction about to be executed.
+type Breakpoint struct {
+	Contract *common.Address `json:"contract,omitempty"` // Address of the code to break in, any if nil
+	PC       *hexutil.Uint64 `json:"pc,omitempty"`       // Program counter to break at, any if nil
+	Op       string          `json:"op,omitempty"`       // Opcode to break at, any if empty
+}
+
+// matches returns whether the breakpoint is hit by the given instruction.
+func (bp *Breakpoint) matches(code common.Address, pc uint64, op vm.OpCode) bool {
+	if bp.Contract != nil && *bp.Contract != code {
+		return false
+	}
+	if bp.PC != nil && uint64(*bp.PC) != pc {
+		return false
+	}
+	if bp.Op != "" && !strings.EqualFold(bp.Op, op.String()) {
+		return false
+	}
+	return true
+}
+
+// DebugStep is a snapshot of the EVM at an instruction the execution paused at,
+// before the instruction is executed.
+type DebugStep struct {
+	PC          uint64         `json:"pc"`              // Program counter of the instruction
+	Op          string         `json:"op"`              // Opcode of the instruction
+	Gas         uint64         `json:"gas"`             // Gas available before the instruction
+	Cost        uint64         `json:"gasCost"`         // Gas cost of the instruction
+	Depth       int            `json:"depth"`           // Call depth of the instruction
+	Address     common.Address `json:"address"`         // Account whose storage is being operated on
+	CodeAddress common.Address `json:"codeAddress"`     // Account whose code is being executed
+	Stack       []*hexutil.Big `json:"stack"`           // Stack items, bottom first
+	Memory      hexutil.Bytes  `json:"memory"`          // Contents of the memory
+	Error       string         `json:"error,omitempty"` // Error the instruction is failing with
+}
+
+// DebugResult is the outcome of a debugged execution.
+type DebugResult struct {
+	Output  hexutil.Bytes  `json:"output"`          // Data returned by the execution
+	GasUsed hexutil.Uint64 `json:"gasUsed"`         // Gas used by the execution
+	Error   string         `json:"error,omitempty"` // Error the execution failed with
+}
+
+// DebugEvent is reported whenever the debugged execution pauses or finishes.
+type DebugEvent struct {
+	Step   *DebugStep   `json:"step,omitempty"`   // Instruction the execution paused at
+	Result *DebugResult `json:"result,omitempty"` // Outcome of the execution once finished
+}
+
+// debugCommand is an action executed on the paused EVM goroutine. It returns
+// whether execution should be resumed.
+type debugCommand func(env *vm.EVM) bool
+
+// StepDebugger is a tracer pausing the EVM execution on its own goroutine at
+// every instruction matching the current stepping mode or a breakpoint, and
+// serving inspection and resumption commands until execution is resumed.
+//
+// The EVM must run on a different goroutine than the one issuing commands. Once
+// the debugger is closed, execution continues to completion without pausing.
+type StepDebugger struct {
+	mode        debugMode    // Condition on which to pause next
+	depth       int          // Call depth of the last paused instruction
+	breakpoints []Breakpoint // Breakpoints pausing the execution
+	result      *DebugResult // Outcome of the execution reported by the EVM
+
+	events   chan *DebugEvent  // Pause and finish notifications from the EVM goroutine
+	commands chan debugCommand // Commands to execute on the paused EVM goroutine
+	quit     chan struct{}     // Channel closed when the debugger is closed
+	final    *DebugEvent       // Finish notification, once received
+	once     sync.Once         // Ensures the quit channel is closed only once
+	lock     sync.Mutex        // Lock serializing the commands issued
+}
+
+// NewStepDebugger creates a step debugger pausing at the first instruction.
+func NewStepDebugger() *StepDebugger {
+	return &StepDebugger{
+		mode:     debugStepInto,
+		events:   make(chan *DebugEvent),
+		commands: make(chan debugCommand),
+		quit:     make(chan struct{}),
+	}
+}
+
+// CaptureStart implements the Tracer interface.
+func (d *StepDebugger) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface, pausing the execution if the
+// instruction matches the stepping mode or any breakpoint.
+func (d *StepDebugger) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	select {
+	case <-d.quit:
+		return nil
+	default:
+	}
+	code := contract.Address()
+	if contract.CodeAddr != nil {
+		code = *contract.CodeAddr
+	}
+	if !d.shouldPause(code, pc, op, depth) {
+		return nil
+	}
+	d.depth = depth
+
+	// Report the paused instruction and serve commands until resumed
+	step := &DebugStep{
+		PC:          pc,
+		Op:          op.String(),
+		Gas:         gas,
+		Cost:        cost,
+		Depth:       depth,
+		Address:     contract.Address(),
+		CodeAddress: code,
+		Stack:       make([]*hexutil.Big, 0, len(stack.Data())),
+		Memory:      common.CopyBytes(memory.Data()),
+	}
+	for _, item := range stack.Data() {
+		step.Stack = append(step.Stack, (*hexutil.Big)(new(big.Int).Set(item)))
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	select {
+	case d.events <- &DebugEvent{Step: step}:
+	case <-d.quit:
+		return nil
+	}
+	for {
+		select {
+		case cmd := <-d.commands:
+			if cmd(env) {
+				return nil
+			}
+		case <-d.quit:
+			return nil
+		}
+	}
+}
+
+// shouldPause returns whether execution should pause at the given instruction.
+func (d *StepDebugger) shouldPause(code common.Address, pc uint64, op vm.OpCode, depth int) bool {
+	for i := range d.breakpoints {
+		if d.breakpoints[i].matches(code, pc, op) {
+			return true
+		}
+	}
+	switch d.mode {
+	case debugStepInto:
+		return true
+	case debugStepOver:
+		return depth <= d.depth
+	case debugStepOut:
+		return depth < d.depth
+	default:
+		return false
+	}
+}
+
+// CaptureFault implements the Tracer interface. The faulting instruction was
+// already paused at (if needed) by CaptureState.
+func (d *StepDebugger) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the Tracer interface, recording the outcome of the
+// execution.
+func (d *StepDebugger) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	d.result = &DebugResult{
+		Output:  common.CopyBytes(output),
+		GasUsed: hexutil.Uint64(gasUsed),
+	}
+	if err != nil {
+		d.result.Error = err.Error()
+	}
+	return nil
+}
+
+// Finish must be called from the EVM goroutine once the execution is done, with
+// any error that prevented it from running.
+func (d *StepDebugger) Finish(err error) {
+	result := d.result
+	if result == nil {
+		result = new(DebugResult)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	select {
+	case d.events <- &DebugEvent{Result: result}:
+	case <-d.quit:
+	}
+}
+
+// Wait blocks until the execution pauses or finishes, returning the event.
+func (d *StepDebugger) Wait() (*DebugEvent, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.wait()
+}
+
+// wait blocks until the next event is reported, caching the final one.
+func (d *StepDebugger) wait() (*DebugEvent, error) {
+	if d.final != nil {
+		return d.final, nil
+	}
+	select {
+	case event := <-d.events:
+		if event.Result != nil {
+			d.final = event
+		}
+		return event, nil
+	case <-d.quit:
+		return nil, ErrDebuggerClosed
+	}
+}
+
+// execute runs a command on the paused EVM goroutine, waiting for it to complete.
+// If the execution already finished, the command is silently dropped.
+func (d *StepDebugger) execute(cmd debugCommand) error {
+	if d.final != nil {
+		return nil
+	}
+	done := make(chan struct{})
+	wrapped := func(env *vm.EVM) bool {
+		defer close(done)
+		return cmd(env)
+	}
+	select {
+	case d.commands <- wrapped:
+	case <-d.quit:
+		return ErrDebuggerClosed
+	}
+	<-done
+	return nil
+}
+
+// resume continues the execution until it pauses again according to the given
+// mode, or finishes.
+func (d *StepDebugger) resume(mode debugMode) (*DebugEvent, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	err := d.execute(func(*vm.EVM) bool {
+		d.mode = mode
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.wait()
+}
+
+// StepInto resumes the execution until the next instruction.
+func (d *StepDebugger) StepInto() (*DebugEvent, error) {
+	return d.resume(debugStepInto)
+}
+
+// StepOver resumes the execution until the next instruction of the current call,
+// or its caller if the current call returns.
+func (d *StepDebugger) StepOver() (*DebugEvent, error) {
+	return d.resume(debugStepOver)
+}
+
+// StepOut resumes the execution until the current call returns to its caller.
+func (d *StepDebugger) StepOut() (*DebugEvent, error) {
+	return d.resume(debugStepOut)
+}
+
+// Continue resumes the execution until the next breakpoint is hit.
+func (d *StepDebugger) Continue() (*DebugEvent, error) {
+	return d.resume(debugContinue)
+}
+
+// SetBreakpoints replaces the breakpoints pausing the execution.
+func (d *StepDebugger) SetBreakpoints(breakpoints []Breakpoint) error {
+	for _, bp := range breakpoints {
+		if bp.Contract == nil && bp.PC == nil && bp.Op == "" {
+			return errors.New("breakpoint without any condition")
+		}
+		if bp.Op != "" && vm.StringToOp(strings.ToUpper(bp.Op)) == vm.STOP && !strings.EqualFold(bp.Op, "STOP") {
+			return errors.New("unknown breakpoint opcode: " + bp.Op)
+		}
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.execute(func(*vm.EVM) bool {
+		d.breakpoints = breakpoints
+		return false
+	})
+}
+
+// Storage retrieves a storage slot of an account at the paused instruction.
+func (d *StepDebugger) Storage(address common.Address, key common.Hash) (common.Hash, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.final != nil {
+		return common.Hash{}, errors.New("execution already finished")
+	}
+	var value common.Hash
+	err := d.execute(func(env *vm.EVM) bool {
+		value = env.StateDB.GetState(address, key)
+		return false
+	})
+	return value, err
+}
+
+// Close terminates the debugging session, letting the execution run to completion
+// without pausing anymore.
+func (d *StepDebugger) Close() {
+	d.once.Do(func() { close(d.quit) })
+}
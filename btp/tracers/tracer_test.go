@@ -18,6 +18,7 @@ package tracers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"math/big"
@@ -145,3 +146,159 @@ func TestHaltBetweenSteps(t *testing.T) {
 		t.Errorf("Expected timeout error, got %v", err)
 	}
 }
+
+func TestBudget(t *testing.T) {
+	// Ensure the result size allowance is enforced and interrupts the tracers
+	budget := NewBudget(context.Background(), 0, 10, 0)
+	defer budget.Release()
+
+	if err := budget.Charge(8); err != nil {
+		t.Fatalf("failed to charge result within budget: %v", err)
+	}
+	if err := budget.Charge(8); err != ErrBudgetResultSize {
+		t.Fatalf("result size error mismatch: have %v, want %v", err, ErrBudgetResultSize)
+	}
+	select {
+	case <-budget.Context().Done():
+	default:
+		t.Fatalf("exhausted budget didn't cancel its context")
+	}
+	// Ensure the heap allowance is enforced based on the tracer state
+	budget = NewBudget(context.Background(), 0, 0, 16)
+	defer budget.Release()
+
+	tracer, err := New("{data: 'abcdefghijklmnopqrstuvwxyz', step: function() {}, fault: function() {}, result: function() { return null; }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer.SetBudget(budget)
+	tracer.sampleHeap()
+
+	if err := budget.Err(); err != ErrBudgetHeapSize {
+		t.Fatalf("heap size error mismatch: have %v, want %v", err, ErrBudgetHeapSize)
+	}
+	if _, err := tracer.GetResult(); err != nil {
+		t.Fatalf("failed to retrieve result: %v", err)
+	}
+	if budget.heap != 0 {
+		t.Fatalf("heap allowance not released: %d bytes", budget.heap)
+	}
+	// Ensure cyclic tracer state is accounted for too, not skipped as unserializable
+	budget = NewBudget(context.Background(), 0, 0, 1024)
+	defer budget.Release()
+
+	tracer, err = New(`{data: [], grow: function() {
+		this.self = this;
+		for (var i = 0; i < 100; i++) { this.data.push({parent: this.data, value: 'abcdefghijklmnopqrstuvwxyz'}); }
+	}, step: function() {}, fault: function() {}, result: function() { return null; }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracer.call("grow"); err != nil {
+		t.Fatalf("failed to grow tracer state: %v", err)
+	}
+	tracer.vm.Dup(tracer.tracerObject)
+	full, capped := retainedSize(tracer.vm, -1, 0), retainedSize(tracer.vm, -1, 1024)
+	tracer.vm.Pop()
+
+	if full < 100*26 {
+		t.Fatalf("cyclic state undercounted: have %d bytes, want at least %d", full, 100*26)
+	}
+	if capped <= 1024 || capped >= full {
+		t.Fatalf("capped walk size mismatch: have %d bytes, want in (1024, %d)", capped, full)
+	}
+	tracer.SetBudget(budget)
+	tracer.sampleHeap()
+
+	if err := budget.Err(); err != ErrBudgetHeapSize {
+		t.Fatalf("cyclic heap size error mismatch: have %v, want %v", err, ErrBudgetHeapSize)
+	}
+	// Ensure the time allowance is enforced
+	budget = NewBudget(context.Background(), time.Millisecond, 0, 0)
+	defer budget.Release()
+
+	<-budget.Context().Done()
+	if err := budget.Err(); err != ErrBudgetTimeout {
+		t.Fatalf("timeout error mismatch: have %v, want %v", err, ErrBudgetTimeout)
+	}
+}
+
+// Tests that structured loggers bound to a budget stop collecting logs and abort
+// the execution once the budget runs out.
+func TestBudgetLogger(t *testing.T) {
+	run := func(budget *Budget) *vm.StructLogger {
+		logger := vm.NewStructLogger(nil)
+		env := vm.NewEVM(vm.Context{BlockNumber: big.NewInt(1)}, &dummyStatedb{}, params.TestChainConfig, vm.Config{Debug: true, Tracer: WithBudget(logger, budget)})
+
+		// Run an infinite loop, only bounded by the gas allowance
+		contract := vm.NewContract(account{}, account{}, big.NewInt(0), 1000000)
+		contract.Code = []byte{byte(vm.JUMPDEST), byte(vm.PUSH1), 0x0, byte(vm.JUMP)}
+
+		env.Interpreter().Run(contract, []byte{}, false)
+		return logger
+	}
+	// Ensure the collected logs are bounded by the result allowance
+	budget := NewBudget(context.Background(), 0, 1024, 0)
+	defer budget.Release()
+
+	if logs := len(run(budget).StructLogs()); logs > 1024/128 {
+		t.Fatalf("collected logs above the result allowance: %d", logs)
+	}
+	if err := budget.Err(); err != ErrBudgetResultSize {
+		t.Fatalf("result size error mismatch: have %v, want %v", err, ErrBudgetResultSize)
+	}
+	// Ensure nothing is collected once the budget is exhausted
+	budget = NewBudget(context.Background(), time.Millisecond, 0, 0)
+	defer budget.Release()
+
+	<-budget.Context().Done()
+	if logs := len(run(budget).StructLogs()); logs != 0 {
+		t.Fatalf("collected logs after the budget ran out: %d", logs)
+	}
+}
+
+func TestStepDebugger(t *testing.T) {
+	debugger := NewStepDebugger()
+	defer debugger.Close()
+
+	env := vm.NewEVM(vm.Context{BlockNumber: big.NewInt(1)}, &dummyStatedb{}, params.TestChainConfig, vm.Config{Debug: true, Tracer: debugger})
+
+	contract := vm.NewContract(account{}, account{}, big.NewInt(0), 10000)
+	contract.Code = []byte{byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x2, 0x0}
+
+	go func() {
+		_, err := env.Interpreter().Run(contract, []byte{}, false)
+		debugger.Finish(err)
+	}()
+	// Execution should pause at the very first instruction
+	event, err := debugger.Wait()
+	if err != nil {
+		t.Fatalf("failed to wait for first pause: %v", err)
+	}
+	if event.Step == nil || event.Step.PC != 0 || event.Step.Op != "PUSH1" {
+		t.Fatalf("first pause mismatch: have %+v", event.Step)
+	}
+	// Stepping should pause at the next instruction, with the stack populated
+	if event, err = debugger.StepInto(); err != nil {
+		t.Fatalf("failed to step: %v", err)
+	}
+	if event.Step == nil || event.Step.PC != 2 || len(event.Step.Stack) != 1 || event.Step.Stack[0].ToInt().Uint64() != 1 {
+		t.Fatalf("stepped pause mismatch: have %+v", event.Step)
+	}
+	// Continuing should run until the breakpoint, and then until the end
+	if err := debugger.SetBreakpoints([]Breakpoint{{Op: "stop"}}); err != nil {
+		t.Fatalf("failed to set breakpoints: %v", err)
+	}
+	if event, err = debugger.Continue(); err != nil {
+		t.Fatalf("failed to continue: %v", err)
+	}
+	if event.Step == nil || event.Step.PC != 4 || len(event.Step.Stack) != 2 {
+		t.Fatalf("breakpoint pause mismatch: have %+v", event.Step)
+	}
+	if event, err = debugger.Continue(); err != nil {
+		t.Fatalf("failed to continue: %v", err)
+	}
+	if event.Step != nil || event.Result == nil || event.Result.Error != "" {
+		t.Fatalf("finished execution mismatch: have %+v", event)
+	}
+}
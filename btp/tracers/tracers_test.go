@@ -17,6 +17,8 @@
 package tracers
 
 import (
+	"bytes"
+	"compress/gzip"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/json"
@@ -32,6 +34,7 @@ import (
 	"github.com/btpereum/go-btpereum/common/math"
 	"github.com/btpereum/go-btpereum/core"
 	"github.com/btpereum/go-btpereum/core/rawdb"
+	"github.com/btpereum/go-btpereum/core/state"
 	"github.com/btpereum/go-btpereum/core/types"
 	"github.com/btpereum/go-btpereum/core/vm"
 	"github.com/btpereum/go-btpereum/crypto"
@@ -275,3 +278,197 @@ func TestCallTracer(t *testing.T) {
 		})
 	}
 }
+
+// callTreeMatches reports whether the call tree produced by the native call
+// tracer matches the structure of the one produced by the JavaScript tracer.
+func callTreeMatches(have *CallFrame, want *callTrace) bool {
+	if have.Type.String() != want.Type || len(have.Calls) != len(want.Calls) {
+		return false
+	}
+	if have.Type == vm.SELFDESTRUCT {
+		return true
+	}
+	if have.From != want.From || have.To != want.To || have.Error != want.Error {
+		return false
+	}
+	if !bytes.Equal(have.Input, want.Input) {
+		return false
+	}
+	if (have.Value == nil) != (want.Value == nil) || (have.Value != nil && have.Value.Cmp(want.Value.ToInt()) != 0) {
+		return false
+	}
+	for i := range have.Calls {
+		if !callTreeMatches(have.Calls[i], &want.Calls[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Tests that the native call tracer produces the same call trees as the
+// JavaScript one.
+func TestNativeCallTracer(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+				t.Fatalf("failed to parse testcase input: %v", err)
+			}
+			signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+			origin, _ := signer.Sender(tx)
+
+			context := vm.Context{
+				CanTransfer: core.CanTransfer,
+				Transfer:    core.Transfer,
+				Origin:      origin,
+				Coinbase:    test.Context.Miner,
+				BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+				Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+				Difficulty:  (*big.Int)(test.Context.Difficulty),
+				GasLimit:    uint64(test.Context.GasLimit),
+				GasPrice:    tx.GasPrice(),
+			}
+			statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc)
+
+			tracer := NewCallTracer()
+			evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+			msg, err := tx.AsMessage(signer)
+			if err != nil {
+				t.Fatalf("failed to prepare transaction for tracing: %v", err)
+			}
+			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+			if _, _, _, err = st.TransitionDb(); err != nil {
+				t.Fatalf("failed to execute transaction: %v", err)
+			}
+			res, err := tracer.Result()
+			if err != nil {
+				t.Fatalf("failed to retrieve trace result: %v", err)
+			}
+			if !callTreeMatches(res, test.Result) {
+				t.Fatalf("call tree mismatch: \nhave %+v\nwant %+v", res, test.Result)
+			}
+		})
+	}
+}
+
+// Tests that the native call tracer reports the failure of inner calls which
+// never started executing, such as value transfers exceeding the balance.
+func TestNativeCallTracerFailedTransfer(t *testing.T) {
+	var (
+		caller   = common.HexToAddress("0x01")
+		contract = common.HexToAddress("0x02")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	// Transfer 1 wei from a contract without balance to 0xdead, then stop
+	statedb.SetCode(contract, common.FromHex("0x6000600060006000600161dead5af100"))
+
+	tracer := NewCallTracer()
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(1),
+		GasPrice:    big.NewInt(1),
+	}
+	evm := vm.NewEVM(context, statedb, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	if _, _, err := evm.Call(vm.AccountRef(caller), contract, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("failed to execute call: %v", err)
+	}
+	res, err := tracer.Result()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	if res.Error != "" {
+		t.Fatalf("outer call failed: %v", res.Error)
+	}
+	if len(res.Calls) != 1 {
+		t.Fatalf("inner call count mismatch: have %d, want %d", len(res.Calls), 1)
+	}
+	if call := res.Calls[0]; call.To != common.HexToAddress("0xdead") || call.Value.Cmp(big.NewInt(1)) != 0 || call.Error == "" {
+		t.Fatalf("failed transfer mismatch: have %+v", call)
+	}
+}
+
+// Tests that the gas profiler charges call instructions only with their own
+// overhead, attributing the gas consumed by inner calls to the callees.
+func TestGasProfiler(t *testing.T) {
+	var (
+		outer = common.HexToAddress("0x01")
+		inner = common.HexToAddress("0x02")
+	)
+	caller := vm.NewContract(account{}, account{}, big.NewInt(0), 1000)
+	caller.CodeAddr, caller.Input = &outer, []byte{0x11, 0x22, 0x33, 0x44, 0xff}
+
+	callee := vm.NewContract(account{}, account{}, big.NewInt(0), 500)
+	callee.CodeAddr, callee.Input = &inner, []byte{0xaa, 0xbb, 0xcc, 0xdd}
+
+	profiler := NewGasProfiler()
+	profiler.CaptureStart(common.Address{}, outer, false, caller.Input, 1000, big.NewInt(0))
+	profiler.CaptureState(nil, 0, vm.PUSH1, 1000, 3, nil, nil, caller, 1, nil)
+	profiler.CaptureState(nil, 2, vm.CALL, 997, 1200, nil, nil, caller, 1, nil)
+	profiler.CaptureState(nil, 0, vm.PUSH1, 500, 3, nil, nil, callee, 2, nil)
+	profiler.CaptureState(nil, 2, vm.STOP, 497, 0, nil, nil, callee, 2, nil)
+	profiler.CaptureState(nil, 3, vm.STOP, 294, 0, nil, nil, caller, 1, nil)
+	profiler.CaptureEnd(nil, 706, 0, nil)
+
+	want := GasProfile{
+		outer: {
+			Gas: 703, Steps: 3, Calls: 1,
+			Selectors: map[string]*SelectorProfile{"0x11223344": {Gas: 703, Steps: 3, Calls: 1}},
+			Instructions: map[uint64]*InstructionProfile{
+				0: {Op: "PUSH1", Gas: 3, Count: 1},
+				2: {Op: "CALL", Gas: 700, Count: 1},
+				3: {Op: "STOP", Gas: 0, Count: 1},
+			},
+		},
+		inner: {
+			Gas: 3, Steps: 2, Calls: 1,
+			Selectors: map[string]*SelectorProfile{"0xaabbccdd": {Gas: 3, Steps: 2, Calls: 1}},
+			Instructions: map[uint64]*InstructionProfile{
+				0: {Op: "PUSH1", Gas: 3, Count: 1},
+				2: {Op: "STOP", Gas: 0, Count: 1},
+			},
+		},
+	}
+	if have := profiler.Profile(); !reflect.DeepEqual(have, want) {
+		haveBlob, _ := json.Marshal(have)
+		wantBlob, _ := json.Marshal(want)
+		t.Fatalf("gas profile mismatch:\nhave %s\nwant %s", haveBlob, wantBlob)
+	}
+	buf := new(bytes.Buffer)
+	if err := profiler.WritePprof(buf); err != nil {
+		t.Fatalf("failed to write pprof profile: %v", err)
+	}
+	zr, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatalf("pprof profile not gzipped: %v", err)
+	}
+	blob, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress pprof profile: %v", err)
+	}
+	for _, name := range []string{"gas", "steps", "PUSH1", "CALL", outer.Hex() + ".0x11223344"} {
+		if !bytes.Contains(blob, []byte(name)) {
+			t.Errorf("pprof profile missing %q", name)
+		}
+	}
+}
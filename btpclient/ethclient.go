@@ -32,6 +32,19 @@ import (
 	"github.com/btpereum/go-btpereum/rpc"
 )
 
+// Block number tags, which may be passed in place of a concrete block number in
+// the FromBlock and ToBlock fields of log filter queries, resolved by the node
+// when the request is served. Other methods don't accept them.
+var (
+	// SafeBlockTag references the most recent block with the node's configured
+	// number of confirmations on top of it.
+	SafeBlockTag = big.NewInt(-4)
+
+	// FinalizedBlockTag references the most recent block the node's consensus
+	// engine considers final.
+	FinalizedBlockTag = big.NewInt(-3)
+)
+
 // Client defines typed wrappers for the btpereum RPC API.
 type Client struct {
 	c *rpc.Client
@@ -180,6 +193,22 @@ func (ec *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.H
 	return head, err
 }
 
+// SafeBlockNumber returns the number of the most recent block with at least the
+// node's configured number of confirmations on top of it.
+func (ec *Client) SafeBlockNumber(ctx context.Context) (uint64, error) {
+	var result hexutil.Uint64
+	err := ec.c.CallContext(ctx, &result, "btp_safeBlockNumber")
+	return uint64(result), err
+}
+
+// FinalizedBlockNumber returns the number of the most recent block the node's
+// consensus engine considers final.
+func (ec *Client) FinalizedBlockNumber(ctx context.Context) (uint64, error) {
+	var result hexutil.Uint64
+	err := ec.c.CallContext(ctx, &result, "btp_finalizedBlockNumber")
+	return uint64(result), err
+}
+
 type rpcTransaction struct {
 	tx *types.Transaction
 	txExtraInfo
@@ -384,6 +413,31 @@ func (ec *Client) FilterLogs(ctx context.Context, q btpereum.FilterQuery) ([]typ
 	return result, err
 }
 
+// FilterLogsPage executes a filter query, returning at most limit logs starting
+// at the given cursor of a previous page (empty for the first page). The cursor
+// of the next page is returned, which is empty if no more logs are available.
+func (ec *Client) FilterLogsPage(ctx context.Context, q btpereum.FilterQuery, limit uint, cursor string) ([]types.Log, string, error) {
+	arg, err := toFilterArg(q)
+	if err != nil {
+		return nil, "", err
+	}
+	var next *string
+	if cursor != "" {
+		next = &cursor
+	}
+	var result struct {
+		Logs   []types.Log `json:"logs"`
+		Cursor *string     `json:"cursor"`
+	}
+	if err := ec.c.CallContext(ctx, &result, "btp_getLogsPage", arg, hexutil.Uint(limit), next); err != nil {
+		return nil, "", err
+	}
+	if result.Cursor == nil {
+		return result.Logs, "", nil
+	}
+	return result.Logs, *result.Cursor, nil
+}
+
 // SubscribeFilterLogs subscribes to the results of a streaming filter query.
 func (ec *Client) SubscribeFilterLogs(ctx context.Context, q btpereum.FilterQuery, ch chan<- types.Log) (btpereum.Subscription, error) {
 	arg, err := toFilterArg(q)
@@ -407,13 +461,27 @@ func toFilterArg(q btpereum.FilterQuery) (interface{}, error) {
 		if q.FromBlock == nil {
 			arg["fromBlock"] = "0x0"
 		} else {
-			arg["fromBlock"] = toBlockNumArg(q.FromBlock)
+			arg["fromBlock"] = toFilterBlockArg(q.FromBlock)
 		}
-		arg["toBlock"] = toBlockNumArg(q.ToBlock)
+		arg["toBlock"] = toFilterBlockArg(q.ToBlock)
 	}
 	return arg, nil
 }
 
+// toFilterBlockArg converts a filter query bound into its RPC form, additionally
+// encoding the block tags which only the log filters understand.
+func toFilterBlockArg(number *big.Int) string {
+	switch {
+	case number == nil:
+		return "latest"
+	case number.Cmp(SafeBlockTag) == 0:
+		return "safe"
+	case number.Cmp(FinalizedBlockTag) == 0:
+		return "finalized"
+	}
+	return hexutil.EncodeBig(number)
+}
+
 // Pending State
 
 // PendingBalanceAt returns the wei balance of the given account in the pending state.
@@ -492,6 +560,37 @@ func (ec *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	return (*big.Int)(&hex), nil
 }
 
+type feeHistoryResultMarshaling struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory retrieves the fee market history of up to blockCount blocks ending
+// with lastBlock (nil = latest): the gas used ratio of each block, along with the
+// requested percentiles of the gas prices paid in each block.
+func (ec *Client) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*btpereum.FeeHistory, error) {
+	var res feeHistoryResultMarshaling
+	if err := ec.c.CallContext(ctx, &res, "btp_feeHistory", hexutil.Uint64(blockCount), toBlockNumArg(lastBlock), rewardPercentiles); err != nil {
+		return nil, err
+	}
+	if res.OldestBlock == nil {
+		return nil, btpereum.NotFound
+	}
+	reward := make([][]*big.Int, len(res.Reward))
+	for i, r := range res.Reward {
+		reward[i] = make([]*big.Int, len(r))
+		for j, r := range r {
+			reward[i][j] = (*big.Int)(r)
+		}
+	}
+	return &btpereum.FeeHistory{
+		OldestBlock:  (*big.Int)(res.OldestBlock),
+		Reward:       reward,
+		GasUsedRatio: res.GasUsedRatio,
+	}, nil
+}
+
 // EstimateGas tries to estimate the gas needed to execute a specific transaction based on
 // the current pending state of the backend blockchain. There is no guarantee that this is
 // the true gas limit requirement as other transactions may be added or removed by miners,
@@ -97,6 +97,22 @@ func TestToFilterArg(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"with safe fromBlock and finalized toBlock",
+			btpereum.FilterQuery{
+				Addresses: addresses,
+				FromBlock: SafeBlockTag,
+				ToBlock:   FinalizedBlockTag,
+				Topics:    [][]common.Hash{},
+			},
+			map[string]interface{}{
+				"address":   addresses,
+				"fromBlock": "safe",
+				"toBlock":   "finalized",
+				"topics":    [][]common.Hash{},
+			},
+			nil,
+		},
 		{
 			"with blockhash",
 			btpereum.FilterQuery{
@@ -335,3 +351,59 @@ func TestChainID(t *testing.T) {
 		t.Fatalf("ChainID returned wrong number: %+v", id)
 	}
 }
+
+func TestFeeHistory(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	// Request more blocks than available, the history should be capped at genesis
+	history, err := ec.FeeHistory(context.Background(), 4, nil, []float64{10, 50})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if history.OldestBlock.Sign() != 0 {
+		t.Fatalf("oldest block mismatch: have %v, want 0", history.OldestBlock)
+	}
+	if len(history.GasUsedRatio) != 2 || len(history.Reward) != 2 {
+		t.Fatalf("history length mismatch: have %d ratios, %d rewards, want 2", len(history.GasUsedRatio), len(history.Reward))
+	}
+	for i, reward := range history.Reward {
+		if len(reward) != 2 || reward[0].Sign() != 0 || reward[1].Sign() != 0 {
+			t.Fatalf("block %d: empty block rewards mismatch: have %v", i, reward)
+		}
+	}
+	if _, err := ec.FeeHistory(context.Background(), 1, nil, []float64{50, 10}); err == nil {
+		t.Fatalf("unordered percentiles accepted")
+	}
+}
+
+func TestSafeAndFinalizedBlocks(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	// The test node requires no confirmations, the head itself is safe
+	safe, err := ec.SafeBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("failed to retrieve safe block: %v", err)
+	}
+	if safe != 1 {
+		t.Fatalf("safe block mismatch: have %d, want %d", safe, 1)
+	}
+	// Finality is a clique concept, ethash nodes can't provide it
+	if _, err := ec.FinalizedBlockNumber(context.Background()); err == nil {
+		t.Fatalf("finalized block retrieved from a non-clique node")
+	}
+	// Log filters accept the tags in place of block numbers
+	if _, err := ec.FilterLogs(context.Background(), btpereum.FilterQuery{FromBlock: big.NewInt(0), ToBlock: SafeBlockTag}); err != nil {
+		t.Fatalf("failed to filter logs up to the safe block: %v", err)
+	}
+	if _, err := ec.FilterLogs(context.Background(), btpereum.FilterQuery{FromBlock: big.NewInt(0), ToBlock: FinalizedBlockTag}); err == nil {
+		t.Fatalf("filtered logs up to the finalized block of a non-clique node")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package btpclient
+
+import (
+	"context"
+
+	"github.com/btpereum/go-btpereum"
+	"github.com/btpereum/go-btpereum/core/types"
+)
+
+// LogIterator iterates over the logs matching a filter query, transparently
+// retrieving them from the node page by page.
+type LogIterator struct {
+	client *Client
+	ctx    context.Context
+	query  btpereum.FilterQuery
+	limit  uint // Maximum number of logs to retrieve at once (0 = server cap)
+
+	logs   []types.Log // Logs of the current page not yet iterated over
+	cursor string      // Cursor of the next page to retrieve
+	done   bool        // Whether the last page has been retrieved
+	log    types.Log   // Log the iterator is currently positioned at
+	err    error       // Any error that occurred during the retrieval
+}
+
+// FilterLogsIterator creates an iterator over the logs matching the given filter
+// query, retrieving at most pageSize logs at once, or the maximum allowed by the
+// server if zero.
+func (ec *Client) FilterLogsIterator(ctx context.Context, q btpereum.FilterQuery, pageSize uint) *LogIterator {
+	return &LogIterator{
+		client: ec,
+		ctx:    ctx,
+		query:  q,
+		limit:  pageSize,
+	}
+}
+
+// Next advances the iterator to the next log, retrieving the next page from the
+// node if needed. It returns whether there is such a log; when false, Error
+// should be checked to distinguish the end of the logs from a failure.
+func (it *LogIterator) Next() bool {
+	for len(it.logs) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.logs, it.cursor, it.err = it.client.FilterLogsPage(it.ctx, it.query, it.limit, it.cursor)
+		if it.err != nil {
+			return false
+		}
+		it.done = it.cursor == ""
+	}
+	it.log, it.logs = it.logs[0], it.logs[1:]
+	return true
+}
+
+// Log returns the log the iterator is currently positioned at.
+func (it *LogIterator) Log() types.Log {
+	return it.log
+}
+
+// Error returns any retrieval error that occurred during the iteration.
+func (it *LogIterator) Error() error {
+	return it.err
+}
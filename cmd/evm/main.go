@@ -44,6 +44,10 @@ var (
 		Name:  "cpuprofile",
 		Usage: "creates a CPU profile at the given path",
 	}
+	GasProfileFlag = cli.StringFlag{
+		Name:  "gasprofile",
+		Usage: "creates a pprof gas profile of the execution at the given path",
+	}
 	StatDumpFlag = cli.BoolFlag{
 		Name:  "statdump",
 		Usage: "displays stack and heap memory information",
@@ -75,6 +79,10 @@ var (
 		Name:  "dump",
 		Usage: "dumps the state after the run",
 	}
+	StateDiffFlag = cli.BoolFlag{
+		Name:  "statediff",
+		Usage: "displays the state changes made by the run",
+	}
 	InputFlag = cli.StringFlag{
 		Name:  "input",
 		Usage: "input for the EVM",
@@ -129,9 +137,11 @@ func init() {
 		PriceFlag,
 		ValueFlag,
 		DumpFlag,
+		StateDiffFlag,
 		InputFlag,
 		MemProfileFlag,
 		CPUProfileFlag,
+		GasProfileFlag,
 		StatDumpFlag,
 		GenesisFlag,
 		MachineFlag,
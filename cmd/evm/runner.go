@@ -34,6 +34,7 @@ import (
 	"github.com/btpereum/go-btpereum/core/state"
 	"github.com/btpereum/go-btpereum/core/vm"
 	"github.com/btpereum/go-btpereum/core/vm/runtime"
+	"github.com/btpereum/go-btpereum/btp/tracers"
 	"github.com/btpereum/go-btpereum/log"
 	"github.com/btpereum/go-btpereum/params"
 	cli "gopkg.in/urfave/cli.v1"
@@ -81,6 +82,7 @@ func runCmd(ctx *cli.Context) error {
 	var (
 		tracer        vm.Tracer
 		debugLogger   *vm.StructLogger
+		profiler      *tracers.GasProfiler
 		statedb       *state.StateDB
 		chainConfig   *params.ChainConfig
 		sender        = common.BytesToAddress([]byte("sender"))
@@ -89,6 +91,9 @@ func runCmd(ctx *cli.Context) error {
 	)
 	if ctx.GlobalBool(MachineFlag.Name) {
 		tracer = vm.NewJSONLogger(logconfig, os.Stdout)
+	} else if ctx.GlobalString(GasProfileFlag.Name) != "" {
+		profiler = tracers.NewGasProfiler()
+		tracer = profiler
 	} else if ctx.GlobalBool(DebugFlag.Name) {
 		debugLogger = vm.NewStructLogger(logconfig)
 		tracer = debugLogger
@@ -178,7 +183,7 @@ func runCmd(ctx *cli.Context) error {
 		BlockNumber: new(big.Int).SetUint64(genesisConfig.Number),
 		EVMConfig: vm.Config{
 			Tracer:         tracer,
-			Debug:          ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
+			Debug:          ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name) || profiler != nil,
 			EVMInterpreter: ctx.GlobalString(EVMInterpreterFlag.Name),
 		},
 	}
@@ -199,19 +204,27 @@ func runCmd(ctx *cli.Context) error {
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
 	}
+	if !ctx.GlobalBool(CreateFlag.Name) && len(code) > 0 {
+		statedb.SetCode(receiver, code)
+	}
+	if ctx.GlobalBool(StateDiffFlag.Name) {
+		// Flush the setup changes out of the journal, only diff the run itself
+		statedb.Finalise(false)
+	}
 	tstart := time.Now()
 	var leftOverGas uint64
 	if ctx.GlobalBool(CreateFlag.Name) {
 		input := append(code, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))...)
 		ret, _, leftOverGas, err = runtime.Create(input, &runtimeConfig)
 	} else {
-		if len(code) > 0 {
-			statedb.SetCode(receiver, code)
-		}
 		ret, leftOverGas, err = runtime.Call(receiver, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name)), &runtimeConfig)
 	}
 	execTime := time.Since(tstart)
 
+	if ctx.GlobalBool(StateDiffFlag.Name) {
+		diff, _ := json.MarshalIndent(statedb.Diff(), "", "  ")
+		fmt.Println(string(diff))
+	}
 	if ctx.GlobalBool(DumpFlag.Name) {
 		statedb.Commit(true)
 		statedb.IntermediateRoot(true)
@@ -231,6 +244,19 @@ func runCmd(ctx *cli.Context) error {
 		f.Close()
 	}
 
+	if profiler != nil {
+		f, err := os.Create(ctx.GlobalString(GasProfileFlag.Name))
+		if err != nil {
+			fmt.Println("could not create gas profile: ", err)
+			os.Exit(1)
+		}
+		if err := profiler.WritePprof(f); err != nil {
+			fmt.Println("could not write gas profile: ", err)
+			os.Exit(1)
+		}
+		f.Close()
+	}
+
 	if ctx.GlobalBool(DebugFlag.Name) {
 		if debugLogger != nil {
 			fmt.Fprintln(os.Stderr, "#### TRACE ####")
@@ -252,7 +278,7 @@ Gas used:           %d
 
 `, execTime, mem.HeapObjects, mem.Alloc, mem.TotalAlloc, mem.NumGC, initialGas-leftOverGas)
 	}
-	if tracer == nil {
+	if tracer == nil || profiler != nil {
 		fmt.Printf("0x%x\n", ret)
 		if err != nil {
 			fmt.Printf(" error: %v\n", err)
@@ -23,6 +23,9 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/rawdb"
 	"github.com/btpereum/go-btpereum/core/state"
 	"github.com/btpereum/go-btpereum/core/vm"
 	"github.com/btpereum/go-btpereum/log"
@@ -39,13 +42,15 @@ var stateTestCommand = cli.Command{
 }
 
 // StatetestResult contains the execution status after running a state test, any
-// error that might have occurred and a dump of the final state if requested.
+// error that might have occurred, a dump of the final state and the changes
+// made to the pre-state if requested.
 type StatetestResult struct {
-	Name  string      `json:"name"`
-	Pass  bool        `json:"pass"`
-	Fork  string      `json:"fork"`
-	Error string      `json:"error,omitempty"`
-	State *state.Dump `json:"state,omitempty"`
+	Name  string          `json:"name"`
+	Pass  bool            `json:"pass"`
+	Fork  string          `json:"fork"`
+	Error string          `json:"error,omitempty"`
+	State *state.Dump     `json:"state,omitempty"`
+	Diff  state.StateDiff `json:"diff,omitempty"`
 }
 
 func stateTestCmd(ctx *cli.Context) error {
@@ -86,6 +91,14 @@ func stateTestCmd(ctx *cli.Context) error {
 	if err = json.Unmarshal(src, &tests); err != nil {
 		return err
 	}
+	var pres map[string]struct {
+		Pre core.GenesisAlloc `json:"pre"`
+	}
+	if ctx.GlobalBool(StateDiffFlag.Name) {
+		if err = json.Unmarshal(src, &pres); err != nil {
+			return err
+		}
+	}
 	// Iterate over all the tests, run them and aggregate the results
 	cfg := vm.Config{
 		Tracer: tracer,
@@ -109,6 +122,9 @@ func stateTestCmd(ctx *cli.Context) error {
 					result.State = &dump
 				}
 			}
+			if ctx.GlobalBool(StateDiffFlag.Name) && state != nil {
+				result.Diff = stateDiff(pres[key].Pre, state)
+			}
 
 			results = append(results, *result)
 
@@ -125,3 +141,33 @@ func stateTestCmd(ctx *cli.Context) error {
 	fmt.Println(string(out))
 	return nil
 }
+
+// stateDiff collects the changes a state test made to its pre-state. The tests
+// commit their final state, which clears its journal, so the final values are
+// replayed onto a fresh copy of the pre-state for the journal to report them.
+func stateDiff(pre core.GenesisAlloc, post *state.StateDB) state.StateDiff {
+	db := rawdb.NewMemoryDatabase()
+	genesis := (&core.Genesis{Alloc: pre}).ToBlock(db)
+	statedb, _ := state.New(genesis.Root(), state.NewDatabase(db))
+
+	accounts := post.RawDump(true, true, true).Accounts
+	for addr := range pre {
+		if _, ok := accounts[addr]; !ok {
+			statedb.Suicide(addr)
+		}
+	}
+	for addr := range accounts {
+		statedb.SetBalance(addr, post.GetBalance(addr))
+		statedb.SetNonce(addr, post.GetNonce(addr))
+		statedb.SetCode(addr, post.GetCode(addr))
+
+		for key := range pre[addr].Storage {
+			statedb.SetState(addr, key, post.GetState(addr, key))
+		}
+		post.ForEachStorage(addr, func(key, value common.Hash) bool {
+			statedb.SetState(addr, key, value)
+			return true
+		})
+	}
+	return statedb.Diff()
+}
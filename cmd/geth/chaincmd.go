@@ -33,6 +33,7 @@ import (
 	"github.com/btpereum/go-btpereum/core/rawdb"
 	"github.com/btpereum/go-btpereum/core/state"
 	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/core/vm"
 	"github.com/btpereum/go-btpereum/btp/downloader"
 	"github.com/btpereum/go-btpereum/event"
 	"github.com/btpereum/go-btpereum/log"
@@ -188,6 +189,46 @@ Use "btpereum dump 0" to dump the genesis block.`,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 	}
+	rewindForceFlag = cli.BoolFlag{
+		Name:  "force",
+		Usage: "Allow rewinds removing more than the default limit of blocks",
+	}
+	rewindCommand = cli.Command{
+		Action:    utils.MigrateFlags(rewind),
+		Name:      "rewind",
+		Usage:     "Rewind the chain to the nearest block with state at or below a number",
+		ArgsUsage: "<blockNum>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			rewindForceFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The rewind command rewinds the header, fast and full block heads to the nearest
+canonical block at or below the given number whose state is available, truncating
+the ancient store if the target is below the freezer boundary.
+
+Rewinds removing more than 10000 blocks are refused unless --force is given.`,
+	}
+	badBlockCommand = cli.Command{
+		Action:    utils.MigrateFlags(badBlock),
+		Name:      "badblock",
+		Usage:     "List the stored bad blocks or re-validate one of them",
+		ArgsUsage: "[<blockHash>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Without arguments, the badblock command lists the blocks that were rejected during
+import along with the reason they were rejected for. If a block hash is given, the
+block is re-validated against its parent state, tracing every executed opcode to
+stderr and reporting the expected and computed roots.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -406,7 +447,7 @@ func copyDb(ctx *cli.Context) error {
 	if syncMode == downloader.FastSync {
 		syncBloom = trie.NewSyncBloom(uint64(ctx.GlobalInt(utils.CacheFlag.Name)/2), chainDb)
 	}
-	dl := downloader.New(0, chainDb, syncBloom, new(event.TypeMux), chain, nil, nil)
+	dl := downloader.New(0, common.Hash{}, chainDb, syncBloom, new(event.TypeMux), chain, nil, nil, nil)
 
 	// Create a source peer to satisfy downloader requests from
 	db, err := rawdb.NewLevelDBDatabaseWithFreezer(ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name)/2, 256, ctx.Args().Get(1), "")
@@ -552,6 +593,91 @@ func inspect(ctx *cli.Context) error {
 	return rawdb.InspectDatabase(chainDb)
 }
 
+func rewind(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	number, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid block number: %v", err)
+	}
+	stack := makeFullNode(ctx)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	limit := uint64(core.DefaultRewindLimit)
+	if ctx.Bool(rewindForceFlag.Name) {
+		limit = 0
+	}
+	result, err := chain.Rewind(number, limit)
+	if err != nil {
+		utils.Fatalf("Rewind failed: %v", err)
+	}
+	fmt.Printf("New head:         %d %x\n", result.Head.NumberU64(), result.Head.Hash())
+	fmt.Printf("Previous heads:   header %d, fast block %d, block %d\n", result.OldHeader, result.OldFastBlock, result.OldBlock)
+	fmt.Printf("Removed headers:  %d\n", result.RemovedHeaders)
+	fmt.Printf("Removed ancients: %d\n", result.RemovedAncients)
+	return nil
+}
+
+func badBlock(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	if len(ctx.Args()) < 1 {
+		for _, bad := range rawdb.ReadAllBadBlocks(chainDb) {
+			fmt.Printf("%d %x time=%v peer=%q reason=%q\n", bad.Block.NumberU64(), bad.Block.Hash(),
+				time.Unix(int64(bad.Time), 0), bad.Peer, bad.Reason)
+		}
+		return nil
+	}
+	hash := common.HexToHash(ctx.Args().First())
+	bad := rawdb.ReadBadBlock(chainDb, hash)
+	if bad == nil {
+		utils.Fatalf("Bad block %x not found", hash)
+	}
+	block := bad.Block
+	fmt.Printf("Block:    %d %x\n", block.NumberU64(), block.Hash())
+	fmt.Printf("Rejected: %v (%s)\n", time.Unix(int64(bad.Time), 0), bad.Reason)
+
+	// Re-run the same validation steps as the import did, tracing the execution
+	if err := chain.Engine().VerifyHeader(chain, block.Header(), true); err != nil {
+		utils.Fatalf("Header verification failed: %v", err)
+	}
+	if err := chain.Validator().ValidateBody(block); err != nil {
+		utils.Fatalf("Body validation failed: %v", err)
+	}
+	parent := chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		utils.Fatalf("Parent %x not found", block.ParentHash())
+	}
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		utils.Fatalf("Parent state unavailable: %v", err)
+	}
+	config := vm.Config{Debug: true, Tracer: vm.NewJSONLogger(nil, os.Stderr)}
+	receipts, _, usedGas, err := chain.Processor().Process(block, statedb, config)
+	if err != nil {
+		utils.Fatalf("Block processing failed: %v", err)
+	}
+	fmt.Printf("State root:   expected %x, computed %x\n", block.Root(), statedb.IntermediateRoot(chain.Config().IsEIP158(block.Number())))
+	fmt.Printf("Receipt root: expected %x, computed %x\n", block.ReceiptHash(), types.DeriveSha(receipts))
+	fmt.Printf("Gas used:     expected %d, computed %d\n", block.GasUsed(), usedGas)
+
+	if err := chain.Validator().ValidateState(block, statedb, receipts, usedGas); err != nil {
+		fmt.Printf("State validation failed: %v\n", err)
+		return nil
+	}
+	fmt.Println("Block is valid with the current rules")
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -83,6 +83,10 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolSnapshotFlag,
+		utils.TxPoolAllowedSendersFlag,
+		utils.TxPoolDeniedRecipientsFlag,
+		utils.TxPoolSenderGasFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -90,6 +94,7 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrivateLifetimeFlag,
 		utils.ULCModeConfigFlag,
 		utils.OnlyAnnounceModeFlag,
 		utils.ULCTrustedNodesFlag,
@@ -97,12 +102,18 @@ var (
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
+		utils.TraceIndexFlag,
 		utils.LightServFlag,
 		utils.LightBandwidthInFlag,
 		utils.LightBandwidthOutFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
 		utils.WhitelistFlag,
+		utils.CheckpointSyncFlag,
+		utils.CheckpointSignaturesFlag,
+		utils.ServeRequestRateFlag,
+		utils.ServePeerBandwidthFlag,
+		utils.ServeTotalBandwidthFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
@@ -126,6 +137,7 @@ var (
 		utils.MinerLegacyExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerOrderingFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -144,6 +156,8 @@ var (
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoMempoolFlag,
+		utils.GpoMempoolBlocksFlag,
 		utils.EWASMInterpreterFlag,
 		utils.EVMInterpreterFlag,
 		configFileFlag,
@@ -170,6 +184,14 @@ var (
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCap,
+		utils.RPCGlobalLogsCap,
+		utils.RPCSafeDepthFlag,
+		utils.RPCTraceTimeoutFlag,
+		utils.RPCTraceTimeoutCapFlag,
+		utils.RPCTraceResultSizeFlag,
+		utils.RPCTraceResultSizeCapFlag,
+		utils.RPCTraceHeapSizeFlag,
+		utils.RPCTraceHeapSizeCapFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -207,6 +229,8 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		inspectCommand,
+		rewindCommand,
+		badBlockCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
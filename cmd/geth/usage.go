@@ -80,6 +80,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.SyncModeFlag,
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
+			utils.TraceIndexFlag,
 			utils.btpStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
@@ -88,6 +89,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
+			utils.CheckpointSyncFlag,
+			utils.CheckpointSignaturesFlag,
+			utils.ServeRequestRateFlag,
+			utils.ServePeerBandwidthFlag,
+			utils.ServeTotalBandwidthFlag,
 		},
 	},
 	{
@@ -125,6 +131,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolSnapshotFlag,
+			utils.TxPoolAllowedSendersFlag,
+			utils.TxPoolDeniedRecipientsFlag,
+			utils.TxPoolSenderGasFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -132,6 +142,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolPrivateLifetimeFlag,
 		},
 	},
 	{
@@ -163,6 +174,14 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
 			utils.RPCGlobalGasCap,
+			utils.RPCGlobalLogsCap,
+			utils.RPCSafeDepthFlag,
+			utils.RPCTraceTimeoutFlag,
+			utils.RPCTraceTimeoutCapFlag,
+			utils.RPCTraceResultSizeFlag,
+			utils.RPCTraceResultSizeCapFlag,
+			utils.RPCTraceHeapSizeFlag,
+			utils.RPCTraceHeapSizeCapFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
 			utils.WSEnabledFlag,
@@ -210,6 +229,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerOrderingFlag,
 		},
 	},
 	{
@@ -217,6 +237,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoMempoolFlag,
+			utils.GpoMempoolBlocksFlag,
 		},
 	},
 	{
@@ -34,6 +34,7 @@ import (
 	"github.com/btpereum/go-btpereum/accounts/keystore"
 	"github.com/btpereum/go-btpereum/common"
 	"github.com/btpereum/go-btpereum/common/fdlimit"
+	"github.com/btpereum/go-btpereum/common/hexutil"
 	"github.com/btpereum/go-btpereum/consensus"
 	"github.com/btpereum/go-btpereum/consensus/clique"
 	"github.com/btpereum/go-btpereum/consensus/btpash"
@@ -211,7 +212,7 @@ var (
 	defaultSyncMode = btp.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "header")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -219,6 +220,10 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	TraceIndexFlag = cli.BoolFlag{
+		Name:  "traceindex",
+		Usage: "Trace all canonical blocks and index the internal transactions by account",
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (multi-threaded processing allows values over 100)",
@@ -247,6 +252,29 @@ var (
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>)",
 	}
+	CheckpointSyncFlag = cli.BoolFlag{
+		Name:  "checkpoint.sync",
+		Usage: "Anchor the chain sync to a checkpoint approved by the checkpoint oracle",
+	}
+	CheckpointSignaturesFlag = cli.StringFlag{
+		Name:  "checkpoint.signatures",
+		Usage: "Comma separated oracle signer signatures of the configured checkpoint (hex)",
+	}
+	ServeRequestRateFlag = cli.IntFlag{
+		Name:  "serve.requests",
+		Usage: "Maximum number of data requests served per second per peer (0 = unlimited)",
+		Value: 0,
+	}
+	ServePeerBandwidthFlag = cli.IntFlag{
+		Name:  "serve.peerbw",
+		Usage: "Outgoing bandwidth limit for serving data requests per peer (kilobytes/sec, 0 = unlimited)",
+		Value: 0,
+	}
+	ServeTotalBandwidthFlag = cli.IntFlag{
+		Name:  "serve.totalbw",
+		Usage: "Outgoing bandwidth limit for serving data requests to all peers (kilobytes/sec, 0 = unlimited)",
+		Value: 0,
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  "dashboard",
@@ -316,6 +344,27 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolSnapshotFlag = cli.StringFlag{
+		Name:  "txpool.snapshot",
+		Usage: "File to dump the whole transaction pool into on shutdown and restore it from on startup",
+	}
+	TxPoolPrivateLifetimeFlag = cli.Uint64Flag{
+		Name:  "txpool.privatelifetime",
+		Usage: "Number of blocks private transactions are kept for inclusion before being dropped",
+		Value: core.DefaultTxPoolConfig.PrivateLifetime,
+	}
+	TxPoolAllowedSendersFlag = cli.StringFlag{
+		Name:  "txpool.allowedsenders",
+		Usage: "Comma separated accounts whose transactions are exclusively accepted",
+	}
+	TxPoolDeniedRecipientsFlag = cli.StringFlag{
+		Name:  "txpool.deniedrecipients",
+		Usage: "Comma separated accounts to reject transactions to",
+	}
+	TxPoolSenderGasFlag = cli.Uint64Flag{
+		Name:  "txpool.sendergas",
+		Usage: "Maximum total gas of the pooled transactions of a single sender (0 = unlimited)",
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -447,6 +496,11 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerOrderingFlag = cli.StringFlag{
+		Name:  "miner.ordering",
+		Usage: `Transaction ordering within mined blocks ("price", "fifo" or "roundrobin")`,
+		Value: "price",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -475,6 +529,46 @@ var (
 		Name:  "rpc.gascap",
 		Usage: "Sets a cap on gas that can be used in btp_call/estimateGas",
 	}
+	RPCGlobalLogsCap = cli.IntFlag{
+		Name:  "rpc.logscap",
+		Usage: "Sets a cap on the number of logs returned by a single log query (0 = no cap)",
+		Value: btp.DefaultConfig.RPCLogsCap,
+	}
+	RPCSafeDepthFlag = cli.Uint64Flag{
+		Name:  "rpc.safedepth",
+		Usage: "Number of confirmations a block needs to be reported as safe",
+		Value: btp.DefaultConfig.RPCSafeDepth,
+	}
+	RPCTraceTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.tracetimeout",
+		Usage: "Default total time allowance of block and chain traces (0 = unlimited)",
+		Value: btp.DefaultConfig.RPCTraceTimeout,
+	}
+	RPCTraceTimeoutCapFlag = cli.DurationFlag{
+		Name:  "rpc.tracetimeoutcap",
+		Usage: "Maximum total time allowance of block and chain traces (0 = no cap)",
+		Value: btp.DefaultConfig.RPCTraceTimeoutCap,
+	}
+	RPCTraceResultSizeFlag = cli.Uint64Flag{
+		Name:  "rpc.traceresultsize",
+		Usage: "Default total result size in bytes of block and chain traces (0 = unlimited)",
+		Value: btp.DefaultConfig.RPCTraceResultSize,
+	}
+	RPCTraceResultSizeCapFlag = cli.Uint64Flag{
+		Name:  "rpc.traceresultsizecap",
+		Usage: "Maximum total result size in bytes of block and chain traces (0 = no cap)",
+		Value: btp.DefaultConfig.RPCTraceResultSizeCap,
+	}
+	RPCTraceHeapSizeFlag = cli.Uint64Flag{
+		Name:  "rpc.traceheapsize",
+		Usage: "Default total JavaScript tracer heap size in bytes of block and chain traces (0 = unlimited)",
+		Value: btp.DefaultConfig.RPCTraceHeapSize,
+	}
+	RPCTraceHeapSizeCapFlag = cli.Uint64Flag{
+		Name:  "rpc.traceheapsizecap",
+		Usage: "Maximum total JavaScript tracer heap size in bytes of block and chain traces (0 = no cap)",
+		Value: btp.DefaultConfig.RPCTraceHeapSizeCap,
+	}
 	// Logging and debug settings
 	btpStatsURLFlag = cli.StringFlag{
 		Name:  "btpstats",
@@ -658,6 +752,15 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: btp.DefaultConfig.GPO.Percentile,
 	}
+	GpoMempoolFlag = cli.BoolFlag{
+		Name:  "gpomempool",
+		Usage: "Account for the pending transaction pool in gas price suggestions",
+	}
+	GpoMempoolBlocksFlag = cli.IntFlag{
+		Name:  "gpomempoolblocks",
+		Usage: "Number of upcoming blocks to spread the pending transaction pool across for slow inclusion",
+		Value: btp.DefaultConfig.GPO.MempoolBlocks,
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -1207,6 +1310,25 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoMempoolFlag.Name) {
+		cfg.Mempool = ctx.GlobalBool(GpoMempoolFlag.Name)
+	}
+	if ctx.GlobalIsSet(GpoMempoolBlocksFlag.Name) {
+		cfg.MempoolBlocks = ctx.GlobalInt(GpoMempoolBlocksFlag.Name)
+	}
+}
+
+// splitAccounts parses a comma separated list of accounts from the given flag.
+func splitAccounts(ctx *cli.Context, name string) []common.Address {
+	var accounts []common.Address
+	for _, account := range strings.Split(ctx.GlobalString(name), ",") {
+		trimmed := strings.TrimSpace(account)
+		if !common.IsHexAddress(trimmed) {
+			Fatalf("Invalid account in --%s: %s", name, trimmed)
+		}
+		accounts = append(accounts, common.HexToAddress(trimmed))
+	}
+	return accounts
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -1229,6 +1351,21 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalString(TxPoolSnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPrivateLifetimeFlag.Name) {
+		cfg.PrivateLifetime = ctx.GlobalUint64(TxPoolPrivateLifetimeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolAllowedSendersFlag.Name) {
+		cfg.AllowedSenders = splitAccounts(ctx, TxPoolAllowedSendersFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolDeniedRecipientsFlag.Name) {
+		cfg.DeniedRecipients = splitAccounts(ctx, TxPoolDeniedRecipientsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSenderGasFlag.Name) {
+		cfg.SenderGasBudget = ctx.GlobalUint64(TxPoolSenderGasFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1304,6 +1441,13 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.Noverify = ctx.Bool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerOrderingFlag.Name) {
+		ordering, err := miner.NewOrderingStrategy(ctx.GlobalString(MinerOrderingFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", MinerOrderingFlag.Name, err)
+		}
+		cfg.Ordering = ordering
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *btp.Config) {
@@ -1329,6 +1473,21 @@ func setWhitelist(ctx *cli.Context, cfg *btp.Config) {
 	}
 }
 
+func setCheckpointSignatures(ctx *cli.Context, cfg *btp.Config) {
+	sigs := ctx.GlobalString(CheckpointSignaturesFlag.Name)
+	if sigs == "" {
+		return
+	}
+	cfg.CheckpointSignatures = cfg.CheckpointSignatures[:0]
+	for _, entry := range strings.Split(sigs, ",") {
+		sig, err := hexutil.Decode(strings.TrimSpace(entry))
+		if err != nil {
+			Fatalf("Invalid checkpoint signature %s: %v", entry, err)
+		}
+		cfg.CheckpointSignatures = append(cfg.CheckpointSignatures, sig)
+	}
+}
+
 // CheckExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1400,6 +1559,7 @@ func SetbtpConfig(ctx *cli.Context, stack *node.Node, cfg *btp.Config) {
 	setbtpash(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
 	setWhitelist(ctx, cfg)
+	setCheckpointSignatures(ctx, cfg)
 
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
@@ -1415,6 +1575,18 @@ func SetbtpConfig(ctx *cli.Context, stack *node.Node, cfg *btp.Config) {
 	if ctx.GlobalIsSet(OnlyAnnounceModeFlag.Name) {
 		cfg.OnlyAnnounce = ctx.GlobalBool(OnlyAnnounceModeFlag.Name)
 	}
+	if ctx.GlobalIsSet(CheckpointSyncFlag.Name) {
+		cfg.CheckpointSync = ctx.GlobalBool(CheckpointSyncFlag.Name)
+	}
+	if ctx.GlobalIsSet(ServeRequestRateFlag.Name) {
+		cfg.ServeRequestRate = ctx.GlobalInt(ServeRequestRateFlag.Name)
+	}
+	if ctx.GlobalIsSet(ServePeerBandwidthFlag.Name) {
+		cfg.ServePeerBandwidth = ctx.GlobalInt(ServePeerBandwidthFlag.Name) * 1024
+	}
+	if ctx.GlobalIsSet(ServeTotalBandwidthFlag.Name) {
+		cfg.ServeTotalBandwidth = ctx.GlobalInt(ServeTotalBandwidthFlag.Name) * 1024
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1431,6 +1603,7 @@ func SetbtpConfig(ctx *cli.Context, stack *node.Node, cfg *btp.Config) {
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 	cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
+	cfg.TraceIndex = ctx.GlobalBool(TraceIndexFlag.Name)
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
@@ -1456,6 +1629,30 @@ func SetbtpConfig(ctx *cli.Context, stack *node.Node, cfg *btp.Config) {
 	if ctx.GlobalIsSet(RPCGlobalGasCap.Name) {
 		cfg.RPCGasCap = new(big.Int).SetUint64(ctx.GlobalUint64(RPCGlobalGasCap.Name))
 	}
+	if ctx.GlobalIsSet(RPCGlobalLogsCap.Name) {
+		cfg.RPCLogsCap = ctx.GlobalInt(RPCGlobalLogsCap.Name)
+	}
+	if ctx.GlobalIsSet(RPCSafeDepthFlag.Name) {
+		cfg.RPCSafeDepth = ctx.GlobalUint64(RPCSafeDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTraceTimeoutFlag.Name) {
+		cfg.RPCTraceTimeout = ctx.GlobalDuration(RPCTraceTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTraceTimeoutCapFlag.Name) {
+		cfg.RPCTraceTimeoutCap = ctx.GlobalDuration(RPCTraceTimeoutCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTraceResultSizeFlag.Name) {
+		cfg.RPCTraceResultSize = ctx.GlobalUint64(RPCTraceResultSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTraceResultSizeCapFlag.Name) {
+		cfg.RPCTraceResultSizeCap = ctx.GlobalUint64(RPCTraceResultSizeCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTraceHeapSizeFlag.Name) {
+		cfg.RPCTraceHeapSize = ctx.GlobalUint64(RPCTraceHeapSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTraceHeapSizeCapFlag.Name) {
+		cfg.RPCTraceHeapSizeCap = ctx.GlobalUint64(RPCTraceHeapSizeCapFlag.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -18,6 +18,7 @@ package clique
 
 import (
 	"math/big"
+	"sort"
 	"testing"
 
 	"github.com/btpereum/go-btpereum/common"
@@ -111,3 +112,76 @@ func TestReimportMirroredState(t *testing.T) {
 		t.Fatalf("chain head mismatch: have %d, want %d", head, 3)
 	}
 }
+
+// Tests that the finalized header is the most recent one built upon by a majority
+// of the authorized signers.
+func TestFinalizedHeader(t *testing.T) {
+	// Create a chain with three signers taking turns in sealing blocks
+	accounts := newTesterAccountPool()
+
+	names := []string{"A", "B", "C"}
+	signers := make([]common.Address, len(names))
+	for i, name := range names {
+		signers[i] = accounts.address(name)
+	}
+	sort.Sort(signersAscending(signers))
+
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(signers)+extraSeal),
+	}
+	for i, signer := range signers {
+		copy(genesis.ExtraData[extraVanity+i*common.AddressLength:], signer[:])
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+	engine.fakeDiff = true
+
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, 5, nil)
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffInTurn
+
+		accounts.sign(header, names[i%len(names)])
+		blocks[i] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	// A block is final once two distinct signers out of three (a majority) have
+	// sealed blocks on top of it, the block completing the majority excluded
+	tests := []struct {
+		head  int    // Index of the head block to finalize from
+		final uint64 // Number of the expected finalized block
+	}{
+		{0, 0}, // Single signer on top of genesis, only genesis is final
+		{1, 0}, // Majority reached at #1, its parent (genesis) is final
+		{2, 1}, // Majority reached at #2, #1 is final
+		{4, 3}, // Majority reached at #4, #3 is final
+	}
+	for i, tt := range tests {
+		final, err := engine.FinalizedHeader(chain, blocks[tt.head].Header())
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve finalized header: %v", i, err)
+		}
+		if final.Number.Uint64() != tt.final {
+			t.Errorf("test %d: finalized header mismatch: have #%d, want #%d", i, final.Number, tt.final)
+		}
+		if tt.final > 0 && final.Hash() != blocks[tt.final-1].Hash() {
+			t.Errorf("test %d: finalized hash mismatch: have %x, want %x", i, final.Hash(), blocks[tt.final-1].Hash())
+		}
+	}
+}
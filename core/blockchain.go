@@ -77,7 +77,6 @@ const (
 	receiptsCacheLimit  = 32
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
-	badBlockLimit       = 10
 	TriesInMemory       = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
@@ -142,6 +141,7 @@ type BlockChain struct {
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
 	blockProcFeed event.Feed
+	reorgFeed     event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -169,7 +169,6 @@ type BlockChain struct {
 	processor  Processor  // Block transaction processor interface
 	vmConfig   vm.Config
 
-	badBlocks       *lru.Cache                     // Bad block cache
 	shouldPreserve  func(*types.Block) bool        // Function used to determine whbtper should preserve the given block.
 	terminateInsert func(common.Hash, uint64) bool // Testing hook used to terminate ancient receipt chain insertion.
 }
@@ -190,7 +189,6 @@ func NewBlockChain(db btpdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	receiptsCache, _ := lru.New(receiptsCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
-	badBlocks, _ := lru.New(badBlockLimit)
 
 	bc := &BlockChain{
 		chainConfig:    chainConfig,
@@ -207,7 +205,6 @@ func NewBlockChain(db btpdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		futureBlocks:   futureBlocks,
 		engine:         engine,
 		vmConfig:       vmConfig,
-		badBlocks:      badBlocks,
 	}
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc, engine)
@@ -376,11 +373,16 @@ func (bc *BlockChain) loadLastState() error {
 // though, the head may be further rewound if block bodies are missing (non-archive
 // nodes after a fast sync).
 func (bc *BlockChain) Sbtpead(head uint64) error {
-	log.Warn("Rewinding blockchain", "target", head)
-
 	bc.chainmu.Lock()
 	defer bc.chainmu.Unlock()
 
+	return bc.sbtpead(head)
+}
+
+// sbtpead rewinds the local chain to a new head, assuming the chain mutex is held.
+func (bc *BlockChain) sbtpead(head uint64) error {
+	log.Warn("Rewinding blockchain", "target", head)
+
 	updateFn := func(db btpdb.KeyValueWriter, header *types.Header) {
 		// Rewind the block chain, ensuring we don't end up with a stateless head block
 		if currentBlock := bc.CurrentBlock(); currentBlock != nil && header.Number.Uint64() < currentBlock.NumberU64() {
@@ -418,7 +420,7 @@ func (bc *BlockChain) Sbtpead(head uint64) error {
 		if num+1 <= frozen {
 			// Truncate all relative data(header, total difficulty, body, receipt
 			// and canonical hash) from ancient store.
-			if err := bc.db.TruncateAncients(num + 1); err != nil {
+			if err := bc.db.TruncateAncients(num); err != nil {
 				log.Crit("Failed to truncate ancient data", "number", num, "err", err)
 			}
 
@@ -445,6 +447,82 @@ func (bc *BlockChain) Sbtpead(head uint64) error {
 	return bc.loadLastState()
 }
 
+// DefaultRewindLimit is the maximum number of headers a Rewind is expected to
+// remove unless explicitly forced, guarding against mistyped block numbers.
+const DefaultRewindLimit = 10000
+
+// RewindResult reports the chain data removed by a Rewind.
+type RewindResult struct {
+	Head *types.Block // New head header, fast block and full block
+
+	OldHeader    uint64 // Head header number before the rewind
+	OldFastBlock uint64 // Head fast block number before the rewind
+	OldBlock     uint64 // Head full block number before the rewind
+
+	RemovedHeaders  uint64 // Number of headers removed from the chain
+	RemovedAncients uint64 // Number of items truncated from the ancient store
+}
+
+// Rewind is a safer variant of Sbtpead meant for operators rewinding past a bad
+// fork. Instead of leaving a stateless head block behind, it rewinds to the nearest
+// canonical block at or below the target whose state is available, moving the
+// header, fast and full heads to it together and truncating the ancient store if
+// the target is below the freezer boundary.
+//
+// If limit is non-zero, the rewind is refused if it would remove more headers than
+// that, including those removed to reach a block with state.
+func (bc *BlockChain) Rewind(target uint64, limit uint64) (*RewindResult, error) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	current := bc.CurrentHeader()
+	if target >= current.Number.Uint64() {
+		return nil, fmt.Errorf("rewind target %d not below current head %d", target, current.Number)
+	}
+	// Find the nearest block with state to avoid ending up with a stateless head,
+	// giving up as soon as the limit is crossed to avoid scanning under the lock
+	var head *types.Block
+	for number := target; head == nil; number-- {
+		if depth := current.Number.Uint64() - number; limit > 0 && depth > limit {
+			return nil, fmt.Errorf("rewind to %d would remove at least %d headers, above the limit of %d", number, depth, limit)
+		}
+		if block := bc.GetBlockByNumber(number); block != nil && bc.HasState(block.Root()) {
+			head = block
+		} else if number == 0 {
+			return nil, fmt.Errorf("no block with available state at or below %d", target)
+		}
+	}
+	frozen, _ := bc.db.Ancients()
+	result := &RewindResult{
+		OldHeader:    current.Number.Uint64(),
+		OldFastBlock: bc.CurrentFastBlock().NumberU64(),
+		OldBlock:     bc.CurrentBlock().NumberU64(),
+	}
+	if head.NumberU64() != target {
+		log.Warn("Rewind target state unavailable", "target", target, "stateful", head.NumberU64())
+	}
+	if err := bc.sbtpead(head.NumberU64()); err != nil {
+		return nil, err
+	}
+	// Make sure none of the heads were left dangling above the new head
+	if hash := bc.CurrentHeader().Hash(); hash != head.Hash() {
+		return nil, fmt.Errorf("head header rewound to %x instead of %x", hash, head.Hash())
+	}
+	if number := bc.CurrentFastBlock().NumberU64(); number > head.NumberU64() {
+		return nil, fmt.Errorf("head fast block %d left above new head %d", number, head.NumberU64())
+	}
+	if number := bc.CurrentBlock().NumberU64(); number > head.NumberU64() {
+		return nil, fmt.Errorf("head block %d left above new head %d", number, head.NumberU64())
+	}
+	result.Head = head
+	result.RemovedHeaders = result.OldHeader - head.NumberU64()
+	if remaining, _ := bc.db.Ancients(); remaining < frozen {
+		result.RemovedAncients = frozen - remaining
+	}
+	log.Info("Rewound blockchain", "number", head.Number(), "hash", head.Hash(), "headers", result.RemovedHeaders, "ancients", result.RemovedAncients)
+	return result, nil
+}
+
 // FastSyncCommitHead sets the current head block to the one defined by the hash
 // irrelevant what the chain contents were prior.
 func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
@@ -1542,7 +1620,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 	// Some other error occurred, abort
 	case err != nil:
 		stats.ignored += len(it.chain)
-		bc.reportBlock(block, nil, err)
+		bc.reportBlock(block, nil, common.Hash{}, err)
 		return it.index, events, coalescedLogs, err
 	}
 	// No validation errors for the first block (or chain prefix skipped)
@@ -1554,7 +1632,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		}
 		// If the header is a banned one, straight out abort
 		if BadHashes[block.Hash()] {
-			bc.reportBlock(block, nil, ErrBlacklistedHash)
+			bc.reportBlock(block, nil, common.Hash{}, ErrBlacklistedHash)
 			return it.index, events, coalescedLogs, ErrBlacklistedHash
 		}
 		// If the block is known (in the middle of the chain), it's a special case for
@@ -1615,7 +1693,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		substart := time.Now()
 		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
 		if err != nil {
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, common.Hash{}, err)
 			atomic.StoreUint32(&followupInterrupt, 1)
 			return it.index, events, coalescedLogs, err
 		}
@@ -1634,7 +1712,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		// Validate the state using the default validator
 		substart = time.Now()
 		if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, statedb.IntermediateRoot(bc.chainConfig.IsEIP158(block.Number())), err)
 			atomic.StoreUint32(&followupInterrupt, 1)
 			return it.index, events, coalescedLogs, err
 		}
@@ -1960,6 +2038,33 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		rawdb.DeleteCanonicalHash(batch, i)
 	}
 	batch.Write()
+
+	// Summarise the reorg for subscribers and keep a record of it for later analysis
+	var reorg *ReorgEvent
+	if len(oldChain) > 0 && len(newChain) > 0 {
+		reorg = &ReorgEvent{
+			Ancestor:   commonBlock.Header(),
+			Depth:      uint64(len(oldChain)),
+			DroppedTxs: types.TxDifference(deletedTxs, append(addedTxs, newChain[0].Transactions()...)),
+		}
+		for i := len(oldChain) - 1; i >= 0; i-- {
+			reorg.Dropped = append(reorg.Dropped, oldChain[i].Hash())
+		}
+		for i := len(newChain) - 1; i >= 0; i-- {
+			reorg.Added = append(reorg.Added, newChain[i].Hash())
+		}
+		record := &rawdb.ReorgRecord{
+			Time:           uint64(time.Now().Unix()),
+			AncestorHash:   commonBlock.Hash(),
+			AncestorNumber: commonBlock.NumberU64(),
+			Dropped:        reorg.Dropped,
+			Added:          reorg.Added,
+		}
+		for _, tx := range reorg.DroppedTxs {
+			record.DroppedTxs = append(record.DroppedTxs, tx.Hash())
+		}
+		rawdb.WriteReorg(bc.db, record)
+	}
 	// If any logs need to be fired, do it now. In theory we could avoid creating
 	// this goroutine if there are no events to fire, but realistcally that only
 	// ever happens if we're reorging empty blocks, which will only happen on idle
@@ -1979,6 +2084,9 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 				bc.chainSideFeed.Send(ChainSideEvent{Block: block})
 			}
 		}
+		if reorg != nil {
+			bc.reorgFeed.Send(*reorg)
+		}
 	}()
 	return nil
 }
@@ -2018,26 +2126,38 @@ func (bc *BlockChain) update() {
 	}
 }
 
-// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
-func (bc *BlockChain) BadBlocks() []*types.Block {
-	blocks := make([]*types.Block, 0, bc.badBlocks.Len())
-	for _, hash := range bc.badBlocks.Keys() {
-		if blk, exist := bc.badBlocks.Peek(hash); exist {
-			block := blk.(*types.Block)
-			blocks = append(blocks, block)
-		}
-	}
-	return blocks
+// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the
+// network, along with the diagnostics gathered while rejecting them.
+func (bc *BlockChain) BadBlocks() []*rawdb.BadBlock {
+	return rawdb.ReadAllBadBlocks(bc.db)
 }
 
-// addBadBlock adds a bad block to the bad-block LRU cache
-func (bc *BlockChain) addBadBlock(block *types.Block) {
-	bc.badBlocks.Add(block.Hash(), block)
+// addBadBlock persists a bad block along with the diagnostics gathered while
+// importing it. The root is the state root computed by processing the block, or
+// zero if processing didn't get that far.
+func (bc *BlockChain) addBadBlock(block *types.Block, receipts types.Receipts, root common.Hash, err error) {
+	bad := &rawdb.BadBlock{
+		Block:     block,
+		Reason:    err.Error(),
+		Receipts:  receipts,
+		StateRoot: root,
+		Time:      uint64(time.Now().Unix()),
+	}
+	if receipts != nil {
+		bad.ReceiptRoot = types.DeriveSha(receipts)
+	}
+	switch peer := block.ReceivedFrom.(type) {
+	case fmt.Stringer:
+		bad.Peer = peer.String()
+	case string:
+		bad.Peer = peer
+	}
+	rawdb.WriteBadBlock(bc.db, bad)
 }
 
-// reportBlock logs a bad block error.
-func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
-	bc.addBadBlock(block)
+// reportBlock logs a bad block error and persists the block for later analysis.
+func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, root common.Hash, err error) {
+	bc.addBadBlock(block, receipts, root, err)
 
 	var receiptString string
 	for i, receipt := range receipts {
@@ -2051,11 +2171,14 @@ Chain config: %v
 
 Number: %v
 Hash: 0x%x
+Peer: %v
+Expected root: 0x%x
+Computed root: 0x%x
 %v
 
 Error: %v
 ##############################
-`, bc.chainConfig, block.Number(), block.Hash(), receiptString, err))
+`, bc.chainConfig, block.Number(), block.Hash(), block.ReceivedFrom, block.Root(), root, receiptString, err))
 }
 
 // InsertHeaderChain attempts to insert the given header chain in to the local
@@ -2182,3 +2305,8 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 func (bc *BlockChain) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscription {
 	return bc.scope.Track(bc.blockProcFeed.Subscribe(ch))
 }
+
+// SubscribeReorgEvent registers a subscription of ReorgEvent.
+func (bc *BlockChain) SubscribeReorgEvent(ch chan<- ReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -149,12 +150,12 @@ func testBlockChainImport(chain types.Blocks, blockchain *BlockChain) error {
 		}
 		receipts, _, usedGas, err := blockchain.processor.Process(block, statedb, vm.Config{})
 		if err != nil {
-			blockchain.reportBlock(block, receipts, err)
+			blockchain.reportBlock(block, receipts, common.Hash{}, err)
 			return err
 		}
 		err = blockchain.validator.ValidateState(block, statedb, receipts, usedGas)
 		if err != nil {
-			blockchain.reportBlock(block, receipts, err)
+			blockchain.reportBlock(block, receipts, common.Hash{}, err)
 			return err
 		}
 		blockchain.chainmu.Lock()
@@ -813,6 +814,97 @@ func TestLightVsFastVsFullChainHeads(t *testing.T) {
 	assert(t, "light", light, height/2, 0, 0)
 }
 
+// Tests that rewinding the chain moves all head pointers to the nearest block
+// with available state and truncates the ancient store if needed.
+func TestRewind(t *testing.T) {
+	// Configure and generate a sample block chain
+	var (
+		gendb   = rawdb.NewMemoryDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(gendb)
+	)
+	height := uint64(256)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, btpash.NewFaker(), gendb, int(height), nil)
+
+	// Import the chain as an archive node and rewind into the stateful range
+	archiveDb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(archiveDb)
+	archive, _ := NewBlockChain(archiveDb, nil, gspec.Config, btpash.NewFaker(), vm.Config{}, nil)
+	defer archive.Stop()
+
+	if n, err := archive.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to process block %d: %v", n, err)
+	}
+	if _, err := archive.Rewind(height, 0); err == nil {
+		t.Fatalf("rewind to the current head succeeded")
+	}
+	if _, err := archive.Rewind(height-10, 9); err == nil {
+		t.Fatalf("rewind above the limit succeeded")
+	}
+	if archive.CurrentHeader().Number.Uint64() != height || archive.CurrentBlock().NumberU64() != height {
+		t.Fatalf("refused rewind modified the heads")
+	}
+	result, err := archive.Rewind(height-10, 10)
+	if err != nil {
+		t.Fatalf("failed to rewind archive chain: %v", err)
+	}
+	if result.Head.NumberU64() != height-10 || result.RemovedHeaders != 10 {
+		t.Fatalf("archive rewind mismatch: have #%d (-%d), want #%d (-%d)", result.Head.NumberU64(), result.RemovedHeaders, height-10, 10)
+	}
+	if archive.CurrentHeader().Number.Uint64() != height-10 || archive.CurrentFastBlock().NumberU64() != height-10 || archive.CurrentBlock().NumberU64() != height-10 {
+		t.Fatalf("archive heads not rewound consistently")
+	}
+	// Import the chain as an ancient-first node and rewind below the freezer boundary
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ancientDb, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), dir, "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	gspec.MustCommit(ancientDb)
+	ancient, _ := NewBlockChain(ancientDb, nil, gspec.Config, btpash.NewFaker(), vm.Config{}, nil)
+	defer ancient.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := ancient.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := ancient.InsertReceiptChain(blocks, receipts, height/2); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+	frozen, _ := ancientDb.Ancients()
+
+	// No state is available besides genesis, so the rewind should go all the way,
+	// which must also be accounted for in the limit
+	if _, err := ancient.Rewind(height/4, height-1); err == nil {
+		t.Fatalf("rewind past the limit to a stateful block succeeded")
+	}
+	// The search for state must stop right past the limit, not at genesis
+	want := fmt.Sprintf("rewind to %d would remove at least %d headers", height/2-1, height/2+1)
+	if _, err := ancient.Rewind(height-10, height/2); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("limited state search error mismatch: have %v, want %q", err, want)
+	}
+	result, err = ancient.Rewind(height/4, 0)
+	if err != nil {
+		t.Fatalf("failed to rewind ancient chain: %v", err)
+	}
+	if result.Head.NumberU64() != 0 || result.RemovedHeaders != height {
+		t.Fatalf("ancient rewind mismatch: have #%d (-%d), want #%d (-%d)", result.Head.NumberU64(), result.RemovedHeaders, 0, height)
+	}
+	if remaining, err := ancientDb.Ancients(); err != nil || remaining != 1 {
+		t.Fatalf("failed to truncate ancient store, want %v, have %v", 1, remaining)
+	}
+	if result.RemovedAncients != frozen-1 {
+		t.Fatalf("removed ancients mismatch: have %d, want %d", result.RemovedAncients, frozen-1)
+	}
+}
+
 // Tests that chain reorganisations handle transaction removals and reinsertions.
 func TestChainTxReorgs(t *testing.T) {
 	var (
@@ -1265,6 +1357,88 @@ done:
 
 }
 
+// Tests that a reorg posts a summary event and records it in the reorg history.
+func TestReorgEvent(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{addr1: {Balance: big.NewInt(10000000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, btpash.NewFaker(), vm.Config{}, nil)
+	defer blockchain.Stop()
+
+	// Create an original chain with a transaction in each block, and a longer
+	// replacement which only re-includes the first one
+	newTx := func(gen *BlockGen) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(addr1), common.Address{0x01}, big.NewInt(1), params.TxGas, nil, nil), signer, key1)
+		if err != nil {
+			t.Fatalf("failed to create tx: %v", err)
+		}
+		return tx
+	}
+	chain, _ := GenerateChain(gspec.Config, genesis, btpash.NewFaker(), db, 3, func(i int, gen *BlockGen) {
+		gen.AddTx(newTx(gen))
+	})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	replacement, _ := GenerateChain(gspec.Config, genesis, btpash.NewFaker(), db, 4, func(i int, gen *BlockGen) {
+		if i == 0 {
+			gen.AddTx(newTx(gen))
+		}
+	})
+	reorgCh := make(chan ReorgEvent, 8)
+	sub := blockchain.SubscribeReorgEvent(reorgCh)
+	defer sub.Unsubscribe()
+
+	if _, err := blockchain.InsertChain(replacement); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var ev ReorgEvent
+	select {
+	case ev = <-reorgCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for reorg event")
+	}
+	if ev.Ancestor.Hash() != genesis.Hash() || ev.Depth != 3 {
+		t.Fatalf("reorg mismatch: have ancestor %x depth %d, want %x depth %d", ev.Ancestor.Hash(), ev.Depth, genesis.Hash(), 3)
+	}
+	for i, hash := range ev.Dropped {
+		if hash != chain[i].Hash() {
+			t.Errorf("dropped block %d mismatch: have %x, want %x", i, hash, chain[i].Hash())
+		}
+	}
+	if len(ev.Added) < 3 {
+		t.Fatalf("added block count mismatch: have %d, want at least %d", len(ev.Added), 3)
+	}
+	for i, hash := range ev.Added {
+		if hash != replacement[i].Hash() {
+			t.Errorf("added block %d mismatch: have %x, want %x", i, hash, replacement[i].Hash())
+		}
+	}
+	dropped := make(map[common.Hash]bool)
+	for _, tx := range ev.DroppedTxs {
+		dropped[tx.Hash()] = true
+	}
+	if len(dropped) != 2 || !dropped[chain[1].Transactions()[0].Hash()] || !dropped[chain[2].Transactions()[0].Hash()] {
+		t.Fatalf("dropped transactions mismatch: have %v", ev.DroppedTxs)
+	}
+	// Make sure the reorg was also recorded in the history
+	history := rawdb.ReadReorgHistory(db)
+	if len(history) != 1 {
+		t.Fatalf("reorg history length mismatch: have %d, want %d", len(history), 1)
+	}
+	if history[0].AncestorHash != genesis.Hash() || len(history[0].Dropped) != 3 || len(history[0].DroppedTxs) != 2 {
+		t.Fatalf("reorg record mismatch: %+v", history[0])
+	}
+}
+
 // Tests if the canonical block can be fetched from the database during chain insertion.
 func TestCanonicalBlockRetrieval(t *testing.T) {
 	_, blockchain, err := newCanonical(btpash.NewFaker(), 0, true)
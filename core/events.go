@@ -24,6 +24,10 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxLifecycleEvent is posted when pooled transactions change state, e.g. get
+// queued, promoted, replaced, dropped or included.
+type TxLifecycleEvent struct{ Changes []TxChange }
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
@@ -46,3 +50,13 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ReorgEvent is posted when the canonical chain is reorganised, summarising the
+// blocks and transactions affected.
+type ReorgEvent struct {
+	Ancestor   *types.Header      // Common ancestor of the old and new chains
+	Dropped    []common.Hash      // Blocks dropped from the canonical chain, ascending
+	Added      []common.Hash      // Blocks added to the canonical chain, ascending
+	Depth      uint64             // Number of blocks dropped from the canonical chain
+	DroppedTxs types.Transactions // Dropped transactions not re-included by the new chain
+}
@@ -558,3 +558,117 @@ func FindCommonAncestor(db btpdb.Reader, a, b *types.Header) *types.Header {
 	}
 	return a
 }
+
+// badBlockToKeep is the maximum number of bad blocks retained in the database.
+const badBlockToKeep = 10
+
+// BadBlock is a block that failed validation, along with the diagnostics gathered
+// while importing it.
+type BadBlock struct {
+	Block       *types.Block   // Block that failed validation
+	Reason      string         // Validation error the block was rejected with
+	Receipts    types.Receipts // Receipts computed while processing the block, if it got that far
+	StateRoot   common.Hash    // State root computed by processing the block, zero if unavailable
+	ReceiptRoot common.Hash    // Receipt root computed by processing the block, zero if unavailable
+	Peer        string         // Peer the block was received from, empty if unknown
+	Time        uint64         // Unix timestamp the block was rejected at
+}
+
+// ReadBadBlock retrieves the bad block with the corresponding hash, or nil if it
+// wasn't found among the retained ones.
+func ReadBadBlock(db btpdb.KeyValueReader, hash common.Hash) *BadBlock {
+	for _, bad := range ReadAllBadBlocks(db) {
+		if bad.Block.Hash() == hash {
+			return bad
+		}
+	}
+	return nil
+}
+
+// ReadAllBadBlocks retrieves all the retained bad blocks, oldest first.
+func ReadAllBadBlocks(db btpdb.KeyValueReader) []*BadBlock {
+	data, _ := db.Get(badBlockKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var blocks []*BadBlock
+	if err := rlp.DecodeBytes(data, &blocks); err != nil {
+		log.Error("Invalid bad block list RLP", "err", err)
+		return nil
+	}
+	return blocks
+}
+
+// WriteBadBlock stores a bad block, replacing any previous entry of the same block
+// and dropping the oldest ones if more than badBlockToKeep are retained.
+func WriteBadBlock(db btpdb.KeyValueStore, bad *BadBlock) {
+	blocks := ReadAllBadBlocks(db)
+	for i, old := range blocks {
+		if old.Block.Hash() == bad.Block.Hash() {
+			blocks = append(blocks[:i], blocks[i+1:]...)
+			break
+		}
+	}
+	blocks = append(blocks, bad)
+	if len(blocks) > badBlockToKeep {
+		blocks = blocks[len(blocks)-badBlockToKeep:]
+	}
+	data, err := rlp.EncodeToBytes(blocks)
+	if err != nil {
+		log.Crit("Failed to encode bad blocks", "err", err)
+	}
+	if err := db.Put(badBlockKey, data); err != nil {
+		log.Crit("Failed to store bad blocks", "err", err)
+	}
+}
+
+// DeleteBadBlocks removes all the retained bad blocks.
+func DeleteBadBlocks(db btpdb.KeyValueWriter) {
+	if err := db.Delete(badBlockKey); err != nil {
+		log.Crit("Failed to delete bad blocks", "err", err)
+	}
+}
+
+// reorgsToKeep is the maximum number of chain reorganisations retained in the
+// database.
+const reorgsToKeep = 128
+
+// ReorgRecord is a summary of a chain reorganisation kept for later analysis.
+type ReorgRecord struct {
+	Time           uint64        // Unix timestamp the reorg happened at
+	AncestorHash   common.Hash   // Hash of the common ancestor of the two chains
+	AncestorNumber uint64        // Number of the common ancestor of the two chains
+	Dropped        []common.Hash // Hashes of the blocks dropped from the canonical chain, ascending
+	Added          []common.Hash // Hashes of the blocks added to the canonical chain, ascending
+	DroppedTxs     []common.Hash // Hashes of the dropped transactions not re-included by the new chain
+}
+
+// ReadReorgHistory retrieves the retained chain reorganisations, oldest first.
+func ReadReorgHistory(db btpdb.KeyValueReader) []*ReorgRecord {
+	data, _ := db.Get(reorgHistoryKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var reorgs []*ReorgRecord
+	if err := rlp.DecodeBytes(data, &reorgs); err != nil {
+		log.Error("Invalid reorg history RLP", "err", err)
+		return nil
+	}
+	return reorgs
+}
+
+// WriteReorg appends a chain reorganisation to the history, dropping the oldest
+// ones if more than reorgsToKeep are retained.
+func WriteReorg(db btpdb.KeyValueStore, reorg *ReorgRecord) {
+	reorgs := append(ReadReorgHistory(db), reorg)
+	if len(reorgs) > reorgsToKeep {
+		reorgs = reorgs[len(reorgs)-reorgsToKeep:]
+	}
+	data, err := rlp.EncodeToBytes(reorgs)
+	if err != nil {
+		log.Crit("Failed to encode reorg history", "err", err)
+	}
+	if err := db.Put(reorgHistoryKey, data); err != nil {
+		log.Crit("Failed to store reorg history", "err", err)
+	}
+}
@@ -358,3 +358,47 @@ func checkReceiptsRLP(have, want types.Receipts) error {
 	}
 	return nil
 }
+
+// Tests bad block storage, deduplication and retention limits.
+func TestBadBlockStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if blocks := ReadAllBadBlocks(db); len(blocks) != 0 {
+		t.Fatalf("Non existent bad blocks returned: %v", blocks)
+	}
+	// Store a bad block and make sure it's retrievable
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Extra: []byte("bad block")})
+	WriteBadBlock(db, &BadBlock{Block: block, Reason: "invalid", StateRoot: common.Hash{0x01}, Peer: "peer"})
+
+	bad := ReadBadBlock(db, block.Hash())
+	if bad == nil {
+		t.Fatalf("Stored bad block not found")
+	}
+	if bad.Reason != "invalid" || bad.StateRoot != (common.Hash{0x01}) || bad.Peer != "peer" {
+		t.Fatalf("Bad block diagnostics mismatch: %+v", bad)
+	}
+	// Store the same block again and make sure it replaces the old entry
+	WriteBadBlock(db, &BadBlock{Block: block, Reason: "invalid again"})
+	if blocks := ReadAllBadBlocks(db); len(blocks) != 1 || blocks[0].Reason != "invalid again" {
+		t.Fatalf("Bad block not deduplicated: %v", blocks)
+	}
+	// Overflow the retention limit and make sure the oldest ones are dropped
+	for i := 0; i < badBlockToKeep; i++ {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i + 2))})
+		WriteBadBlock(db, &BadBlock{Block: block, Reason: "invalid"})
+	}
+	blocks := ReadAllBadBlocks(db)
+	if len(blocks) != badBlockToKeep {
+		t.Fatalf("Bad block count mismatch: have %d, want %d", len(blocks), badBlockToKeep)
+	}
+	if ReadBadBlock(db, block.Hash()) != nil {
+		t.Fatalf("Oldest bad block not dropped")
+	}
+	if blocks[len(blocks)-1].Block.NumberU64() != badBlockToKeep+1 {
+		t.Fatalf("Newest bad block mismatch: have %d, want %d", blocks[len(blocks)-1].Block.NumberU64(), badBlockToKeep+1)
+	}
+	DeleteBadBlocks(db)
+	if blocks := ReadAllBadBlocks(db); len(blocks) != 0 {
+		t.Fatalf("Deleted bad blocks returned: %v", blocks)
+	}
+}
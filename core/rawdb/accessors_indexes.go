@@ -129,3 +129,18 @@ func WriteBloomBits(db btpdb.KeyValueWriter, bit uint, section uint64, head comm
 		log.Crit("Failed to store bloom bits", "err", err)
 	}
 }
+
+// ReadInternalTxs retrieves the encoded internal transactions of an account
+// within the given section of the trace index.
+func ReadInternalTxs(db btpdb.KeyValueReader, address common.Address, section uint64, head common.Hash) []byte {
+	data, _ := db.Get(internalTxsKey(address, section, head))
+	return data
+}
+
+// WriteInternalTxs stores the encoded internal transactions of an account within
+// the given section of the trace index.
+func WriteInternalTxs(db btpdb.KeyValueWriter, address common.Address, section uint64, head common.Hash, data []byte) {
+	if err := db.Put(internalTxsKey(address, section, head), data); err != nil {
+		log.Crit("Failed to store internal transactions", "err", err)
+	}
+}
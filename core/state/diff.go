@@ -0,0 +1,194 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
+)
+
+// StateDiff is the set of account modifications accumulated in the state
+// journal, keyed by the address of the modified account.
+type StateDiff map[common.Address]*AccountDiff
+
+// AccountDiff represents the changes made to a single account. Fields that
+// were not modified (or were modified back to their original values) are
+// left empty.
+type AccountDiff struct {
+	Created   bool                         `json:"created,omitempty"`
+	Destroyed bool                         `json:"destroyed,omitempty"`
+	Balance   *BalanceDiff                 `json:"balance,omitempty"`
+	Nonce     *NonceDiff                   `json:"nonce,omitempty"`
+	Code      *CodeDiff                    `json:"code,omitempty"`
+	Storage   map[common.Hash]*StorageDiff `json:"storage,omitempty"`
+}
+
+// BalanceDiff is the balance of an account before and after the changes.
+type BalanceDiff struct {
+	From *hexutil.Big `json:"from"`
+	To   *hexutil.Big `json:"to"`
+}
+
+// NonceDiff is the nonce of an account before and after the changes.
+type NonceDiff struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// CodeDiff is the code of an account before and after the changes.
+type CodeDiff struct {
+	From hexutil.Bytes `json:"from"`
+	To   hexutil.Bytes `json:"to"`
+}
+
+// StorageDiff is the value of a storage slot before and after the changes.
+type StorageDiff struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+// accountOrigin tracks the first seen (i.e. original) values of the fields of
+// an account while walking the journal.
+type accountOrigin struct {
+	created bool
+	balance *big.Int
+	nonce   *uint64
+	code    []byte
+	coded   bool
+	storage map[common.Hash]common.Hash
+}
+
+// Diff derives the account modifications made since the last Finalise, Commit
+// or Reset from the state journal. The original values are taken from the
+// journal entries, the current ones from the live state.
+//
+// Diff must be called before the state is finalised, as finalisation clears
+// the journal.
+func (self *StateDB) Diff() StateDiff {
+	origins := make(map[common.Address]*accountOrigin)
+	origin := func(addr common.Address) *accountOrigin {
+		if o, ok := origins[addr]; ok {
+			return o
+		}
+		o := &accountOrigin{storage: make(map[common.Hash]common.Hash)}
+		origins[addr] = o
+		return o
+	}
+	for _, entry := range self.journal.entries {
+		switch ch := entry.(type) {
+		case createObjectChange:
+			o := origin(*ch.account)
+			if o.balance == nil && o.nonce == nil && !o.coded {
+				o.created = true
+			}
+			if o.balance == nil {
+				o.balance = new(big.Int)
+			}
+			if o.nonce == nil {
+				o.nonce = new(uint64)
+			}
+			if !o.coded {
+				o.code, o.coded = nil, true
+			}
+		case resetObjectChange:
+			o := origin(ch.prev.address)
+			if o.balance == nil {
+				o.balance = new(big.Int).Set(ch.prev.Balance())
+			}
+			if o.nonce == nil {
+				nonce := ch.prev.Nonce()
+				o.nonce = &nonce
+			}
+			if !o.coded {
+				o.code, o.coded = ch.prev.Code(self.db), true
+			}
+		case suicideChange:
+			o := origin(*ch.account)
+			if o.balance == nil {
+				o.balance = new(big.Int).Set(ch.prevbalance)
+			}
+		case balanceChange:
+			o := origin(*ch.account)
+			if o.balance == nil {
+				o.balance = new(big.Int).Set(ch.prev)
+			}
+		case nonceChange:
+			o := origin(*ch.account)
+			if o.nonce == nil {
+				nonce := ch.prev
+				o.nonce = &nonce
+			}
+		case codeChange:
+			o := origin(*ch.account)
+			if !o.coded {
+				o.code, o.coded = ch.prevcode, true
+			}
+		case storageChange:
+			o := origin(*ch.account)
+			if _, ok := o.storage[ch.key]; !ok {
+				o.storage[ch.key] = ch.prevalue
+			}
+		}
+	}
+	diff := make(StateDiff)
+	for addr, o := range origins {
+		var (
+			account = new(AccountDiff)
+			changed bool
+		)
+		if o.created && !self.Empty(addr) {
+			account.Created, changed = true, true
+		}
+		if self.HasSuicided(addr) {
+			account.Destroyed, changed = true, true
+		}
+		if o.balance != nil {
+			if balance := self.GetBalance(addr); o.balance.Cmp(balance) != 0 {
+				account.Balance = &BalanceDiff{From: (*hexutil.Big)(o.balance), To: (*hexutil.Big)(balance)}
+				changed = true
+			}
+		}
+		if o.nonce != nil {
+			if nonce := self.GetNonce(addr); *o.nonce != nonce {
+				account.Nonce = &NonceDiff{From: hexutil.Uint64(*o.nonce), To: hexutil.Uint64(nonce)}
+				changed = true
+			}
+		}
+		if o.coded {
+			if code := self.GetCode(addr); !bytes.Equal(o.code, code) {
+				account.Code = &CodeDiff{From: o.code, To: code}
+				changed = true
+			}
+		}
+		for key, prev := range o.storage {
+			if value := self.GetState(addr, key); prev != value {
+				if account.Storage == nil {
+					account.Storage = make(map[common.Hash]*StorageDiff)
+				}
+				account.Storage[key] = &StorageDiff{From: prev, To: value}
+				changed = true
+			}
+		}
+		if changed {
+			diff[addr] = account
+		}
+	}
+	return diff
+}
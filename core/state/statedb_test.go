@@ -31,6 +31,7 @@ import (
 	check "gopkg.in/check.v1"
 
 	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/common/hexutil"
 	"github.com/btpereum/go-btpereum/core/rawdb"
 	"github.com/btpereum/go-btpereum/core/types"
 )
@@ -449,3 +450,59 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// Tests that the state diff is derived correctly from the journal, reporting
+// only the fields that actually changed.
+func TestDiff(t *testing.T) {
+	var (
+		addr1 = common.HexToAddress("0x01")
+		addr2 = common.HexToAddress("0x02")
+		addr3 = common.HexToAddress("0x03")
+		slot1 = common.HexToHash("0x01")
+		slot2 = common.HexToHash("0x02")
+	)
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+	state.SetBalance(addr1, big.NewInt(10))
+	state.SetNonce(addr1, 1)
+	state.SetState(addr1, slot1, common.HexToHash("0x01"))
+	root, _ := state.Commit(false)
+	state.Reset(root)
+
+	// Modify an existing account, create a new one and touch an empty one
+	state.AddBalance(addr1, big.NewInt(5))
+	state.SetNonce(addr1, 2)
+	state.SetState(addr1, slot1, common.HexToHash("0x02"))
+	state.SetState(addr1, slot2, common.HexToHash("0x03"))
+	state.SetState(addr1, slot2, common.Hash{})
+	state.AddBalance(addr2, big.NewInt(7))
+	state.SetCode(addr2, []byte{0x60, 0x00})
+	state.AddBalance(addr3, new(big.Int))
+
+	diff := state.Diff()
+	if len(diff) != 2 {
+		t.Fatalf("diff account count mismatch: have %d, want %d", len(diff), 2)
+	}
+	want1 := &AccountDiff{
+		Balance: &BalanceDiff{From: (*hexutil.Big)(big.NewInt(10)), To: (*hexutil.Big)(big.NewInt(15))},
+		Nonce:   &NonceDiff{From: 1, To: 2},
+		Storage: map[common.Hash]*StorageDiff{
+			slot1: {From: common.HexToHash("0x01"), To: common.HexToHash("0x02")},
+		},
+	}
+	if !reflect.DeepEqual(diff[addr1], want1) {
+		t.Errorf("modified account diff mismatch: have %+v, want %+v", diff[addr1], want1)
+	}
+	want2 := &AccountDiff{
+		Created: true,
+		Balance: &BalanceDiff{From: (*hexutil.Big)(new(big.Int)), To: (*hexutil.Big)(big.NewInt(7))},
+		Code:    &CodeDiff{To: []byte{0x60, 0x00}},
+	}
+	if !reflect.DeepEqual(diff[addr2], want2) {
+		t.Errorf("created account diff mismatch: have %+v, want %+v", diff[addr2], want2)
+	}
+	// Finalising the state should clear the journal and thus the diff
+	state.Finalise(false)
+	if diff := state.Diff(); len(diff) != 0 {
+		t.Errorf("diff not empty after finalise: %v", diff)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/event"
+)
+
+// TxLifecycle is a state transition of a transaction within the pool.
+type TxLifecycle uint
+
+const (
+	TxQueued   TxLifecycle = iota // Transaction entered (or returned to) the non-executable queue
+	TxPromoted                    // Transaction became executable and entered the pending set
+	TxReplaced                    // Transaction was replaced by another one with the same nonce
+	TxDropped                     // Transaction was removed from the pool without inclusion
+	TxIncluded                    // Transaction was removed from the pool due to block inclusion
+)
+
+// String implements fmt.Stringer.
+func (s TxLifecycle) String() string {
+	switch s {
+	case TxQueued:
+		return "queued"
+	case TxPromoted:
+		return "promoted"
+	case TxReplaced:
+		return "replaced"
+	case TxDropped:
+		return "dropped"
+	case TxIncluded:
+		return "included"
+	default:
+		return "unknown"
+	}
+}
+
+// Reasons for transactions being dropped from the pool.
+const (
+	DropUnderpriced = "underpriced"        // Evicted by better priced transactions or a raised price limit
+	DropNonceTooLow = "nonce too low"      // Made obsolete by a transaction of the same nonce in the chain
+	DropLifetime    = "lifetime"           // Queued for longer than the allowed lifetime
+	DropCapacity    = "capacity"           // Evicted due to the pool or account slots running out
+	DropNoFunds     = "insufficient funds" // Sender can no longer pay for the transaction
+)
+
+// TxChange is a single state transition of a pooled transaction.
+type TxChange struct {
+	Hash        common.Hash // Hash of the transaction changing state
+	Status      TxLifecycle // State transition the transaction went through
+	Replacement common.Hash // Hash of the replacing transaction (only for TxReplaced)
+	Reason      string      // Reason of the removal (only for TxDropped)
+}
+
+// SubscribeTxLifecycleEvent registers a subscription of TxLifecycleEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeTxLifecycleEvent(ch chan<- TxLifecycleEvent) event.Subscription {
+	return pool.scope.Track(pool.changeFeed.Subscribe(ch))
+}
+
+// Generation returns a counter of the transaction state transitions within the
+// pool, changing whenever its content does.
+func (pool *TxPool) Generation() uint64 {
+	return atomic.LoadUint64(&pool.generation)
+}
+
+// noteChange records a state transition of a transaction, to be posted once the
+// pool lock is released. Private transactions are never reported. The pool lock
+// must be held.
+func (pool *TxPool) noteChange(change TxChange) {
+	atomic.AddUint64(&pool.generation, 1)
+
+	if _, ok := pool.private[change.Hash]; ok {
+		return
+	}
+	pool.changes = append(pool.changes, change)
+}
+
+// noteDrops records the removal of a batch of transactions for the given reason,
+// classifying the ones included in the new chain head as such. The pool lock
+// must be held.
+func (pool *TxPool) noteDrops(txs types.Transactions, reason string) {
+	for _, tx := range txs {
+		hash := tx.Hash()
+		if _, ok := pool.included[hash]; ok {
+			pool.noteChange(TxChange{Hash: hash, Status: TxIncluded})
+		} else {
+			pool.noteChange(TxChange{Hash: hash, Status: TxDropped, Reason: reason})
+		}
+	}
+}
+
+// postChanges sends out all the recorded transaction state transitions. The pool
+// lock must not be held.
+func (pool *TxPool) postChanges() {
+	pool.mu.Lock()
+	changes := pool.changes
+	pool.changes = nil
+	pool.mu.Unlock()
+
+	if len(changes) > 0 {
+		pool.changeFeed.Send(TxLifecycleEvent{changes})
+	}
+}
+
+// markIncluded collects the transactions of all the blocks the chain advanced by
+// from the old head to the new one (down to their common ancestor in case of a
+// reorg), so that their removal can be reported as inclusion instead of a drop.
+// The pool lock must be held.
+func (pool *TxPool) markIncluded(oldHead, newHead *types.Header) {
+	pool.included = nil
+	if newHead == nil {
+		return
+	}
+	add := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64())
+	if add == nil {
+		return
+	}
+	var rem *types.Block
+	if oldHead != nil {
+		rem = pool.chain.GetBlock(oldHead.Hash(), oldHead.Number.Uint64())
+	}
+	pool.included = make(map[common.Hash]struct{})
+	for depth := 0; depth < maxReorgDepth; depth++ {
+		// Rewind the old chain to the height of the new one, stopping at the
+		// common ancestor (the old head itself if the chain simply advanced)
+		for rem != nil && rem.NumberU64() > add.NumberU64() {
+			rem = pool.chain.GetBlock(rem.ParentHash(), rem.NumberU64()-1)
+		}
+		if rem != nil && rem.Hash() == add.Hash() {
+			return
+		}
+		for _, tx := range add.Transactions() {
+			pool.included[tx.Hash()] = struct{}{}
+		}
+		// Without a known old chain, only the new head can be considered
+		if rem == nil || add.NumberU64() == 0 {
+			return
+		}
+		if add = pool.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/metrics"
+)
+
+// policyRejectMeter counts the transactions rejected by any admission policy.
+var policyRejectMeter = metrics.NewRegisteredMeter("txpool/policy/reject", nil)
+
+// AdmissionRequest contains the details of a transaction attempting to enter
+// the transaction pool, as presented to the admission policies.
+type AdmissionRequest struct {
+	Tx     *types.Transaction // Transaction requesting admission
+	From   common.Address     // Sender of the transaction
+	Local  bool               // Whbtper the transaction or its sender is local
+	Pooled uint64             // Gas allowance of the sender's other transactions already in the pool
+}
+
+// AdmissionPolicy is a custom rule consulted by the transaction pool for every
+// local and remote transaction that passed the consensus and pool rules. It
+// may reject the transaction by returning an error describing the reason.
+type AdmissionPolicy interface {
+	// Name returns a short identifier of the policy, used in errors and metrics.
+	Name() string
+
+	// Admit checks whbtper a transaction may enter the pool.
+	Admit(req *AdmissionRequest) error
+}
+
+// AdmissionError is returned if a transaction is rejected by an admission policy.
+type AdmissionError struct {
+	Policy string // Name of the rejecting policy
+	Reason error  // Reason of the rejection given by the policy
+}
+
+// Error implements error.
+func (err *AdmissionError) Error() string {
+	return fmt.Sprintf("rejected by %s policy: %v", err.Policy, err.Reason)
+}
+
+// SenderAllowlist is an admission policy accepting only transactions of a fixed
+// set of senders.
+type SenderAllowlist map[common.Address]struct{}
+
+// NewSenderAllowlist creates an admission policy accepting only transactions
+// sent from the given accounts.
+func NewSenderAllowlist(senders []common.Address) SenderAllowlist {
+	list := make(SenderAllowlist)
+	for _, sender := range senders {
+		list[sender] = struct{}{}
+	}
+	return list
+}
+
+// Name implements AdmissionPolicy.
+func (SenderAllowlist) Name() string { return "sender" }
+
+// Admit implements AdmissionPolicy, rejecting unknown senders.
+func (list SenderAllowlist) Admit(req *AdmissionRequest) error {
+	if _, ok := list[req.From]; !ok {
+		return fmt.Errorf("sender %x not allowed", req.From)
+	}
+	return nil
+}
+
+// RecipientDenylist is an admission policy rejecting transactions to a fixed set
+// of recipients.
+type RecipientDenylist map[common.Address]struct{}
+
+// NewRecipientDenylist creates an admission policy rejecting transactions sent
+// to the given accounts.
+func NewRecipientDenylist(recipients []common.Address) RecipientDenylist {
+	list := make(RecipientDenylist)
+	for _, recipient := range recipients {
+		list[recipient] = struct{}{}
+	}
+	return list
+}
+
+// Name implements AdmissionPolicy.
+func (RecipientDenylist) Name() string { return "recipient" }
+
+// Admit implements AdmissionPolicy, rejecting denied recipients.
+func (list RecipientDenylist) Admit(req *AdmissionRequest) error {
+	if to := req.Tx.To(); to != nil {
+		if _, ok := list[*to]; ok {
+			return fmt.Errorf("recipient %x denied", *to)
+		}
+	}
+	return nil
+}
+
+// SenderGasBudget is an admission policy limiting the total gas allowance of
+// the pooled transactions of any single sender.
+type SenderGasBudget uint64
+
+// Name implements AdmissionPolicy.
+func (SenderGasBudget) Name() string { return "gasbudget" }
+
+// Admit implements AdmissionPolicy, rejecting transactions over the budget.
+func (budget SenderGasBudget) Admit(req *AdmissionRequest) error {
+	if total := req.Pooled + req.Tx.Gas(); total > uint64(budget) {
+		return fmt.Errorf("sender gas %d over budget %d", total, uint64(budget))
+	}
+	return nil
+}
+
+// admissionPolicies assembles the admission policies requested by the pool
+// configuration: the built-in ones first, followed by any custom ones.
+func (config *TxPoolConfig) admissionPolicies() []AdmissionPolicy {
+	var policies []AdmissionPolicy
+	if len(config.AllowedSenders) > 0 {
+		policies = append(policies, NewSenderAllowlist(config.AllowedSenders))
+	}
+	if len(config.DeniedRecipients) > 0 {
+		policies = append(policies, NewRecipientDenylist(config.DeniedRecipients))
+	}
+	if config.SenderGasBudget > 0 {
+		policies = append(policies, SenderGasBudget(config.SenderGasBudget))
+	}
+	return append(policies, config.Policies...)
+}
+
+// admit runs a transaction through all the admission policies of the pool. The
+// pool lock must be held.
+func (pool *TxPool) admit(tx *types.Transaction, from common.Address, local bool) error {
+	if len(pool.policies) == 0 {
+		return nil
+	}
+	req := &AdmissionRequest{
+		Tx:     tx,
+		From:   from,
+		Local:  local,
+		Pooled: pool.pooledGas(from, tx.Nonce()),
+	}
+	for _, policy := range pool.policies {
+		if err := policy.Admit(req); err != nil {
+			policyRejectMeter.Mark(1)
+			metrics.GetOrRegisterMeter("txpool/policy/"+policy.Name()+"/reject", nil).Mark(1)
+			return &AdmissionError{Policy: policy.Name(), Reason: err}
+		}
+	}
+	return nil
+}
+
+// pooledGas sums up the gas allowance of the pending and queued transactions of
+// an account, excluding the one with the given nonce (as it would be replaced).
+func (pool *TxPool) pooledGas(addr common.Address, nonce uint64) uint64 {
+	var gas uint64
+	for _, list := range []*txList{pool.pending[addr], pool.queue[addr]} {
+		if list == nil {
+			continue
+		}
+		for _, tx := range list.txs.items {
+			if tx.Nonce() != nonce {
+				gas += tx.Gas()
+			}
+		}
+	}
+	return gas
+}
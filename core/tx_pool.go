@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"os"
 	"sort"
 	"sync"
 	"time"
@@ -38,6 +39,10 @@ import (
 const (
 	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
 	chainHeadChanSize = 10
+
+	// maxReorgDepth is the maximum number of blocks the pool walks back on a head
+	// change to reinject reorged and report included transactions.
+	maxReorgDepth = 64
 )
 
 var (
@@ -132,6 +137,7 @@ type TxPoolConfig struct {
 	NoLocals  bool             // Whbtper local transaction handling should be disabled
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
+	Snapshot  string           // Snapshot of the whole pool dumped on shutdown and restored on startup (empty = disabled)
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -141,7 +147,13 @@ type TxPoolConfig struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
-	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+	Lifetime        time.Duration // Maximum amount of time non-executable transaction are queued
+	PrivateLifetime uint64        // Number of blocks private transactions are kept for inclusion
+
+	AllowedSenders   []common.Address  // Senders whose transactions are accepted (empty = anyone)
+	DeniedRecipients []common.Address  // Recipients to reject transactions to
+	SenderGasBudget  uint64            // Maximum total gas of the pooled transactions of a sender (0 = unlimited)
+	Policies         []AdmissionPolicy `toml:"-"` // Custom admission policies to enforce on top of the above
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -158,7 +170,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	AccountQueue: 64,
 	GlobalQueue:  1024,
 
-	Lifetime: 3 * time.Hour,
+	Lifetime:        3 * time.Hour,
+	PrivateLifetime: 25,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -197,6 +210,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
 	}
+	if conf.PrivateLifetime < 1 {
+		log.Warn("Sanitizing invalid txpool private lifetime", "provided", conf.PrivateLifetime, "updated", DefaultTxPoolConfig.PrivateLifetime)
+		conf.PrivateLifetime = DefaultTxPoolConfig.PrivateLifetime
+	}
 	return conf
 }
 
@@ -213,6 +230,7 @@ type TxPool struct {
 	chain       blockChain
 	gasPrice    *big.Int
 	txFeed      event.Feed
+	changeFeed  event.Feed
 	scope       event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
@@ -221,8 +239,15 @@ type TxPool struct {
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals   *accountSet       // Set of local transaction to exempt from eviction rules
+	journal  *txJournal        // Journal of local transaction to back up to disk
+	policies []AdmissionPolicy // Admission policies to consult before accepting transactions
+
+	private map[common.Hash]uint64 // Private transactions never to announce, mapped to their expiry block
+
+	changes    []TxChange               // Transaction state transitions waiting to be posted
+	included   map[common.Hash]struct{} // Transactions of the new head block, used during resets
+	generation uint64                   // Number of transaction state transitions so far (atomic)
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -259,6 +284,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		pending:         make(map[common.Address]*txList),
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
+		private:         make(map[common.Hash]uint64),
 		all:             newTxLookup(),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
@@ -267,6 +293,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		reorgDoneCh:     make(chan chan struct{}),
 		reorgShutdownCh: make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
+		policies:        config.admissionPolicies(),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -291,6 +318,16 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If pool snapshots are enabled, restore the content of the last shutdown
+	if config.Snapshot != "" {
+		if _, err := os.Stat(config.Snapshot); err == nil {
+			total, failed, err := pool.ImportFile(config.Snapshot)
+			if err != nil {
+				log.Warn("Failed to load transaction pool snapshot", "err", err)
+			}
+			log.Info("Loaded transaction pool snapshot", "transactions", total, "dropped", failed)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -357,10 +394,12 @@ func (pool *TxPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					for _, tx := range pool.queue[addr].Flatten() {
 						pool.removeTx(tx.Hash(), true)
+						pool.noteChange(TxChange{Hash: tx.Hash(), Status: TxDropped, Reason: DropLifetime})
 					}
 				}
 			}
 			pool.mu.Unlock()
+			pool.postChanges()
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -384,6 +423,14 @@ func (pool *TxPool) Stop() {
 	pool.chainHeadSub.Unsubscribe()
 	pool.wg.Wait()
 
+	if pool.config.Snapshot != "" {
+		if count, err := pool.ExportFile(pool.config.Snapshot); err != nil {
+			log.Warn("Failed to dump transaction pool snapshot", "err", err)
+		} else {
+			log.Info("Dumped transaction pool snapshot", "transactions", count)
+		}
+	}
+
 	if pool.journal != nil {
 		pool.journal.close()
 	}
@@ -407,13 +454,17 @@ func (pool *TxPool) GasPrice() *big.Int {
 // SetGasPrice updates the minimum price required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
+	defer pool.postChanges()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals) {
+	drops := pool.priced.Cap(price, pool.locals)
+	for _, tx := range drops {
 		pool.removeTx(tx.Hash(), false)
 	}
+	pool.noteDrops(drops, DropUnderpriced)
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
@@ -547,7 +598,8 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
-	return nil
+	// Ensure the transaction is acceptable by the configured admission policies
+	return pool.admit(tx, from, local)
 }
 
 // add validates a transaction and inserts it into the non-executable queue for later
@@ -587,6 +639,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			underpricedTxMeter.Mark(1)
 			pool.removeTx(tx.Hash(), false)
 		}
+		pool.noteDrops(drop, DropUnderpriced)
 	}
 
 	// Try to replace an existing transaction in the pending pool
@@ -603,9 +656,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.noteChange(TxChange{Hash: old.Hash(), Status: TxReplaced, Replacement: hash})
 		}
 		pool.all.Add(tx)
 		pool.priced.Put(tx)
+		pool.noteChange(TxChange{Hash: hash, Status: TxPromoted})
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -618,8 +673,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		return false, err
 	}
 
-	// Mark local addresses and journal local transactions
-	if local {
+	// Mark local addresses and journal local transactions. Private transactions
+	// are exempt from pricing but must not whitelist their sender permanently.
+	if _, private := pool.private[hash]; local && !private {
 		if !pool.locals.contains(from) {
 			log.Info("Setting new local account", "address", from)
 			pool.locals.add(from)
@@ -654,6 +710,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.noteChange(TxChange{Hash: old.Hash(), Status: TxReplaced, Replacement: hash})
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedCounter.Inc(1)
@@ -662,16 +719,20 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.all.Add(tx)
 		pool.priced.Put(tx)
 	}
+	pool.noteChange(TxChange{Hash: hash, Status: TxQueued})
 	return old != nil, nil
 }
 
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
+	// Only journal if it's enabled and the transaction is local (but not private)
 	if pool.journal == nil || !pool.locals.contains(from) {
 		return
 	}
+	if _, ok := pool.private[tx.Hash()]; ok {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -695,6 +756,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed(1)
 
 		pendingDiscardMeter.Mark(1)
+		pool.noteChange(TxChange{Hash: hash, Status: TxDropped, Reason: DropUnderpriced})
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -703,6 +765,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed(1)
 
 		pendingReplaceMeter.Mark(1)
+		pool.noteChange(TxChange{Hash: old.Hash(), Status: TxReplaced, Replacement: hash})
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingCounter.Inc(1)
@@ -715,6 +778,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.beats[addr] = time.Now()
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
+	pool.noteChange(TxChange{Hash: hash, Status: TxPromoted})
 
 	return true
 }
@@ -774,6 +838,7 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	pool.mu.Lock()
 	errs, dirtyAddrs := pool.addTxsLocked(txs, local)
 	pool.mu.Unlock()
+	pool.postChanges()
 
 	done := pool.requestPromoteExecutables(dirtyAddrs)
 	if sync {
@@ -823,6 +888,12 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
 }
 
+// Arrivals returns the time the given pooled transactions were first seen by the
+// local node, as used for arrival based ordering.
+func (pool *TxPool) Arrivals(txs map[common.Address]types.Transactions) map[common.Hash]time.Time {
+	return pool.all.Arrivals(txs)
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
@@ -988,6 +1059,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	if reset != nil {
 		// Reset from the old head to the new, rescheduling any reorged transactions
 		pool.reset(reset.oldHead, reset.newHead)
+		pool.markIncluded(reset.oldHead, reset.newHead)
 
 		// Nonces were reset, discard any events that became stale
 		for addr := range events {
@@ -1016,6 +1088,10 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
 		pool.demoteUnexecutables()
+		if reset.newHead != nil {
+			pool.expirePrivate(reset.newHead.Number.Uint64())
+		}
+		pool.included = nil
 	}
 	// Ensure pool.queue and pool.pending sizes stay within the configured limits.
 	pool.truncatePending()
@@ -1026,7 +1102,20 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		txs := list.Flatten() // Heavy but will be cached and is needed by the miner anyway
 		pool.pendingNonces.set(addr, txs[len(txs)-1].Nonce()+1)
 	}
+	// Private transactions are never announced, keep them out of the events
+	if len(pool.private) > 0 {
+		for addr, set := range events {
+			set.Filter(func(tx *types.Transaction) bool {
+				_, private := pool.private[tx.Hash()]
+				return private
+			})
+			if set.Len() == 0 {
+				delete(events, addr)
+			}
+		}
+	}
 	pool.mu.Unlock()
+	pool.postChanges()
 
 	// Notify subsystems for newly added transactions
 	if len(events) > 0 {
@@ -1049,7 +1138,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		oldNum := oldHead.Number.Uint64()
 		newNum := newHead.Number.Uint64()
 
-		if depth := uint64(math.Abs(float64(oldNum) - float64(newNum))); depth > 64 {
+		if depth := uint64(math.Abs(float64(oldNum) - float64(newNum))); depth > maxReorgDepth {
 			log.Debug("Skipping deep transaction reorg", "depth", depth)
 		} else {
 			// Reorg seems shallow enough to pull in all transactions into memory
@@ -1142,6 +1231,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			pool.all.Remove(hash)
 			log.Trace("Removed old queued transaction", "hash", hash)
 		}
+		pool.noteDrops(forwards, DropNonceTooLow)
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
@@ -1149,6 +1239,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			pool.all.Remove(hash)
 			log.Trace("Removed unpayable queued transaction", "hash", hash)
 		}
+		pool.noteDrops(drops, DropNoFunds)
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
 		// Gather all executable transactions and promote them
@@ -1171,6 +1262,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 				pool.all.Remove(hash)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
+			pool.noteDrops(caps, DropCapacity)
 			queuedRateLimitMeter.Mark(int64(len(caps)))
 		}
 		// Mark all the items dropped as removed
@@ -1237,6 +1329,7 @@ func (pool *TxPool) truncatePending() {
 						}
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
+					pool.noteDrops(caps, DropCapacity)
 					pool.priced.Removed(len(caps))
 					pendingCounter.Dec(int64(len(caps)))
 					if pool.locals.contains(offenders[i]) {
@@ -1266,6 +1359,7 @@ func (pool *TxPool) truncatePending() {
 					}
 					log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 				}
+				pool.noteDrops(caps, DropCapacity)
 				pool.priced.Removed(len(caps))
 				pendingCounter.Dec(int64(len(caps)))
 				if pool.locals.contains(addr) {
@@ -1306,9 +1400,11 @@ func (pool *TxPool) truncateQueue() {
 
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
-			for _, tx := range list.Flatten() {
+			txs := list.Flatten()
+			for _, tx := range txs {
 				pool.removeTx(tx.Hash(), true)
 			}
+			pool.noteDrops(txs, DropCapacity)
 			drop -= size
 			queuedRateLimitMeter.Mark(int64(size))
 			continue
@@ -1317,6 +1413,7 @@ func (pool *TxPool) truncateQueue() {
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 			pool.removeTx(txs[i].Hash(), true)
+			pool.noteChange(TxChange{Hash: txs[i].Hash(), Status: TxDropped, Reason: DropCapacity})
 			drop--
 			queuedRateLimitMeter.Mark(1)
 		}
@@ -1338,6 +1435,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
+		pool.noteDrops(olds, DropNonceTooLow)
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
@@ -1345,6 +1443,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
 		}
+		pool.noteDrops(drops, DropNoFunds)
 		pool.priced.Removed(len(olds) + len(drops))
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -1468,13 +1567,15 @@ func (as *accountSet) merge(other *accountSet) {
 // TxPool.mu mutex.
 type txLookup struct {
 	all  map[common.Hash]*types.Transaction
+	seen map[common.Hash]time.Time // Time each transaction was first seen locally
 	lock sync.RWMutex
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		all: make(map[common.Hash]*types.Transaction),
+		all:  make(map[common.Hash]*types.Transaction),
+		seen: make(map[common.Hash]time.Time),
 	}
 }
 
@@ -1506,12 +1607,16 @@ func (t *txLookup) Count() int {
 	return len(t.all)
 }
 
-// Add adds a transaction to the lookup.
+// Add adds a transaction to the lookup, recording its arrival time.
 func (t *txLookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.all[tx.Hash()] = tx
+	hash := tx.Hash()
+	t.all[hash] = tx
+	if _, ok := t.seen[hash]; !ok {
+		t.seen[hash] = time.Now()
+	}
 }
 
 // Remove removes a transaction from the lookup.
@@ -1520,4 +1625,23 @@ func (t *txLookup) Remove(hash common.Hash) {
 	defer t.lock.Unlock()
 
 	delete(t.all, hash)
+	delete(t.seen, hash)
+}
+
+// Arrivals returns the time the given transactions were first seen locally.
+// Transactions not in the lookup are omitted.
+func (t *txLookup) Arrivals(txs map[common.Address]types.Transactions) map[common.Hash]time.Time {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	seen := make(map[common.Hash]time.Time)
+	for _, list := range txs {
+		for _, tx := range list {
+			hash := tx.Hash()
+			if arrival, ok := t.seen[hash]; ok {
+				seen[hash] = arrival
+			}
+		}
+	}
+	return seen
 }
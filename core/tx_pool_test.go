@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -1631,6 +1632,347 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that transactions are only admitted into the pool if they pass all the
+// configured admission policies.
+func TestTransactionAdmissionPolicies(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	allowed, _ := crypto.GenerateKey()
+	denied, _ := crypto.GenerateKey()
+
+	config := testTxPoolConfig
+	config.AllowedSenders = []common.Address{crypto.PubkeyToAddress(allowed.PublicKey)}
+	config.SenderGasBudget = 250000
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(allowed.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(denied.PublicKey), big.NewInt(1000000000))
+
+	// Transactions of non-allowed senders should be rejected, even if local
+	err := pool.AddLocal(transaction(0, 100000, denied))
+	if err, ok := err.(*AdmissionError); !ok || err.Policy != "sender" {
+		t.Fatalf("non-allowed sender error mismatch: have %v, want sender policy rejection", err)
+	}
+	// Transactions of allowed senders should be accepted until the gas budget runs out
+	if err := pool.addRemoteSync(transaction(0, 100000, allowed)); err != nil {
+		t.Fatalf("failed to add first transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(transaction(1, 100000, allowed)); err != nil {
+		t.Fatalf("failed to add second transaction: %v", err)
+	}
+	err = pool.addRemoteSync(transaction(2, 100000, allowed))
+	if err, ok := err.(*AdmissionError); !ok || err.Policy != "gasbudget" {
+		t.Fatalf("over-budget error mismatch: have %v, want gas budget policy rejection", err)
+	}
+	// Replacements should not count the replaced transaction against the budget
+	if err := pool.addRemoteSync(pricedTransaction(1, 150000, big.NewInt(2), allowed)); err != nil {
+		t.Fatalf("failed to replace transaction within budget: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that private transactions are available to the miner, but are never
+// announced or reported publicly, and get dropped if not included in time.
+// Tests that the pool tracks the arrival time of its transactions, forgetting
+// it along with the transaction.
+func TestTransactionArrivals(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+
+	start := time.Now()
+	tx := pricedTransaction(0, 100000, big.NewInt(1), key)
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	txs := map[common.Address]types.Transactions{from: {tx}}
+	if seen := pool.Arrivals(txs); seen[tx.Hash()].Before(start) {
+		t.Fatalf("arrival time mismatch: have %v, want after %v", seen[tx.Hash()], start)
+	}
+	// Replace the transaction and ensure the old arrival is forgotten
+	replacement := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.AddLocal(replacement); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	txs[from] = append(txs[from], replacement)
+	if seen := pool.Arrivals(txs); len(seen) != 1 || seen[replacement.Hash()].Before(start) {
+		t.Fatalf("arrival times mismatch: have %v, want only the replacement", seen)
+	}
+}
+
+func TestTransactionPrivate(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	events := make(chan NewTxsEvent, 32)
+	sub := pool.txFeed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	// Add a private and a public transaction, only the latter should be announced
+	private := transaction(0, 100000, key)
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if locals := pool.Locals(); len(locals) != 0 {
+		t.Fatalf("private sender marked local: %v", locals)
+	}
+	if err := pool.AddLocal(transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if err := validateEvents(events, 1); err != nil {
+		t.Fatalf("announcement event mismatch: %v", err)
+	}
+	if !pool.IsPrivate(private.Hash()) {
+		t.Fatalf("private transaction not reported private")
+	}
+	// Ensure the miner sees both transactions, but the network only the public one
+	if pending, _ := pool.Pending(); len(pending[crypto.PubkeyToAddress(key.PublicKey)]) != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", len(pending[crypto.PubkeyToAddress(key.PublicKey)]), 2)
+	}
+	if public, _ := pool.Propagatable(); len(public[crypto.PubkeyToAddress(key.PublicKey)]) != 1 {
+		t.Fatalf("propagatable transactions mismatched: have %d, want %d", len(public[crypto.PubkeyToAddress(key.PublicKey)]), 1)
+	}
+	if pending, _ := pool.PublicContent(); len(pending[crypto.PubkeyToAddress(key.PublicKey)]) != 1 {
+		t.Fatalf("public content mismatched: have %d, want %d", len(pending[crypto.PubkeyToAddress(key.PublicKey)]), 1)
+	}
+	pending, _ := pool.Pending()
+	if public, private := pool.SplitPrivate(pending); len(public[crypto.PubkeyToAddress(key.PublicKey)]) != 1 || len(private[crypto.PubkeyToAddress(key.PublicKey)]) != 1 {
+		t.Fatalf("split transactions mismatched: have %d/%d, want %d/%d", len(public[crypto.PubkeyToAddress(key.PublicKey)]), len(private[crypto.PubkeyToAddress(key.PublicKey)]), 1, 1)
+	}
+	// Advance the chain past the private lifetime and ensure it's dropped
+	head := &types.Header{
+		Number:   new(big.Int).SetUint64(testTxPoolConfig.PrivateLifetime),
+		GasLimit: 1000000,
+	}
+	<-pool.requestReset(nil, head)
+
+	if pool.Get(private.Hash()) != nil {
+		t.Fatalf("expired private transaction not dropped")
+	}
+	if pool.IsPrivate(private.Hash()) {
+		t.Fatalf("expired private transaction still tracked")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the state transitions of pooled transactions are reported on the
+// lifecycle feed in the order they happen.
+func TestTransactionLifecycleEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	other, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000000))
+
+	changes := make(chan TxLifecycleEvent, 32)
+	sub := pool.SubscribeTxLifecycleEvent(changes)
+	defer sub.Unsubscribe()
+
+	var (
+		gapped   = pricedTransaction(1, 100000, big.NewInt(10), key)
+		filler   = pricedTransaction(0, 100000, big.NewInt(10), key)
+		replacer = pricedTransaction(0, 100000, big.NewInt(20), key)
+		cheap    = pricedTransaction(0, 100000, big.NewInt(1), other)
+	)
+	for _, tx := range []*types.Transaction{gapped, filler, replacer, cheap} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	pool.SetGasPrice(big.NewInt(2))
+
+	want := []TxChange{
+		{Hash: gapped.Hash(), Status: TxQueued},
+		{Hash: filler.Hash(), Status: TxQueued},
+		{Hash: filler.Hash(), Status: TxPromoted},
+		{Hash: gapped.Hash(), Status: TxPromoted},
+		{Hash: filler.Hash(), Status: TxReplaced, Replacement: replacer.Hash()},
+		{Hash: replacer.Hash(), Status: TxPromoted},
+		{Hash: cheap.Hash(), Status: TxQueued},
+		{Hash: cheap.Hash(), Status: TxPromoted},
+		{Hash: cheap.Hash(), Status: TxDropped, Reason: DropUnderpriced},
+	}
+	var have []TxChange
+	for len(have) < len(want) {
+		select {
+		case ev := <-changes:
+			have = append(have, ev.Changes...)
+		case <-time.After(time.Second):
+			t.Fatalf("lifecycle event count mismatch: have %d, want %d", len(have), len(want))
+		}
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("change %d mismatch: have %+v, want %+v", i, have[i], want[i])
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// blockMapChain is a testBlockChain additionally serving blocks from a fixed set,
+// so pool resets can walk between chain heads.
+type blockMapChain struct {
+	*testBlockChain
+	blocks map[common.Hash]*types.Block
+}
+
+func (bc *blockMapChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return bc.blocks[hash]
+}
+
+// Tests that the transactions of every block the chain advanced by within a
+// single reset are considered included, but none below the common ancestor of
+// the old and new heads.
+func TestTransactionIncludedMarking(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	blockchain := &blockMapChain{&testBlockChain{statedb, 1000000, new(event.Feed)}, make(map[common.Hash]*types.Block)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	txs := make([]*types.Transaction, 5)
+	for i := range txs {
+		txs[i] = transaction(uint64(i), 100000, key)
+	}
+	newBlock := func(parent *types.Block, tx *types.Transaction) *types.Block {
+		var (
+			header = &types.Header{Number: common.Big0, GasLimit: 1000000}
+			body   types.Transactions
+		)
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+			header.Number = new(big.Int).Add(parent.Number(), common.Big1)
+		}
+		if tx != nil {
+			body = types.Transactions{tx}
+		}
+		block := types.NewBlock(header, body, nil, nil)
+		blockchain.blocks[block.Hash()] = block
+		return block
+	}
+	// Create a canonical chain and two forks: one from genesis, one from #1
+	var (
+		genesis = newBlock(nil, nil)
+		b1      = newBlock(genesis, txs[0])
+		b2      = newBlock(b1, txs[1])
+		f1      = newBlock(genesis, txs[2])
+		f2      = newBlock(f1, txs[3])
+		g2      = newBlock(b1, txs[4])
+	)
+	tests := []struct {
+		old, new *types.Block
+		included []*types.Transaction
+	}{
+		{nil, b2, []*types.Transaction{txs[1]}},             // Unknown old head, only the new one counts
+		{genesis, b2, []*types.Transaction{txs[0], txs[1]}}, // Advanced two blocks in one reset
+		{b2, b2, nil}, // Head unchanged, nothing new included
+		{b2, f2, []*types.Transaction{txs[2], txs[3]}}, // Reorg down to genesis
+		{b2, g2, []*types.Transaction{txs[4]}},         // Reorg down to #1
+		{f2, b1, []*types.Transaction{txs[0]}},         // Reorg to a shorter chain
+	}
+	for i, tt := range tests {
+		var old *types.Header
+		if tt.old != nil {
+			old = tt.old.Header()
+		}
+		pool.mu.Lock()
+		pool.markIncluded(old, tt.new.Header())
+		included := pool.included
+		pool.mu.Unlock()
+
+		if len(included) != len(tt.included) {
+			t.Errorf("test %d: included count mismatch: have %d, want %d", i, len(included), len(tt.included))
+		}
+		for _, tx := range tt.included {
+			if _, ok := included[tx.Hash()]; !ok {
+				t.Errorf("test %d: transaction %x not marked included", i, tx.Hash())
+			}
+		}
+	}
+}
+
+// Tests that the whole content of the pool is dumped into a snapshot on shutdown
+// and restored, local flags included, on startup.
+func TestTransactionPoolSnapshot(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary path for the snapshot
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Snapshot = filepath.Join(dir, "txpool.rlp")
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Add an executable local and a gapped remote transaction, then dump the pool
+	if err := pool.AddLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	pool.Stop()
+
+	// Restart the pool without journaling and ensure the content is restored
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pending, queued := pool.Stats()
+	if pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if locals := pool.Locals(); len(locals) != 1 || locals[0] != crypto.PubkeyToAddress(local.PublicKey) {
+		t.Fatalf("local accounts mismatched: have %v, want %v", locals, crypto.PubkeyToAddress(local.PublicKey))
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
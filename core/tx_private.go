@@ -0,0 +1,149 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/log"
+	"github.com/btpereum/go-btpereum/metrics"
+)
+
+// privateExpiredMeter counts the private transactions dropped without inclusion.
+var privateExpiredMeter = metrics.NewRegisteredMeter("txpool/private/expired", nil)
+
+// AddPrivate enqueues a local transaction into the pool which is only ever
+// included by the local miner: it is not announced to subscribers, broadcast to
+// the network or reported in the public pool content. If not included within
+// the configured number of blocks, the transaction is dropped. Unlike AddLocal,
+// the sender is not marked as a local account.
+func (pool *TxPool) AddPrivate(tx *types.Transaction) error {
+	hash := tx.Hash()
+
+	// Mark the transaction private before insertion so it's never announced
+	pool.mu.Lock()
+	if pool.all.Get(hash) != nil {
+		pool.mu.Unlock()
+		return fmt.Errorf("known transaction: %x", hash)
+	}
+	pool.private[hash] = pool.chain.CurrentBlock().NumberU64() + pool.config.PrivateLifetime
+	pool.mu.Unlock()
+
+	if err := pool.addTxs([]*types.Transaction{tx}, !pool.config.NoLocals, true)[0]; err != nil {
+		pool.mu.Lock()
+		delete(pool.private, hash)
+		pool.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// IsPrivate returns whbtper a transaction was submitted as private.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	_, ok := pool.private[hash]
+	return ok
+}
+
+// Propagatable retrieves all currently processable transactions which may be
+// relayed to the network, grouped by origin account and sorted by nonce. Unlike
+// Pending, private transactions are excluded.
+func (pool *TxPool) Propagatable() (map[common.Address]types.Transactions, error) {
+	pending, err := pool.Pending()
+	if err != nil {
+		return nil, err
+	}
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.withoutPrivate(pending), nil
+}
+
+// PublicContent retrieves the data content of the transaction pool like Content
+// does, but without any private transactions.
+func (pool *TxPool) PublicContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	pending, queued := pool.Content()
+
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.withoutPrivate(pending), pool.withoutPrivate(queued)
+}
+
+// SplitPrivate separates the private transactions out of a set of processable
+// transactions grouped by origin account, as returned by Pending. The input is
+// left untouched.
+func (pool *TxPool) SplitPrivate(txs map[common.Address]types.Transactions) (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	public, private := make(map[common.Address]types.Transactions), make(map[common.Address]types.Transactions)
+	for addr, list := range txs {
+		for _, tx := range list {
+			if _, ok := pool.private[tx.Hash()]; ok {
+				private[addr] = append(private[addr], tx)
+			} else {
+				public[addr] = append(public[addr], tx)
+			}
+		}
+	}
+	return public, private
+}
+
+// withoutPrivate filters the private transactions out of a set of transactions
+// grouped by account. The pool lock must be held.
+func (pool *TxPool) withoutPrivate(txs map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	if len(pool.private) == 0 {
+		return txs
+	}
+	for addr, list := range txs {
+		filtered := list[:0]
+		for _, tx := range list {
+			if _, ok := pool.private[tx.Hash()]; !ok {
+				filtered = append(filtered, tx)
+			}
+		}
+		if len(filtered) == 0 {
+			delete(txs, addr)
+		} else {
+			txs[addr] = filtered
+		}
+	}
+	return txs
+}
+
+// expirePrivate drops the private transactions which were not included until
+// their expiration block, and forgets the ones no longer pooled. The pool lock
+// must be held.
+func (pool *TxPool) expirePrivate(number uint64) {
+	for hash, expiry := range pool.private {
+		if pool.all.Get(hash) == nil {
+			delete(pool.private, hash)
+			continue
+		}
+		if number >= expiry {
+			log.Debug("Dropping expired private transaction", "hash", hash, "expiry", expiry)
+			pool.removeTx(hash, true)
+			delete(pool.private, hash)
+			privateExpiredMeter.Mark(1)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io"
+	"os"
+
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/log"
+	"github.com/btpereum/go-btpereum/rlp"
+)
+
+// snapshotTx is a transaction entry within a transaction pool snapshot.
+type snapshotTx struct {
+	Tx    *types.Transaction
+	Local bool // Whbtper the transaction was treated as local by the exporting pool
+}
+
+// Export writes the entire content of the transaction pool, both pending and
+// queued transactions, into the given writer as an RLP stream. Transactions of
+// each account are written in nonce order so that they can be reimported.
+// Private transactions are never exported.
+func (pool *TxPool) Export(w io.Writer) (int, error) {
+	pool.mu.Lock()
+	var txs []*snapshotTx
+	for addr, list := range pool.pending {
+		local := pool.locals.contains(addr)
+		for _, tx := range list.Flatten() {
+			if _, ok := pool.private[tx.Hash()]; !ok {
+				txs = append(txs, &snapshotTx{Tx: tx, Local: local})
+			}
+		}
+	}
+	for addr, list := range pool.queue {
+		local := pool.locals.contains(addr)
+		for _, tx := range list.Flatten() {
+			if _, ok := pool.private[tx.Hash()]; !ok {
+				txs = append(txs, &snapshotTx{Tx: tx, Local: local})
+			}
+		}
+	}
+	pool.mu.Unlock()
+
+	for i, tx := range txs {
+		if err := rlp.Encode(w, tx); err != nil {
+			return i, err
+		}
+	}
+	return len(txs), nil
+}
+
+// Import reads a transaction pool snapshot produced by Export and injects its
+// content into the pool, retaining the local flags of the transactions. The
+// number of processed and rejected transactions is returned.
+func (pool *TxPool) Import(r io.Reader) (int, int, error) {
+	var (
+		stream          = rlp.NewStream(r, 0)
+		total, failed   int
+		locals, remotes []*types.Transaction
+	)
+	flush := func() {
+		var errs []error
+		if len(locals) > 0 {
+			errs = append(errs, pool.AddLocals(locals)...)
+		}
+		if len(remotes) > 0 {
+			errs = append(errs, pool.addRemotesSync(remotes)...)
+		}
+		for _, err := range errs {
+			if err != nil {
+				log.Debug("Failed to import pooled transaction", "err", err)
+				failed++
+			}
+		}
+		locals, remotes = locals[:0], remotes[:0]
+	}
+	for {
+		tx := new(snapshotTx)
+		if err := stream.Decode(tx); err != nil {
+			flush()
+			if err == io.EOF {
+				err = nil
+			}
+			return total, failed, err
+		}
+		total++
+
+		if tx.Local {
+			locals = append(locals, tx.Tx)
+		} else {
+			remotes = append(remotes, tx.Tx)
+		}
+		if len(locals)+len(remotes) >= 1024 {
+			flush()
+		}
+	}
+}
+
+// ExportFile dumps the content of the transaction pool into the given file,
+// replacing it atomically.
+func (pool *TxPool) ExportFile(path string) (int, error) {
+	out, err := os.OpenFile(path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	count, err := pool.Export(out)
+	if err != nil {
+		out.Close()
+		return count, err
+	}
+	if err := out.Close(); err != nil {
+		return count, err
+	}
+	return count, os.Rename(path+".new", path)
+}
+
+// ImportFile loads a transaction pool snapshot from the given file into the pool.
+func (pool *TxPool) ImportFile(path string) (int, int, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer in.Close()
+
+	return pool.Import(in)
+}
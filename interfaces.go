@@ -105,6 +105,14 @@ type SyncProgress struct {
 	KnownStates   uint64 // Total number of state trie entries known about
 }
 
+// FeeHistory provides recent fee market data that consumers can use to determine
+// a reasonable gas price.
+type FeeHistory struct {
+	OldestBlock  *big.Int     // Number of the first block of the history
+	Reward       [][]*big.Int // Requested gas price percentiles of each block
+	GasUsedRatio []float64    // Gas used divided by the gas limit of each block
+}
+
 // ChainSyncReader wraps access to the node's current sync status. If there's no
 // sync currently running, it returns nil.
 type ChainSyncReader interface {
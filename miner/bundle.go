@@ -0,0 +1,263 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/crypto"
+	"github.com/btpereum/go-btpereum/log"
+)
+
+const (
+	// maxBundleTxs is the maximum number of transactions a single bundle may contain.
+	maxBundleTxs = 16
+
+	// maxBundlesPerBlock is the maximum number of bundles kept for a target block.
+	maxBundlesPerBlock = 64
+
+	// maxBundleFuture is the maximum number of blocks a bundle may target ahead
+	// of the current chain head.
+	maxBundleFuture = 32
+)
+
+var (
+	errEmptyBundle     = errors.New("empty bundle")
+	errBundleTooLarge  = errors.New("bundle too large")
+	errStaleBundle     = errors.New("bundle targets a past block")
+	errFutureBundle    = errors.New("bundle targets a block too far in the future")
+	errKnownBundle     = errors.New("known bundle")
+	errBundlePoolFull  = errors.New("bundle pool full for target block")
+	errBundleTxFailed  = errors.New("bundle transaction failed")
+	errBundleNoGasUsed = errors.New("bundle used no gas")
+)
+
+// Bundle is an ordered list of transactions which must be included atomically
+// at the top of a specific block: either all of them in the given order, or
+// none at all.
+type Bundle struct {
+	Txs         types.Transactions // Transactions to include, in execution order
+	BlockNumber uint64             // Number of the only block the bundle may be included in
+}
+
+// Hash returns the identifier of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([][]byte, len(b.Txs))
+	for i, tx := range b.Txs {
+		hashes[i] = tx.Hash().Bytes()
+	}
+	return crypto.Keccak256Hash(hashes...)
+}
+
+// bundlePool holds the submitted bundles grouped by their target block number.
+type bundlePool struct {
+	bundles map[uint64][]*Bundle // Bundles waiting for inclusion, by target block
+	lock    sync.Mutex
+}
+
+// newBundlePool creates an empty bundle pool.
+func newBundlePool() *bundlePool {
+	return &bundlePool{
+		bundles: make(map[uint64][]*Bundle),
+	}
+}
+
+// add validates a bundle against the current chain head and schedules it for
+// inclusion into its target block.
+func (p *bundlePool) add(bundle *Bundle, head uint64) error {
+	switch {
+	case len(bundle.Txs) == 0:
+		return errEmptyBundle
+	case len(bundle.Txs) > maxBundleTxs:
+		return errBundleTooLarge
+	case bundle.BlockNumber <= head:
+		return errStaleBundle
+	case bundle.BlockNumber > head+maxBundleFuture:
+		return errFutureBundle
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Drop all bundles which can't be included any more
+	for number := range p.bundles {
+		if number <= head {
+			delete(p.bundles, number)
+		}
+	}
+	hash := bundle.Hash()
+	for _, known := range p.bundles[bundle.BlockNumber] {
+		if known.Hash() == hash {
+			return errKnownBundle
+		}
+	}
+	if len(p.bundles[bundle.BlockNumber]) >= maxBundlesPerBlock {
+		return errBundlePoolFull
+	}
+	p.bundles[bundle.BlockNumber] = append(p.bundles[bundle.BlockNumber], bundle)
+	return nil
+}
+
+// pending retrieves the bundles targeting the given block.
+func (p *bundlePool) pending(number uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]*Bundle{}, p.bundles[number]...)
+}
+
+// simulatedBundle is a bundle successfully executed against the pending state.
+type simulatedBundle struct {
+	bundle  *Bundle
+	profit  *big.Int // Balance increase of the coinbase caused by the bundle
+	gasUsed uint64   // Gas used by all the transactions of the bundle
+	price   *big.Int // Effective gas price paid to the coinbase (profit / gas)
+}
+
+// addBundle schedules a bundle for inclusion and notifies the main loop.
+func (w *worker) addBundle(bundle *Bundle) error {
+	if err := w.bundles.add(bundle, w.chain.CurrentBlock().NumberU64()); err != nil {
+		return err
+	}
+	select {
+	case w.bundleCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// simulateBundle executes a bundle on a copy of the current environment and
+// measures the profit the bundle yields to the coinbase. Any failing or reverted
+// transaction rejects the whole bundle.
+func (w *worker) simulateBundle(bundle *Bundle, coinbase common.Address) (*simulatedBundle, error) {
+	var (
+		statedb = w.current.state.Copy()
+		header  = types.CopyHeader(w.current.header)
+		gasPool = new(core.GasPool).AddGas(w.current.gasPool.Gas())
+		before  = statedb.GetBalance(coinbase)
+		gasUsed uint64
+	)
+	for i, tx := range bundle.Txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, w.current.tcount+i)
+
+		receipt, _, err := core.ApplyTransaction(w.chainConfig, w.chain, &coinbase, gasPool, statedb, header, tx, &header.GasUsed, *w.chain.GetVMConfig())
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			return nil, fmt.Errorf("transaction %d: %v", i, errBundleTxFailed)
+		}
+		gasUsed += receipt.GasUsed
+	}
+	if gasUsed == 0 {
+		return nil, errBundleNoGasUsed
+	}
+	profit := new(big.Int).Sub(statedb.GetBalance(coinbase), before)
+	return &simulatedBundle{
+		bundle:  bundle,
+		profit:  profit,
+		gasUsed: gasUsed,
+		price:   new(big.Int).Div(profit, new(big.Int).SetUint64(gasUsed)),
+	}, nil
+}
+
+// commitBundle applies all the transactions of a bundle to the current
+// environment, or none of them if any fails. The bundle is executed on a copy
+// of the state which is only swapped in if all the transactions succeed, since
+// journal snapshots don't survive the finalisation done between transactions.
+func (w *worker) commitBundle(bundle *Bundle, coinbase common.Address) ([]*types.Log, error) {
+	var (
+		statedb  = w.current.state.Copy()
+		gasPool  = new(core.GasPool).AddGas(w.current.gasPool.Gas())
+		gasUsed  = w.current.header.GasUsed
+		receipts = make([]*types.Receipt, 0, len(bundle.Txs))
+		logs     []*types.Log
+	)
+	for i, tx := range bundle.Txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, w.current.tcount+i)
+
+		receipt, _, err := core.ApplyTransaction(w.chainConfig, w.chain, &coinbase, gasPool, statedb, w.current.header, tx, &gasUsed, *w.chain.GetVMConfig())
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			return nil, fmt.Errorf("transaction %d: %v", i, errBundleTxFailed)
+		}
+		receipts = append(receipts, receipt)
+		logs = append(logs, receipt.Logs...)
+	}
+	w.current.state = statedb
+	w.current.gasPool = gasPool
+	w.current.header.GasUsed = gasUsed
+	w.current.tcount += len(bundle.Txs)
+	w.current.txs = append(w.current.txs, bundle.Txs...)
+	w.current.receipts = append(w.current.receipts, receipts...)
+
+	return logs, nil
+}
+
+// commitBundles simulates all the bundles targeting the current block against
+// the pending state, and commits them at the top of the block in the order of
+// the gas price they effectively pay. Bundles conflicting with previously
+// committed ones are skipped as a whole.
+func (w *worker) commitBundles(coinbase common.Address) {
+	bundles := w.bundles.pending(w.current.header.Number.Uint64())
+	if len(bundles) == 0 {
+		return
+	}
+	if w.current.gasPool == nil {
+		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+	}
+	var simulated []*simulatedBundle
+	for _, bundle := range bundles {
+		sim, err := w.simulateBundle(bundle, coinbase)
+		if err != nil {
+			log.Debug("Bundle simulation failed", "hash", bundle.Hash(), "err", err)
+			continue
+		}
+		simulated = append(simulated, sim)
+	}
+	sort.SliceStable(simulated, func(i, j int) bool {
+		return simulated[i].price.Cmp(simulated[j].price) > 0
+	})
+	var coalescedLogs []*types.Log
+	for _, sim := range simulated {
+		logs, err := w.commitBundle(sim.bundle, coinbase)
+		if err != nil {
+			log.Debug("Bundle skipped", "hash", sim.bundle.Hash(), "err", err)
+			continue
+		}
+		coalescedLogs = append(coalescedLogs, logs...)
+		log.Debug("Committed bundle", "hash", sim.bundle.Hash(), "txs", len(sim.bundle.Txs), "gas", sim.gasUsed, "profit", sim.profit)
+	}
+	// Announce the pending logs of the bundles the same way as of the other
+	// transactions, copying them to avoid racing with the mined log upgrades
+	if !w.isRunning() && len(coalescedLogs) > 0 {
+		cpy := make([]*types.Log, len(coalescedLogs))
+		for i, l := range coalescedLogs {
+			cpy[i] = new(types.Log)
+			*cpy[i] = *l
+		}
+		go w.mux.Post(core.PendingLogsEvent{Logs: cpy})
+	}
+}
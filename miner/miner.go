@@ -51,6 +51,8 @@ type Config struct {
 	GasPrice  *big.Int       // Minimum gas price for mining a transaction
 	Recommit  time.Duration  // The time interval for miner to re-create mining work.
 	Noverify  bool           // Disable remote mining solution verification(only useful in btpash).
+
+	Ordering OrderingStrategy `toml:"-"` // Transaction ordering strategy for mined blocks (nil = price)
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -169,6 +171,25 @@ func (self *Miner) Pending() (*types.Block, *state.StateDB) {
 	return self.worker.pending()
 }
 
+// SendBundle schedules a bundle of transactions for atomic inclusion at the top
+// of its target block. The bundle is dropped if it can't be included in full.
+func (self *Miner) SendBundle(bundle *Bundle) error {
+	return self.worker.addBundle(bundle)
+}
+
+// BlockTemplate assembles an unsealed block on top of the given parent out of the
+// currently pending transactions, for sealing or further assembly by external
+// block builders. The receipts of the included transactions are also returned.
+func (self *Miner) BlockTemplate(parent common.Hash, coinbase common.Address, timestamp uint64) (*types.Block, types.Receipts, error) {
+	return self.worker.buildTemplate(parent, coinbase, timestamp)
+}
+
+// SubmitBlock validates an externally sealed or assembled block with the
+// consensus engine and imports it into the local chain.
+func (self *Miner) SubmitBlock(block *types.Block) error {
+	return self.worker.submitBlock(block)
+}
+
 // PendingBlock returns the currently pending block.
 //
 // Note, to access both the pending block and the pending state
@@ -0,0 +1,247 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/core/types"
+)
+
+// TransactionSet is a nonce-honouring ordered set of pending transactions, as
+// consumed by the worker when filling a block.
+type TransactionSet interface {
+	// Peek returns the next transaction to include, or nil if none is left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same account.
+	Shift()
+
+	// Pop removes the current transaction and all subsequent ones from the same
+	// account. It's used when a transaction cannot be executed.
+	Pop()
+}
+
+// OrderingStrategy decides the order in which the pending transactions are
+// included into the blocks built by the worker.
+type OrderingStrategy interface {
+	// Name returns the identifier of the strategy.
+	Name() string
+
+	// Order creates an ordered set out of the pending transactions of a number
+	// of accounts, each list sorted by nonce, given the time each transaction was
+	// first seen locally. The input map is reowned.
+	Order(signer types.Signer, txs map[common.Address]types.Transactions, seen map[common.Hash]time.Time) TransactionSet
+}
+
+// NewOrderingStrategy returns the built-in ordering strategy with the given name.
+func NewOrderingStrategy(name string) (OrderingStrategy, error) {
+	switch name {
+	case "", PriceOrdering{}.Name():
+		return PriceOrdering{}, nil
+	case FIFOOrdering{}.Name():
+		return FIFOOrdering{}, nil
+	case RoundRobinOrdering{}.Name():
+		return RoundRobinOrdering{}, nil
+	default:
+		return nil, fmt.Errorf("unknown transaction ordering %q", name)
+	}
+}
+
+// PriceOrdering is the default strategy, including the best paying transactions
+// first to maximize the block rewards.
+type PriceOrdering struct{}
+
+// Name implements OrderingStrategy.
+func (PriceOrdering) Name() string { return "price" }
+
+// Order implements OrderingStrategy.
+func (PriceOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions, seen map[common.Hash]time.Time) TransactionSet {
+	return types.NewTransactionsByPriceAndNonce(signer, txs)
+}
+
+// FIFOOrdering is a first-come-first-served strategy, including transactions in
+// the order they were first seen by the local node.
+type FIFOOrdering struct{}
+
+// Name implements OrderingStrategy.
+func (FIFOOrdering) Name() string { return "fifo" }
+
+// Order implements OrderingStrategy.
+func (FIFOOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions, seen map[common.Hash]time.Time) TransactionSet {
+	return newTxsByTimeAndNonce(signer, txs, seen)
+}
+
+// RoundRobinOrdering is a strategy giving all senders a fair share of the block,
+// taking one transaction from each of them in turn.
+type RoundRobinOrdering struct{}
+
+// Name implements OrderingStrategy.
+func (RoundRobinOrdering) Name() string { return "roundrobin" }
+
+// Order implements OrderingStrategy.
+func (RoundRobinOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions, seen map[common.Hash]time.Time) TransactionSet {
+	return newTxsRoundRobin(signer, txs, seen)
+}
+
+// txsByTime implements heap.Interface, ordering transactions by arrival time.
+type txsByTime struct {
+	txs  []*types.Transaction
+	seen map[common.Hash]time.Time
+}
+
+func (s txsByTime) Len() int { return len(s.txs) }
+func (s txsByTime) Less(i, j int) bool {
+	if ti, tj := s.seen[s.txs[i].Hash()], s.seen[s.txs[j].Hash()]; !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	return s.txs[i].GasPrice().Cmp(s.txs[j].GasPrice()) > 0
+}
+func (s txsByTime) Swap(i, j int) { s.txs[i], s.txs[j] = s.txs[j], s.txs[i] }
+
+func (s *txsByTime) Push(x interface{}) {
+	s.txs = append(s.txs, x.(*types.Transaction))
+}
+
+func (s *txsByTime) Pop() interface{} {
+	old := s.txs
+	n := len(old)
+	x := old[n-1]
+	s.txs = old[0 : n-1]
+	return x
+}
+
+// txsByTimeAndNonce is a transaction set returning the transactions in arrival
+// order, while honouring the nonce order of each account.
+type txsByTimeAndNonce struct {
+	txs    map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	heads  txsByTime                             // Next transaction for each unique account (time heap)
+	signer types.Signer                          // Signer for the set of transactions
+}
+
+// newTxsByTimeAndNonce creates a transaction set that can retrieve transactions
+// in arrival order, in a nonce-honouring way.
+func newTxsByTimeAndNonce(signer types.Signer, txs map[common.Address]types.Transactions, seen map[common.Hash]time.Time) *txsByTimeAndNonce {
+	heads := txsByTime{txs: make([]*types.Transaction, 0, len(txs)), seen: seen}
+	for from, accTxs := range txs {
+		heads.txs = append(heads.txs, accTxs[0])
+		acc, _ := types.Sender(signer, accTxs[0])
+		txs[acc] = accTxs[1:]
+		if from != acc {
+			delete(txs, from)
+		}
+	}
+	heap.Init(&heads)
+
+	return &txsByTimeAndNonce{
+		txs:    txs,
+		heads:  heads,
+		signer: signer,
+	}
+}
+
+// Peek implements TransactionSet, returning the earliest arrived transaction.
+func (t *txsByTimeAndNonce) Peek() *types.Transaction {
+	if len(t.heads.txs) == 0 {
+		return nil
+	}
+	return t.heads.txs[0]
+}
+
+// Shift implements TransactionSet.
+func (t *txsByTimeAndNonce) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads.txs[0])
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads.txs[0], t.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+// Pop implements TransactionSet.
+func (t *txsByTimeAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
+
+// txsRoundRobin is a transaction set cycling through the senders, returning
+// the next transaction of a different account after each included one.
+type txsRoundRobin struct {
+	txs     map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	senders []common.Address                      // Accounts with transactions left, in turn order
+	next    int                                   // Index of the account whose turn it is
+}
+
+// newTxsRoundRobin creates a transaction set cycling through the senders, with
+// the turn order decided by the arrival of the first transaction of each.
+func newTxsRoundRobin(signer types.Signer, txs map[common.Address]types.Transactions, seen map[common.Hash]time.Time) *txsRoundRobin {
+	set := &txsRoundRobin{
+		txs:     make(map[common.Address]types.Transactions, len(txs)),
+		senders: make([]common.Address, 0, len(txs)),
+	}
+	for _, accTxs := range txs {
+		acc, _ := types.Sender(signer, accTxs[0])
+		set.txs[acc] = accTxs
+		set.senders = append(set.senders, acc)
+	}
+	sort.Slice(set.senders, func(i, j int) bool {
+		ti, tj := seen[set.txs[set.senders[i]][0].Hash()], seen[set.txs[set.senders[j]][0].Hash()]
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return bytes.Compare(set.senders[i][:], set.senders[j][:]) < 0
+	})
+	return set
+}
+
+// Peek implements TransactionSet, returning the next transaction of the account
+// whose turn it is.
+func (t *txsRoundRobin) Peek() *types.Transaction {
+	if len(t.senders) == 0 {
+		return nil
+	}
+	return t.txs[t.senders[t.next]][0]
+}
+
+// Shift implements TransactionSet, passing the turn to the next account.
+func (t *txsRoundRobin) Shift() {
+	acc := t.senders[t.next]
+	if t.txs[acc] = t.txs[acc][1:]; len(t.txs[acc]) == 0 {
+		t.remove()
+		return
+	}
+	t.next = (t.next + 1) % len(t.senders)
+}
+
+// Pop implements TransactionSet, dropping the account whose turn it is.
+func (t *txsRoundRobin) Pop() {
+	t.remove()
+}
+
+// remove drops the account whose turn it is, passing the turn to the next one.
+func (t *txsRoundRobin) remove() {
+	delete(t.txs, t.senders[t.next])
+	t.senders = append(t.senders[:t.next], t.senders[t.next+1:]...)
+	if t.next >= len(t.senders) {
+		t.next = 0
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/btpereum/go-btpereum/common"
+	"github.com/btpereum/go-btpereum/consensus/misc"
+	"github.com/btpereum/go-btpereum/core"
+	"github.com/btpereum/go-btpereum/core/types"
+	"github.com/btpereum/go-btpereum/log"
+	"github.com/btpereum/go-btpereum/params"
+)
+
+var (
+	errUnknownParent = errors.New("unknown parent block")
+	errKnownBlock    = errors.New("block already known")
+)
+
+// buildTemplate assembles an unsealed block on top of the given parent out of
+// the pending transactions of the pool, without interfering with the sealing
+// work of the worker. The block is returned along with the receipts of its
+// transactions.
+func (w *worker) buildTemplate(parentHash common.Hash, coinbase common.Address, timestamp uint64) (*types.Block, types.Receipts, error) {
+	parent := w.chain.GetBlockByHash(parentHash)
+	if parent == nil {
+		return nil, nil, errUnknownParent
+	}
+	if timestamp <= parent.Time() {
+		timestamp = parent.Time() + 1
+	}
+	w.mu.RLock()
+	extra := w.extra
+	w.mu.RUnlock()
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   core.CalcGasLimit(parent, w.config.GasFloor, w.config.GasCeil),
+		Extra:      extra,
+		Time:       timestamp,
+		Coinbase:   coinbase,
+	}
+	if err := w.engine.Prepare(w.chain, header); err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare header: %v", err)
+	}
+	statedb, err := w.chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, nil, err
+	}
+	if w.chainConfig.DAOForkSupport && w.chainConfig.DAOForkBlock != nil && w.chainConfig.DAOForkBlock.Cmp(header.Number) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+	}
+	// Templates are handed out to external builders, so private transactions
+	// must never make it into them
+	pending, err := w.btp.TxPool().Propagatable()
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		signer   = types.NewEIP155Signer(w.chainConfig.ChainID)
+		set      = w.ordering().Order(signer, pending, w.btp.TxPool().Arrivals(pending))
+		gasPool  = new(core.GasPool).AddGas(header.GasLimit)
+		txs      []*types.Transaction
+		receipts []*types.Receipt
+	)
+	for gasPool.Gas() >= params.TxGas {
+		tx := set.Peek()
+		if tx == nil {
+			break
+		}
+		if tx.Protected() && !w.chainConfig.IsEIP155(header.Number) {
+			set.Pop()
+			continue
+		}
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(txs))
+
+		snap := statedb.Snapshot()
+		receipt, _, err := core.ApplyTransaction(w.chainConfig, w.chain, &coinbase, gasPool, statedb, header, tx, &header.GasUsed, *w.chain.GetVMConfig())
+		switch err {
+		case nil:
+			txs = append(txs, tx)
+			receipts = append(receipts, receipt)
+			set.Shift()
+
+		case core.ErrNonceTooLow:
+			statedb.RevertToSnapshot(snap)
+			set.Shift()
+
+		default:
+			// Out of gas, nonce gap or otherwise failing, skip the account
+			log.Trace("Skipping transaction in block template", "hash", tx.Hash(), "err", err)
+			statedb.RevertToSnapshot(snap)
+			set.Pop()
+		}
+	}
+	block, err := w.engine.FinalizeAndAssemble(w.chain, header, statedb, txs, nil, receipts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, receipts, nil
+}
+
+// submitBlock validates a sealed block assembled outside of the worker with the
+// consensus engine and imports it into the local chain, broadcasting it on
+// success.
+func (w *worker) submitBlock(block *types.Block) error {
+	if w.chain.HasBlock(block.Hash(), block.NumberU64()) {
+		return errKnownBlock
+	}
+	if !w.chain.HasBlock(block.ParentHash(), block.NumberU64()-1) {
+		return errUnknownParent
+	}
+	if err := w.engine.VerifyHeader(w.chain, block.Header(), true); err != nil {
+		return fmt.Errorf("invalid header: %v", err)
+	}
+	start := time.Now()
+	if _, err := w.chain.InsertChain(types.Blocks{block}); err != nil {
+		return err
+	}
+	log.Info("Imported submitted block", "number", block.Number(), "hash", block.Hash(), "txs", len(block.Transactions()),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+
+	w.mux.Post(core.NewMinedBlockEvent{Block: block})
+	return nil
+}
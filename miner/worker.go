@@ -86,6 +86,7 @@ type environment struct {
 	uncles    mapset.Set     // uncle set
 	tcount    int            // tx count in cycle
 	gasPool   *core.GasPool  // available gas used to pack transactions
+	private   bool           // whbtper private transactions were packed (snapshot frozen)
 
 	header   *types.Header
 	txs      []*types.Transaction
@@ -136,6 +137,7 @@ type worker struct {
 	chainHeadSub event.Subscription
 	chainSideCh  chan core.ChainSideEvent
 	chainSideSub event.Subscription
+	bundleCh     chan struct{}
 
 	// Channels
 	newWorkCh          chan *newWorkReq
@@ -150,6 +152,7 @@ type worker struct {
 	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
+	bundles      *bundlePool                  // A set of transaction bundles waiting for atomic inclusion.
 
 	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
 	coinbase common.Address
@@ -188,10 +191,12 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
 		unconfirmed:        newUnconfirmedBlocks(btp.BlockChain(), miningLogAtDepth),
+		bundles:            newBundlePool(),
 		pendingTasks:       make(map[common.Hash]*task),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
 		chainSideCh:        make(chan core.ChainSideEvent, chainSideChanSize),
+		bundleCh:           make(chan struct{}, 1),
 		newWorkCh:          make(chan *newWorkReq),
 		taskCh:             make(chan *task),
 		resultCh:           make(chan *types.Block, resultQueueSize),
@@ -224,6 +229,14 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	return worker
 }
 
+// ordering returns the transaction ordering strategy used to fill blocks.
+func (w *worker) ordering() OrderingStrategy {
+	if w.config.Ordering == nil {
+		return PriceOrdering{}
+	}
+	return w.config.Ordering
+}
+
 // setbtperbase sets the btperbase used to initialize the block coinbase field.
 func (w *worker) setbtperbase(addr common.Address) {
 	w.mu.Lock()
@@ -466,7 +479,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				txset := w.ordering().Order(w.current.signer, txs, w.btp.TxPool().Arrivals(txs))
 				tcount := w.current.tcount
 				w.commitTransactions(txset, coinbase, nil)
 				// Only update the snapshot if any new transactons were added
@@ -483,6 +496,14 @@ func (w *worker) mainLoop() {
 			}
 			atomic.AddInt32(&w.newTxs, int32(len(ev.Txs)))
 
+		case <-w.bundleCh:
+			// Regenerate the pending block right away if we're not mining,
+			// otherwise let the next resubmit pick the new bundle up.
+			if !w.isRunning() {
+				w.commitNewWork(nil, true, time.Now().Unix())
+			}
+			atomic.AddInt32(&w.newTxs, 1)
+
 		// System stopped
 		case <-w.exitCh:
 			return
@@ -671,6 +692,11 @@ func (w *worker) commitUncle(env *environment, uncle *types.Header) error {
 // updateSnapshot updates pending snapshot block and state.
 // Note this function assumes the current variable is thread safe.
 func (w *worker) updateSnapshot() {
+	// Private transactions must never be served through the pending block, state
+	// or logs, so keep the snapshot taken before they were packed.
+	if w.current.private {
+		return
+	}
 	w.snapshotMu.Lock()
 	defer w.snapshotMu.Unlock()
 
@@ -715,7 +741,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TransactionSet, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -921,19 +947,23 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		w.commit(uncles, nil, false, tstart)
 	}
 
+	// Place the most profitable transaction bundles at the top of the block.
+	w.commitBundles(w.coinbase)
+
 	// Fill the block with all available pending transactions.
 	pending, err := w.btp.TxPool().Pending()
 	if err != nil {
 		log.Error("Failed to fetch pending transactions", "err", err)
 		return
 	}
-	// Short circuit if there is no available pending transactions
-	if len(pending) == 0 {
+	// Short circuit if there is no available pending transactions nor bundles
+	if len(pending) == 0 && w.current.tcount == 0 {
 		w.updateSnapshot()
 		return
 	}
-	// Split the pending transactions into locals and remotes
-	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
+	// Split the pending transactions into privates, locals and remotes
+	remoteTxs, privateTxs := w.btp.TxPool().SplitPrivate(pending)
+	localTxs := make(map[common.Address]types.Transactions)
 	for _, account := range w.btp.TxPool().Locals() {
 		if txs := remoteTxs[account]; len(txs) > 0 {
 			delete(remoteTxs, account)
@@ -941,16 +971,28 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
+		txs := w.ordering().Order(w.current.signer, localTxs, w.btp.TxPool().Arrivals(localTxs))
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
+		txs := w.ordering().Order(w.current.signer, remoteTxs, w.btp.TxPool().Arrivals(remoteTxs))
+		if w.commitTransactions(txs, w.coinbase, interrupt) {
+			return
+		}
+	}
+	// Private transactions are only ever packed into sealed blocks, after the
+	// pending snapshot was taken so that they never leak through it.
+	if len(privateTxs) > 0 && w.isRunning() {
+		w.updateSnapshot()
+
+		tcount := w.current.tcount
+		txs := w.ordering().Order(w.current.signer, privateTxs, w.btp.TxPool().Arrivals(privateTxs))
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
+		w.current.private = w.current.tcount != tcount
 	}
 	w.commit(uncles, w.fullTaskHook, true, tstart)
 }
@@ -17,6 +17,7 @@
 package miner
 
 import (
+	"crypto/ecdsa"
 	"math/big"
 	"testing"
 	"time"
@@ -49,6 +50,11 @@ var (
 	testUserKey, _  = crypto.GenerateKey()
 	testUserAddress = crypto.PubkeyToAddress(testUserKey.PublicKey)
 
+	// Test contract which marks its storage on the first call and reverts on
+	// all the subsequent ones
+	testContractAddress = common.Address{0xc0, 0x01}
+	testContractCode    = common.FromHex("0x600054600c576001600055005b600080fd")
+
 	// Test transactions
 	pendingTxs []*types.Transaction
 	newTxs     []*types.Transaction
@@ -89,7 +95,10 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 		db    = rawdb.NewMemoryDatabase()
 		gspec = core.Genesis{
 			Config: chainConfig,
-			Alloc:  core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
+			Alloc: core.GenesisAlloc{
+				testBankAddress:     {Balance: testBankFunds},
+				testContractAddress: {Code: testContractCode},
+			},
 		}
 	)
 
@@ -177,6 +186,48 @@ func testPendingStateAndBlock(t *testing.T, chainConfig *params.ChainConfig, eng
 	}
 }
 
+// Tests that private transactions are packed into the sealing work, but never
+// leak into the pending block and state served to users.
+func TestPendingPrivateTransactions(t *testing.T) {
+	engine := btpash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, btpashChainConfig, engine, 0)
+	defer w.close()
+
+	private, _ := types.SignTx(types.NewTransaction(1, testUserAddress, big.NewInt(2000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+	if err := b.txPool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	taskCh := make(chan *task, 1)
+	w.newTaskHook = func(task *task) {
+		if len(task.block.Transactions()) == 2 {
+			select {
+			case taskCh <- task:
+			default:
+			}
+		}
+	}
+	w.skipSealHook = func(task *task) bool { return true }
+	w.start()
+
+	select {
+	case task := <-taskCh:
+		if task.block.Transactions()[1].Hash() != private.Hash() {
+			t.Fatalf("private transaction not sealed last")
+		}
+	case <-time.NewTimer(2 * time.Second).C:
+		t.Fatalf("private transaction not sealed")
+	}
+	block, state := w.pending()
+	if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != pendingTxs[0].Hash() {
+		t.Fatalf("pending transactions mismatch: have %d, want %d", len(block.Transactions()), 1)
+	}
+	if balance := state.GetBalance(testUserAddress); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("pending balance mismatch: have %d, want %d", balance, 1000)
+	}
+}
+
 func TestEmptyWorkbtpash(t *testing.T) {
 	testEmptyWork(t, btpashChainConfig, btpash.NewFaker())
 }
@@ -459,3 +510,223 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+func TestBundleInclusion(t *testing.T) {
+	engine := btpash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, btpashChainConfig, engine, 0)
+	defer w.close()
+
+	// Collect the fees on a separate account to make the bundles profitable
+	w.setbtperbase(common.Address{0x01})
+
+	// Ensure worker has finished initialization
+	for {
+		b := w.pendingBlock()
+		if b != nil && b.NumberU64() == 1 {
+			break
+		}
+	}
+	sign := func(nonce uint64, amount int64, price int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, testUserAddress, big.NewInt(amount), params.TxGas, big.NewInt(price), nil), types.HomesteadSigner{}, testBankKey)
+		return tx
+	}
+	call := func(key *ecdsa.PrivateKey, nonce uint64, price int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, testContractAddress, new(big.Int), 100000, big.NewInt(price), nil), types.HomesteadSigner{}, key)
+		return tx
+	}
+	// The most profitable bundle also marks the test contract, making it revert
+	// for all the bundles committed after it
+	pricy := &Bundle{Txs: types.Transactions{sign(0, 2000, 5), call(testBankKey, 1, 5)}, BlockNumber: 1}
+	cheap := &Bundle{Txs: types.Transactions{sign(0, 1000, 1), sign(1, 1000, 1)}, BlockNumber: 1}
+
+	// A bundle whose second transaction fails only after the pricy one is committed
+	userTx, _ := types.SignTx(types.NewTransaction(0, testBankAddress, new(big.Int), params.TxGas, new(big.Int), nil), types.HomesteadSigner{}, testUserKey)
+	secondFails := &Bundle{Txs: types.Transactions{userTx, sign(0, 1000, 2)}, BlockNumber: 1}
+
+	// A bundle whose transaction reverts only after the pricy one is committed
+	reverts := &Bundle{Txs: types.Transactions{call(testUserKey, 0, 0)}, BlockNumber: 1}
+
+	if err := w.addBundle(&Bundle{BlockNumber: 1}); err != errEmptyBundle {
+		t.Fatalf("empty bundle error mismatch: have %v, want %v", err, errEmptyBundle)
+	}
+	if err := w.addBundle(&Bundle{Txs: pricy.Txs, BlockNumber: 0}); err != errStaleBundle {
+		t.Fatalf("stale bundle error mismatch: have %v, want %v", err, errStaleBundle)
+	}
+	if err := w.addBundle(cheap); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if err := w.addBundle(pricy); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if err := w.addBundle(pricy); err != errKnownBundle {
+		t.Fatalf("known bundle error mismatch: have %v, want %v", err, errKnownBundle)
+	}
+	if err := w.addBundle(secondFails); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if err := w.addBundle(reverts); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	// Ensure the more profitable bundle is included in full, and the conflicting
+	// ones not even partially
+	time.Sleep(100 * time.Millisecond)
+
+	block, state := w.pending()
+	if txs := block.Transactions(); len(txs) != 2 || txs[0].Hash() != pricy.Txs[0].Hash() || txs[1].Hash() != pricy.Txs[1].Hash() {
+		t.Fatalf("pending transactions mismatch: have %d, want bundle %x", len(txs), pricy.Hash())
+	}
+	if balance := state.GetBalance(testUserAddress); balance.Cmp(big.NewInt(2000)) != 0 {
+		t.Errorf("account balance mismatch: have %d, want %d", balance, 2000)
+	}
+	if nonce := state.GetNonce(testUserAddress); nonce != 0 {
+		t.Errorf("partially committed bundle: user nonce %d, want %d", nonce, 0)
+	}
+	if mark := state.GetState(testContractAddress, common.Hash{}); mark != common.BytesToHash([]byte{1}) {
+		t.Errorf("contract mark mismatch: have %x, want %x", mark, common.BytesToHash([]byte{1}))
+	}
+}
+
+func TestTransactionOrdering(t *testing.T) {
+	var (
+		keyA, _ = crypto.GenerateKey()
+		keyB, _ = crypto.GenerateKey()
+		keyC, _ = crypto.GenerateKey()
+		signer  = types.HomesteadSigner{}
+	)
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, price int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, testUserAddress, big.NewInt(1000), params.TxGas, big.NewInt(price), nil), signer, key)
+		return tx
+	}
+	// Create the transactions in a fixed arrival order
+	a0 := sign(keyA, 0, 1)
+	b0 := sign(keyB, 0, 3)
+	a1 := sign(keyA, 1, 5)
+	c0 := sign(keyC, 0, 2)
+	a2 := sign(keyA, 2, 1)
+
+	var (
+		arrival = time.Now()
+		seen    = make(map[common.Hash]time.Time)
+	)
+	for i, tx := range []*types.Transaction{a0, b0, a1, c0, a2} {
+		seen[tx.Hash()] = arrival.Add(time.Duration(i) * time.Second)
+	}
+
+	tests := []struct {
+		ordering OrderingStrategy
+		want     []*types.Transaction
+	}{
+		{PriceOrdering{}, []*types.Transaction{b0, c0, a0, a1, a2}},
+		{FIFOOrdering{}, []*types.Transaction{a0, b0, a1, c0, a2}},
+		{RoundRobinOrdering{}, []*types.Transaction{a0, b0, c0, a1, a2}},
+	}
+	for _, tt := range tests {
+		set := tt.ordering.Order(signer, map[common.Address]types.Transactions{
+			crypto.PubkeyToAddress(keyA.PublicKey): {a0, a1, a2},
+			crypto.PubkeyToAddress(keyB.PublicKey): {b0},
+			crypto.PubkeyToAddress(keyC.PublicKey): {c0},
+		}, seen)
+		var have []*types.Transaction
+		for tx := set.Peek(); tx != nil; tx = set.Peek() {
+			have = append(have, tx)
+			set.Shift()
+		}
+		if len(have) != len(tt.want) {
+			t.Errorf("%s: transaction count mismatch: have %d, want %d", tt.ordering.Name(), len(have), len(tt.want))
+			continue
+		}
+		for i := range have {
+			if have[i] != tt.want[i] {
+				t.Errorf("%s: transaction %d mismatch: have nonce %d price %v, want nonce %d price %v", tt.ordering.Name(), i, have[i].Nonce(), have[i].GasPrice(), tt.want[i].Nonce(), tt.want[i].GasPrice())
+			}
+		}
+	}
+}
+
+func TestRoundRobinOrderingPop(t *testing.T) {
+	var (
+		keyA, _ = crypto.GenerateKey()
+		keyB, _ = crypto.GenerateKey()
+		signer  = types.HomesteadSigner{}
+	)
+	a0, _ := types.SignTx(types.NewTransaction(0, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil), signer, keyA)
+	b0, _ := types.SignTx(types.NewTransaction(0, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil), signer, keyB)
+	a1, _ := types.SignTx(types.NewTransaction(1, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil), signer, keyA)
+	b1, _ := types.SignTx(types.NewTransaction(1, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil), signer, keyB)
+
+	arrival := time.Now()
+	seen := map[common.Hash]time.Time{
+		a0.Hash(): arrival,
+		b0.Hash(): arrival.Add(time.Second),
+	}
+	set := RoundRobinOrdering{}.Order(signer, map[common.Address]types.Transactions{
+		crypto.PubkeyToAddress(keyA.PublicKey): {a0, a1},
+		crypto.PubkeyToAddress(keyB.PublicKey): {b0, b1},
+	}, seen)
+	// Drop the first account entirely, the second should get all the turns
+	if tx := set.Peek(); tx != a0 {
+		t.Fatalf("first transaction mismatch: have %x, want %x", tx.Hash(), a0.Hash())
+	}
+	set.Pop()
+	for i, want := range []*types.Transaction{b0, b1} {
+		if tx := set.Peek(); tx != want {
+			t.Fatalf("transaction %d mismatch: have %v, want %x", i, tx, want.Hash())
+		}
+		set.Shift()
+	}
+	if tx := set.Peek(); tx != nil {
+		t.Fatalf("unexpected leftover transaction %x", tx.Hash())
+	}
+}
+
+func TestBlockTemplate(t *testing.T) {
+	engine := btpash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, btpashChainConfig, engine, 0)
+	defer w.close()
+
+	// Add an executable private transaction, which must never leak into templates
+	private, _ := types.SignTx(types.NewTransaction(1, testUserAddress, big.NewInt(2000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+	if err := b.txPool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if pending, _ := b.txPool.Pending(); len(pending[testBankAddress]) != 2 {
+		t.Fatalf("private transaction not pending: have %d bank transactions, want %d", len(pending[testBankAddress]), 2)
+	}
+	// Build a template on top of the genesis and ensure it's filled
+	genesis := b.chain.Genesis()
+	block, receipts, err := w.buildTemplate(genesis.Hash(), testUserAddress, 0)
+	if err != nil {
+		t.Fatalf("failed to build template: %v", err)
+	}
+	if block.NumberU64() != 1 || block.ParentHash() != genesis.Hash() {
+		t.Fatalf("template position mismatch: have #%d [%x], want #1 [%x]", block.NumberU64(), block.ParentHash(), genesis.Hash())
+	}
+	if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != pendingTxs[0].Hash() {
+		t.Fatalf("template transactions mismatch: have %d, want %d", len(block.Transactions()), 1)
+	}
+	for _, tx := range block.Transactions() {
+		if tx.Hash() == private.Hash() {
+			t.Fatalf("private transaction %x leaked into template", tx.Hash())
+		}
+	}
+	if len(receipts) != 1 || block.GasUsed() != params.TxGas {
+		t.Fatalf("template gas used mismatch: have %d, want %d", block.GasUsed(), params.TxGas)
+	}
+	if _, _, err := w.buildTemplate(common.Hash{0x01}, testUserAddress, 0); err != errUnknownParent {
+		t.Fatalf("unknown parent error mismatch: have %v, want %v", err, errUnknownParent)
+	}
+	// Submit the template (the faker accepts it unsealed) and ensure it's imported
+	if err := w.submitBlock(block); err != nil {
+		t.Fatalf("failed to submit block: %v", err)
+	}
+	if head := b.chain.CurrentBlock(); head.Hash() != block.Hash() {
+		t.Fatalf("chain head mismatch: have %x, want %x", head.Hash(), block.Hash())
+	}
+	if err := w.submitBlock(block); err != errKnownBlock {
+		t.Fatalf("known block error mismatch: have %v, want %v", err, errKnownBlock)
+	}
+}
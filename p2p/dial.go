@@ -70,6 +70,7 @@ func (t TCPDialer) Dial(dest *enode.Node) (net.Conn, error) {
 type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
+	db          *enode.DB // Node database to check persisted peer scores against
 	netrestrict *netutil.Netlist
 	self        enode.ID
 	bootnodes   []*enode.Node // default dials when there are no peers
@@ -117,10 +118,11 @@ type waitExpireTask struct {
 	time.Duration
 }
 
-func newDialState(self enode.ID, ntab discoverTable, maxdyn int, cfg *Config) *dialstate {
+func newDialState(self enode.ID, ntab discoverTable, db *enode.DB, maxdyn int, cfg *Config) *dialstate {
 	s := &dialstate{
 		maxDynDials: maxdyn,
 		ntab:        ntab,
+		db:          db,
 		self:        self,
 		netrestrict: cfg.NetRestrict,
 		log:         cfg.Logger,
@@ -157,7 +159,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 
 	var newtasks []task
 	addDial := func(flag connFlag, n *enode.Node) bool {
-		if err := s.checkDial(n, peers); err != nil {
+		if err := s.checkDynDial(n, peers); err != nil {
 			s.log.Trace("Skipping dial candidate", "id", n.ID(), "addr", &net.TCPAddr{IP: n.IP(), Port: n.TCP()}, "err", err)
 			return false
 		}
@@ -249,6 +251,7 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errLowScore         = errors.New("low reputation score")
 )
 
 func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
@@ -268,6 +271,19 @@ func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
 	return nil
 }
 
+// checkDynDial runs the common dial checks and additionally rejects dynamic
+// candidates that misbehaved badly enough in the past to be deprioritised.
+// Static nodes are not subject to scoring, as the user explicitly asked for them.
+func (s *dialstate) checkDynDial(n *enode.Node, peers map[enode.ID]*Peer) error {
+	if err := s.checkDial(n, peers); err != nil {
+		return err
+	}
+	if s.db != nil && s.db.NodeScore(n.ID()) < enode.LowNodeScore {
+		return errLowScore
+	}
+	return nil
+}
+
 func (s *dialstate) taskDone(t task, now time.Time) {
 	switch t := t.(type) {
 	case *dialTask:
@@ -21,6 +21,7 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"sync"
@@ -48,6 +49,8 @@ const (
 	dbNodePing      = "lastping"
 	dbNodePong      = "lastpong"
 	dbNodeSeq       = "seq"
+	dbNodeScore     = "score"
+	dbNodeScoreTime = "scoretime"
 
 	// Local information is keyed by ID only, the full key is "local:<ID>:seq".
 	// Use localItemKey to create those keys.
@@ -60,6 +63,16 @@ const (
 	dbVersion        = 9
 )
 
+const (
+	// LowNodeScore is the persisted reputation score below which a node is neither
+	// dialed nor preferred for requests anymore.
+	LowNodeScore = -50
+
+	// nodeScoreHalfLife is the time in which a persisted reputation score decays
+	// to half its magnitude, so that past behaviour is eventually forgiven.
+	nodeScoreHalfLife = 6 * time.Hour
+)
+
 var zeroIP = make(net.IP, 16)
 
 // DB is the node database, storing previously seen nodes and any collected metadata about
@@ -378,6 +391,35 @@ func (db *DB) UpdateFindFails(id ID, ip net.IP, fails int) error {
 	return db.storeInt64(nodeItemKey(id, ip, dbNodeFindFails), int64(fails))
 }
 
+// NodeScore retrieves the persisted reputation score of a node, decayed towards
+// zero based on the time passed since it was stored. Nodes never scored before
+// report zero.
+func (db *DB) NodeScore(id ID) int64 {
+	return db.nodeScoreAt(id, time.Now())
+}
+
+// nodeScoreAt retrieves the persisted reputation score of a node, decayed up to
+// the given time.
+func (db *DB) nodeScoreAt(id ID, now time.Time) int64 {
+	score := db.fetchInt64(nodeItemKey(id, zeroIP, dbNodeScore))
+	if score == 0 {
+		return 0
+	}
+	stored := time.Unix(db.fetchInt64(nodeItemKey(id, zeroIP, dbNodeScoreTime)), 0)
+	if elapsed := now.Sub(stored); elapsed > 0 {
+		score = int64(math.Round(float64(score) * math.Pow(0.5, float64(elapsed)/float64(nodeScoreHalfLife))))
+	}
+	return score
+}
+
+// UpdateNodeScore stores the reputation score of a node, restarting its decay.
+func (db *DB) UpdateNodeScore(id ID, score int64) error {
+	if err := db.storeInt64(nodeItemKey(id, zeroIP, dbNodeScoreTime), time.Now().Unix()); err != nil {
+		return err
+	}
+	return db.storeInt64(nodeItemKey(id, zeroIP, dbNodeScore), score)
+}
+
 // LocalSeq retrieves the local record sequence counter.
 func (db *DB) localSeq(id ID) uint64 {
 	return db.fetchUint64(localItemKey(id, dbLocalSeq))
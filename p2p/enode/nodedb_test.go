@@ -154,6 +154,34 @@ func TestDBFetchStore(t *testing.T) {
 	if stored := db.FindFails(node.ID(), node.IP()); stored != num {
 		t.Errorf("find-node fails: value mismatch: have %v, want %v", stored, num)
 	}
+	// Check fetch/store operations on a node reputation score
+	if stored := db.NodeScore(node.ID()); stored != 0 {
+		t.Errorf("score: non-existing object: %v", stored)
+	}
+	if err := db.UpdateNodeScore(node.ID(), -int64(num)); err != nil {
+		t.Errorf("score: failed to update: %v", err)
+	}
+	if stored := db.NodeScore(node.ID()); stored != -int64(num) {
+		t.Errorf("score: value mismatch: have %v, want %v", stored, -num)
+	}
+	// Check that persisted reputation scores decay over time
+	if err := db.UpdateNodeScore(node.ID(), -80); err != nil {
+		t.Errorf("score: failed to update: %v", err)
+	}
+	now := time.Now()
+	for _, tt := range []struct {
+		elapsed time.Duration
+		score   int64
+	}{
+		{0, -80},
+		{nodeScoreHalfLife, -40},
+		{2 * nodeScoreHalfLife, -20},
+		{10 * nodeScoreHalfLife, 0},
+	} {
+		if stored := db.nodeScoreAt(node.ID(), now.Add(tt.elapsed)); stored != tt.score {
+			t.Errorf("score: decayed value mismatch after %v: have %v, want %v", tt.elapsed, stored, tt.score)
+		}
+	}
 	// Check fetch/store operations on an actual node object
 	if stored := db.Node(node.ID()); stored != nil {
 		t.Errorf("node: non-existing object: %v", stored)
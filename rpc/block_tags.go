@@ -0,0 +1,28 @@
+// Copyright 2019 The go-btpereum Authors
+// This file is part of the go-btpereum library.
+//
+// The go-btpereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-btpereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-btpereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+const (
+	// SafeBlockNumber is the block number of the "safe" tag, referencing the most
+	// recent block with the serving node's configured number of confirmations on
+	// top of it.
+	SafeBlockNumber = BlockNumber(-4)
+
+	// FinalizedBlockNumber is the block number of the "finalized" tag, referencing
+	// the most recent block the serving node's consensus engine considers final.
+	FinalizedBlockNumber = BlockNumber(-3)
+)